@@ -0,0 +1,84 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/renproject/mercury/usage"
+)
+
+// adminHandler only allows requests which send the admin token as a bearer token.
+func (server *Server) adminHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := []byte("Bearer " + server.opts.AdminToken)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), token) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// usageReport exports the usage records between the `from` and `to` dates (YYYY-MM-DD, inclusive) in the given `format`
+// (csv or json).
+func (server *Server) usageReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = usage.FormatJSON
+		}
+		if format != usage.FormatCSV && format != usage.FormatJSON {
+			http.Error(w, fmt.Sprintf("unsupported format: %s", format), http.StatusBadRequest)
+			return
+		}
+
+		from, to := query.Get("from"), query.Get("to")
+		for _, date := range []string{from, to} {
+			if _, err := time.Parse(usage.DateFormat, date); date != "" && err != nil {
+				http.Error(w, fmt.Sprintf("invalid date: %s", date), http.StatusBadRequest)
+				return
+			}
+		}
+		records, err := server.opts.Usage.Records(from, to)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if format == usage.FormatCSV {
+			w.Header().Set("Content-Type", "text/csv")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(http.StatusOK)
+		if err := usage.Export(w, records, format); err != nil {
+			server.logger.Errorf("cannot export usage: %v", err)
+		}
+	}
+}
+
+// reload reloads the configuration of the server and returns the networks which are supported after the reload.
+func (server *Server) reload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := server.opts.Reload(); err != nil {
+			server.logger.Errorf("cannot reload configuration: %v", err)
+			http.Error(w, fmt.Sprintf("cannot reload configuration: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		networks := []string{}
+		for _, api := range server.Apis() {
+			if reporter, ok := api.(HealthReporter); ok {
+				network := reporter.Network()
+				networks = append(networks, fmt.Sprintf("%s/%s", network.Chain(), network))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string][]string{"networks": networks})
+	}
+}
@@ -1,17 +1,20 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/renproject/mercury/cache"
 	"github.com/renproject/mercury/proxy"
+	"github.com/renproject/mercury/rpc"
 	"github.com/renproject/mercury/stat"
 	"github.com/renproject/mercury/types"
 	"github.com/sirupsen/logrus"
@@ -23,73 +26,225 @@ const (
 	ErrorCodeInvalidRequest = -32600
 )
 
+const (
+	// MaxBatchSize is the maximum number of calls in a batch request.
+	MaxBatchSize = 1000
+
+	// batchConcurrency is the number of calls of a batch request which are handled concurrently.
+	batchConcurrency = 8
+)
+
 type Api struct {
-	network types.Network
-	proxy   *proxy.Proxy
-	cache   *cache.Cache
-	logger  logrus.FieldLogger
+	network   types.Network
+	proxy     *proxy.Proxy
+	cache     *cache.Cache
+	logger    logrus.FieldLogger
+	whitelist map[string]types.AccessLevel
+}
+
+// ApiOptions configure the methods available through an Api.
+type ApiOptions struct {
+	// Whitelist overrides the access level of methods of the default whitelist of the network. Methods with the
+	// `NoAccess` level are removed from the whitelist.
+	Whitelist map[string]types.AccessLevel
 }
 
-// NewApi returns a new Api.
+// NewApi returns a new Api which uses the default whitelist of the network.
 func NewApi(network types.Network, proxy *proxy.Proxy, cache *cache.Cache, logger logrus.FieldLogger) *Api {
+	return NewApiWithOptions(network, proxy, cache, logger, ApiOptions{})
+}
+
+// NewApiWithOptions returns a new Api which uses the given options.
+func NewApiWithOptions(network types.Network, proxy *proxy.Proxy, cache *cache.Cache, logger logrus.FieldLogger, opts ApiOptions) *Api {
 	return &Api{
-		network: network,
-		proxy:   proxy,
-		cache:   cache,
-		logger:  logger,
+		network:   network,
+		proxy:     proxy,
+		cache:     cache,
+		logger:    logger,
+		whitelist: MergeWhitelist(network, opts.Whitelist),
 	}
 }
 
+// Network returns the network of the Api.
+func (api *Api) Network() types.Network {
+	return api.network
+}
+
+// Health returns the health of the upstream clients of the Api.
+func (api *Api) Health() proxy.Health {
+	return api.proxy.Health()
+}
+
+// Usage returns the number of requests sent with each API key by the upstream clients of the Api.
+func (api *Api) Usage() rpc.Usage {
+	return api.proxy.Usage()
+}
+
 // AddHandler implements the `BlockchainApi` interface.
-func (api *Api) AddHandler(r *mux.Router, s *stat.Stat) {
-	r.HandleFunc(fmt.Sprintf("/%s/%s", api.network.Chain(), api.network), api.jsonRPCHandler(s)).Methods("POST")
+func (api *Api) AddHandler(r *mux.Router, recorder stat.Recorder) {
+	r.HandleFunc(fmt.Sprintf("/%s/%s", api.network.Chain(), api.network), api.jsonRPCHandler(recorder)).Methods("POST")
 }
 
-func (api *Api) jsonRPCHandler(s *stat.Stat) http.HandlerFunc {
+func (api *Api) jsonRPCHandler(recorder stat.Recorder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		data, err := ioutil.ReadAll(r.Body)
 		if err != nil {
+			event := api.event(r)
+			event.Status = http.StatusBadRequest
+			recorder.Record(event)
 			writeError(w, r, api.logger, http.StatusBadRequest, ErrorCodeInvalidJSON, err)
 			return
 		}
 
-		method, id, err := GetMethodAndID(data)
-		if err != nil {
-			writeError(w, r, api.logger, http.StatusBadRequest, ErrorCodeInvalidRequest, fmt.Errorf("cannot get the method: %v", err))
+		if IsBatch(data) {
+			api.handleBatch(w, r, data, recorder)
 			return
 		}
 
-		level := WhitelistLevel(api.network, method)
-		if level == 0 {
-			writeError(w, r, api.logger, http.StatusMethodNotAllowed, id, fmt.Errorf("method unavailable: %s", method))
+		result := api.call(r, data, recorder)
+		if result.err != nil {
+			writeError(w, r, api.logger, result.statusCode, result.id, result.err)
 			return
 		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(result.statusCode)
+		w.Write(result.data)
+	}
+}
+
+// handleBatch handles a batch of JSON-RPC calls. Each call is whitelisted, cached and recorded separately, and the
+// responses are returned in the same order as the calls.
+func (api *Api) handleBatch(w http.ResponseWriter, r *http.Request, data []byte, recorder stat.Recorder) {
+	calls := []json.RawMessage{}
+	if err := json.Unmarshal(data, &calls); err != nil {
+		writeError(w, r, api.logger, http.StatusBadRequest, ErrorCodeInvalidJSON, fmt.Errorf("cannot decode the batch: %v", err))
+		return
+	}
+	if len(calls) == 0 || len(calls) > MaxBatchSize {
+		writeError(w, r, api.logger, http.StatusBadRequest, ErrorCodeInvalidRequest, fmt.Errorf("batch size must be between 1 and %d: %d", MaxBatchSize, len(calls)))
+		return
+	}
 
-		s.Insert(method)
+	responses := make([]json.RawMessage, len(calls))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i := range calls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
 
-		hash, err := HashData(data)
-		if err != nil {
-			writeError(w, r, api.logger, http.StatusInternalServerError, id, err)
-			return
-		}
+			result := api.call(r, calls[i], recorder)
+			if result.err == nil && json.Valid(result.data) {
+				responses[i] = result.data
+				return
+			}
+			if result.err == nil {
+				result.err = fmt.Errorf("invalid response with status %d: %s", result.statusCode, result.data)
+			}
+			logError(r, api.logger, result.statusCode, result.err)
+			responses[i] = errorResponse(result.id, result.err)
+		}(i)
+	}
+	wg.Wait()
 
-		// Check if the result has been cached and if not retrieve it (or wait if it is already being retrieved).
-		resp, err := api.cache.Get(level, hash, FetchResponse(api.proxy, r, data))
-		if err != nil {
-			writeError(w, r, api.logger, http.StatusInternalServerError, id, err)
-			return
-		}
+	resp, err := json.Marshal(responses)
+	if err != nil {
+		writeError(w, r, api.logger, http.StatusInternalServerError, ErrorCodeInvalidRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
 
-		var result Result
-		if err := json.Unmarshal(resp, &result); err != nil {
-			writeError(w, r, api.logger, http.StatusInternalServerError, id, fmt.Errorf(string(resp)))
-			return
-		}
+// callResult is the response of a single JSON-RPC call, or the error and status code to respond with if the call
+// failed.
+type callResult struct {
+	id         int
+	statusCode int
+	data       []byte
+	err        error
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(result.StatusCode)
-		w.Write(result.Data)
+// call handles a single JSON-RPC call and records it once it is done.
+func (api *Api) call(r *http.Request, data []byte, recorder stat.Recorder) (result callResult) {
+	event := api.event(r)
+	defer func() {
+		event.Status = result.statusCode
+		event.Latency = time.Since(event.Time)
+		recorder.Record(event)
+	}()
+
+	method, id, err := GetMethodAndID(data)
+	if err != nil {
+		return callResult{id: ErrorCodeInvalidRequest, statusCode: http.StatusBadRequest, err: fmt.Errorf("cannot get the method: %v", err)}
 	}
+
+	level := api.whitelist[method]
+	if level == types.NoAccess {
+		return callResult{id: id, statusCode: http.StatusMethodNotAllowed, err: fmt.Errorf("method unavailable: %s", method)}
+	}
+
+	event.Method = method
+
+	hash, err := HashData(data)
+	if err != nil {
+		return callResult{id: id, statusCode: http.StatusInternalServerError, err: err}
+	}
+
+	// Check if the result has been cached and if not retrieve it (or wait if it is already being retrieved).
+	resp, outcome, err := api.cache.Lookup(level, hash, FetchResponse(api.proxy, r, data, level))
+	event.Cache = string(outcome)
+	if err != nil {
+		return callResult{id: id, statusCode: http.StatusInternalServerError, err: err}
+	}
+
+	var fetched Result
+	if err := json.Unmarshal(resp, &fetched); err != nil {
+		return callResult{id: id, statusCode: http.StatusInternalServerError, err: fmt.Errorf(string(resp))}
+	}
+	return callResult{id: id, statusCode: fetched.StatusCode, data: fetched.Data}
+}
+
+// event returns the event used to record a call of the request.
+func (api *Api) event(r *http.Request) stat.Event {
+	return stat.Event{
+		Time:    time.Now(),
+		Caller:  Caller(r),
+		Network: fmt.Sprintf("%s/%s", api.network.Chain(), api.network),
+		Method:  stat.UnknownMethod,
+	}
+}
+
+// maxTagLength is the maximum length of the tag used to identify a caller.
+const maxTagLength = 64
+
+// Caller identifies the caller of a request by the `tag` query parameter, or by a hash of the `X-Api-Key` header. It
+// returns an empty string for anonymous requests.
+func Caller(r *http.Request) string {
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		if len(tag) > maxTagLength {
+			tag = tag[:maxTagLength]
+		}
+		return TagCaller(tag)
+	}
+	if apiKey := r.Header.Get("X-Api-Key"); apiKey != "" {
+		return KeyCaller(apiKey)
+	}
+	return ""
+}
+
+// TagCaller returns the caller of requests with the given `tag` query parameter.
+func TagCaller(tag string) string {
+	return "tag:" + tag
+}
+
+// KeyCaller returns the caller of requests with the given `X-Api-Key` header.
+func KeyCaller(apiKey string) string {
+	hash := sha3.Sum256([]byte(apiKey))
+	return "key:" + hex.EncodeToString(hash[:4])
 }
 
 type Result struct {
@@ -104,6 +259,12 @@ func HashData(data []byte) (string, error) {
 	return hash, nil
 }
 
+// IsBatch returns whether the request is a batch of JSON-RPC calls.
+func IsBatch(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '['
+}
+
 func GetMethodAndID(data []byte) (string, int, error) {
 	req := struct {
 		Method string `json:"method"`
@@ -115,10 +276,17 @@ func GetMethodAndID(data []byte) (string, int, error) {
 	return req.Method, req.ID, nil
 }
 
-func FetchResponse(proxy *proxy.Proxy, r *http.Request, data []byte) func() ([]byte, error) {
+// FetchResponse returns a function which fetches the response to the request from the proxy. The fetch is shared by
+// every caller waiting for the same cached result, so it is not cancelled if the caller goes away, unless the method
+// bypasses the cache.
+func FetchResponse(proxy *proxy.Proxy, r *http.Request, data []byte, level types.AccessLevel) func() ([]byte, error) {
 	return func() ([]byte, error) {
+		parent := context.Background()
+		if level == types.FullAccess {
+			parent = r.Context()
+		}
 		// TODO: Update the timeout as per requirements.
-		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		ctx, cancel := context.WithTimeout(parent, time.Minute)
 		defer cancel()
 
 		// Fetch the response from the API.
@@ -126,6 +294,7 @@ func FetchResponse(proxy *proxy.Proxy, r *http.Request, data []byte) func() ([]b
 		if err != nil {
 			return nil, err
 		}
+		defer resp.Body.Close()
 
 		// Read the response and return it.
 		respData, err := ioutil.ReadAll(resp.Body)
@@ -143,6 +312,12 @@ func FetchResponse(proxy *proxy.Proxy, r *http.Request, data []byte) func() ([]b
 }
 
 func writeError(w http.ResponseWriter, r *http.Request, logger logrus.FieldLogger, statusCode, id int, err error) {
+	logError(r, logger, statusCode, err)
+	http.Error(w, string(errorResponse(id, err)), statusCode)
+}
+
+// errorResponse returns the error message sent to the caller.
+func errorResponse(id int, err error) []byte {
 	resp := struct {
 		Error string `json:"error"`
 		ID    int    `json:"id"`
@@ -151,16 +326,17 @@ func writeError(w http.ResponseWriter, r *http.Request, logger logrus.FieldLogge
 		ID:    id,
 	}
 
+	errMsg, err := json.Marshal(resp)
+	if err != nil {
+		return []byte(fmt.Sprintf("failed to marshal the error message: %v", err))
+	}
+	return errMsg
+}
+
+func logError(r *http.Request, logger logrus.FieldLogger, statusCode int, err error) {
 	if statusCode >= 500 {
 		logger.Errorf("failed to call %s: %v", r.URL.String(), err)
 	} else if statusCode >= 400 {
 		logger.Warningf("failed to call %s: %v", r.URL.String(), err)
 	}
-
-	errMsg, err := json.Marshal(resp)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to marshal the error message: %v", err), statusCode)
-		return
-	}
-	http.Error(w, string(errMsg), statusCode)
 }
@@ -1,9 +1,29 @@
 package api_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gorilla/mux"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/renproject/mercury/api"
+
+	"github.com/renproject/kv"
+	"github.com/renproject/mercury/cache"
+	"github.com/renproject/mercury/proxy"
+	"github.com/renproject/mercury/stat"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+	"github.com/renproject/mercury/types/ethtypes"
+	"github.com/renproject/mercury/usage"
+	"github.com/sirupsen/logrus"
 )
 
 var _ = Describe("APIs", func() {
@@ -20,4 +40,252 @@ var _ = Describe("APIs", func() {
 			Expect(fstHash).To(Equal(sndHash))
 		})
 	})
+
+	Context("when listing the whitelist", func() {
+		It("should return the access level of each method of the network", func() {
+			whitelist := Whitelist(btctypes.BtcMainnet)
+			Expect(whitelist).To(HaveLen(12))
+			for method, level := range whitelist {
+				Expect(WhitelistLevel(btctypes.BtcMainnet, method)).To(Equal(level))
+			}
+			Expect(whitelist["sendrawtransaction"]).To(Equal(types.CachedAccess))
+			Expect(Whitelist(btctypes.ZecMainnet)["estimatesmartfee"]).To(Equal(types.FullAccess))
+			Expect(Whitelist(btctypes.BchMainnet)["decoderawtransaction"]).To(Equal(types.CachedAccess))
+			Expect(Whitelist(ethtypes.Mainnet)["eth_getProof"]).To(Equal(types.FullAccess))
+
+			// Modifying the returned whitelist should not change the access levels.
+			whitelist["stop"] = types.FullAccess
+			Expect(WhitelistLevel(btctypes.BtcMainnet, "stop")).To(Equal(types.NoAccess))
+		})
+	})
+
+	Context("when handling requests", func() {
+		It("should record the network, method, status and cache outcome", func() {
+			logger := logrus.StandardLogger()
+			btcCache := cache.New(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "test"), logger)
+			btcAPI := NewApi(btctypes.BtcTestnet, proxy.NewProxy(resultClient{}), btcCache, logger)
+
+			s := stat.New()
+			r := mux.NewRouter()
+			btcAPI.AddHandler(r, &s)
+
+			send := func(data string) int {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("POST", "/btc/testnet", bytes.NewBufferString(data)))
+				return w.Code
+			}
+			Expect(send(`{"jsonrpc":"1.0","id":1,"method":"sendrawtransaction","params":["abcd"]}`)).To(Equal(http.StatusOK))
+			Expect(send(`{"jsonrpc":"1.0","id":1,"method":"sendrawtransaction","params":["abcd"]}`)).To(Equal(http.StatusOK))
+			Expect(send(`{"jsonrpc":"1.0","id":1,"method":"listunspent","params":[]}`)).To(Equal(http.StatusOK))
+			Expect(send(`{"jsonrpc":"1.0","id":1,"method":"stop","params":[]}`)).To(Equal(http.StatusMethodNotAllowed))
+
+			summary := s.Get(stat.Filter{Network: "btc/testnet"})
+			Expect(summary.Total.Requests).To(Equal(uint64(4)))
+			Expect(summary.Total.Statuses).To(Equal(map[int]uint64{200: 3, 405: 1}))
+			Expect(summary.Total.Cache).To(Equal(map[string]uint64{"miss": 1, "hit": 1, "bypass": 1}))
+			Expect(summary.Methods[stat.UnknownMethod].Errors).To(Equal(uint64(1)))
+			Expect(summary.Methods["sendrawtransaction"].Requests).To(Equal(uint64(2)))
+		})
+
+		It("should handle each call of a batch request separately", func() {
+			logger := logrus.StandardLogger()
+			btcCache := cache.New(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "test"), logger)
+			btcAPI := NewApi(btctypes.BtcTestnet, proxy.NewProxy(resultClient{}), btcCache, logger)
+
+			s := stat.New()
+			r := mux.NewRouter()
+			btcAPI.AddHandler(r, &s)
+
+			send := func(data string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("POST", "/btc/testnet", bytes.NewBufferString(data)))
+				return w
+			}
+			w := send(`[{"jsonrpc":"1.0","id":1,"method":"getrawtransaction","params":["abcd"]},{"jsonrpc":"1.0","id":2,"method":"stop","params":[]}]`)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(MatchJSON(`[{"result":"abcd","error":null,"id":1},{"error":"method unavailable: stop","id":2}]`))
+
+			summary := s.Get(stat.Filter{Network: "btc/testnet"})
+			Expect(summary.Total.Requests).To(Equal(uint64(2)))
+			Expect(summary.Total.Statuses).To(Equal(map[int]uint64{200: 1, 405: 1}))
+
+			Expect(send(`[]`).Code).To(Equal(http.StatusBadRequest))
+			Expect(send(`[{"jsonrpc":"1.0"`).Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("when fetching responses", func() {
+		It("should only cancel the fetch with the caller if the method bypasses the cache", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			req := httptest.NewRequest("POST", "/btc/testnet", nil).WithContext(ctx)
+			data := []byte(`{"jsonrpc":"1.0","id":1,"method":"getrawtransaction","params":["abcd"]}`)
+			p := proxy.NewProxy(contextClient{})
+
+			// Other callers may be waiting for the cached result of the fetch.
+			_, err := FetchResponse(p, req, data, types.CachedAccess)()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = FetchResponse(p, req, data, types.FullAccess)()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when reloading the configuration", func() {
+		It("should swap the APIs atomically and finish in-flight requests with the old APIs", func() {
+			logger := logrus.StandardLogger()
+			oldClient := newBlockingClient(`{"result":"old","error":null,"id":1}`)
+			oldAPI := NewApi(btctypes.BtcTestnet, proxy.NewProxy(oldClient), cache.New(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "old"), logger), logger)
+			newAPI := NewApiWithOptions(btctypes.BtcTestnet, proxy.NewProxy(resultClient{}), cache.New(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "new"), logger), logger, ApiOptions{
+				Whitelist: map[string]types.AccessLevel{
+					"listunspent":   types.NoAccess,
+					"getblockcount": types.FullAccess,
+				},
+			})
+
+			var server *Server
+			server = NewServerWithOptions(logger, "5000", ServerOptions{
+				AdminToken: "secret",
+				Reload: func() error {
+					server.SetApis(newAPI)
+					return nil
+				},
+			}, oldAPI)
+			handler := server.Handler()
+
+			send := func(method string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				data := fmt.Sprintf(`{"jsonrpc":"1.0","id":1,"method":"%s","params":[]}`, method)
+				handler.ServeHTTP(w, httptest.NewRequest("POST", "/btc/testnet", bytes.NewBufferString(data)))
+				return w
+			}
+
+			// Start a request which blocks until the old client is released.
+			inFlight := make(chan *httptest.ResponseRecorder, 1)
+			go func() {
+				defer GinkgoRecover()
+				inFlight <- send("getrawtransaction")
+			}()
+			Eventually(oldClient.received).Should(Receive())
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/admin/reload", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			handler.ServeHTTP(w, req)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(MatchJSON(`{"networks":["btc/testnet"]}`))
+			Expect(server.Apis()).To(ConsistOf(newAPI))
+
+			// New requests use the new APIs.
+			w = send("getrawtransaction")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring("abcd"))
+			Expect(send("listunspent").Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(send("getblockcount").Code).To(Equal(http.StatusOK))
+
+			// The in-flight request finishes using the old APIs.
+			close(oldClient.release)
+			w = <-inFlight
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring("old"))
+		})
+
+		It("should return an error if the configuration cannot be reloaded", func() {
+			server := NewServerWithOptions(logrus.StandardLogger(), "5000", ServerOptions{
+				AdminToken: "secret",
+				Reload:     func() error { return errors.New("invalid configuration") },
+			})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/admin/reload", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			server.Handler().ServeHTTP(w, req)
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	Context("when exporting usage", func() {
+		It("should only export the usage of each caller to admins", func() {
+			logger := logrus.StandardLogger()
+			btcCache := cache.New(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "test"), logger)
+			btcAPI := NewApi(btctypes.BtcTestnet, proxy.NewProxy(resultClient{}), btcCache, logger)
+			server := NewServerWithOptions(logger, "5000", ServerOptions{
+				Usage:      usage.NewWithOptions(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "usage"), usage.Options{Callers: []string{TagCaller("darknode")}}),
+				AdminToken: "secret",
+			}, btcAPI)
+			handler := server.Handler()
+
+			data := `{"jsonrpc":"1.0","id":1,"method":"listunspent","params":[]}`
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/btc/testnet?tag=darknode", bytes.NewBufferString(data)))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/btc/testnet", bytes.NewBufferString(data)))
+			// Tags and API keys which are not configured are recorded as anonymous.
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/btc/testnet?tag=unknown", bytes.NewBufferString(data)))
+			req := httptest.NewRequest("POST", "/btc/testnet", bytes.NewBufferString(data))
+			req.Header.Set("X-Api-Key", "unknown")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/admin/usage", nil))
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+
+			w = httptest.NewRecorder()
+			req = httptest.NewRequest("GET", "/admin/usage?format=csv", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			handler.ServeHTTP(w, req)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Type")).To(Equal("text/csv"))
+			lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[1]).To(HaveSuffix(",anonymous,btc/testnet,listunspent,3,0"))
+			Expect(lines[2]).To(HaveSuffix(",tag:darknode,btc/testnet,listunspent,1,0"))
+
+			w = httptest.NewRecorder()
+			req = httptest.NewRequest("GET", "/admin/usage?from=yesterday", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			handler.ServeHTTP(w, req)
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
 })
+
+// resultClient is an upstream client which always returns a successful response.
+type resultClient struct{}
+
+func (resultClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"result":"abcd","error":null,"id":1}`)),
+	}, nil
+}
+
+// contextClient is an upstream client which returns a successful response unless the context is done.
+type contextClient struct{}
+
+func (contextClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return resultClient{}.HandleRequest(ctx, r, data)
+}
+
+// blockingClient is an upstream client which returns the given response once it is released.
+type blockingClient struct {
+	response string
+	received chan struct{}
+	release  chan struct{}
+}
+
+func newBlockingClient(response string) blockingClient {
+	return blockingClient{
+		response: response,
+		received: make(chan struct{}, 1),
+		release:  make(chan struct{}),
+	}
+}
+
+func (client blockingClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
+	client.received <- struct{}{}
+	<-client.release
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(client.response)),
+	}, nil
+}
@@ -4,60 +4,121 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/renproject/mercury/proxy"
+	"github.com/renproject/mercury/rpc"
 	"github.com/renproject/mercury/stat"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/usage"
 	"github.com/rs/cors"
 	"github.com/sirupsen/logrus"
 )
 
 type BlockchainApi interface {
-	AddHandler(r *mux.Router, s *stat.Stat)
+	AddHandler(r *mux.Router, recorder stat.Recorder)
+}
+
+// HealthReporter is implemented by blockchain APIs which can report the health of their upstream clients.
+type HealthReporter interface {
+	Network() types.Network
+	Health() proxy.Health
+}
+
+// UsageReporter is implemented by blockchain APIs which can report the usage of the API keys of their upstream clients.
+type UsageReporter interface {
+	Network() types.Network
+	Usage() rpc.Usage
 }
 
 // DefaultMaxHeaderBytes is the maximum permitted size of the headers in an HTTP request.
 const DefaultMaxHeaderBytes = 1 << 10 // 1 KB
 
+// ServerOptions configure the optional features of a server.
+type ServerOptions struct {
+	// Stat keeps the recent statistics of requests. A Stat with the default retention is used if it is nil.
+	Stat *stat.Stat
+
+	// Usage persists the usage of each caller. Usage is not persisted if it is nil.
+	Usage *usage.Store
+
+	// AdminToken is the bearer token required by the admin endpoints. The admin endpoints are disabled if it is empty.
+	AdminToken string
+
+	// Reload is called by the reload admin endpoint to reload the configuration of the server (e.g. by calling
+	// `SetApis`). The endpoint is disabled if it is nil.
+	Reload func() error
+}
+
 type Server struct {
+	port     string
+	logger   logrus.FieldLogger
+	opts     ServerOptions
+	stat     *stat.Stat
+	recorder stat.Recorder
+
+	// routes holds the current *routes, which are swapped atomically by SetApis.
+	routes atomic.Value
+}
+
+// routes are the blockchain APIs served by a Server and the router for their handlers.
+type routes struct {
 	apis   []BlockchainApi
-	port   string
-	logger logrus.FieldLogger
-	stat   *stat.Stat
+	router *mux.Router
 }
 
 // NewServer returns a server which supports the given blockchain APIs.
 func NewServer(logger logrus.FieldLogger, port string, apis ...BlockchainApi) *Server {
-	s := stat.New()
-	return &Server{
-		apis:   apis,
-		port:   port,
-		logger: logger,
-		stat:   &s,
+	return NewServerWithOptions(logger, port, ServerOptions{}, apis...)
+}
+
+// NewServerWithOptions returns a server which supports the given blockchain APIs and the features enabled by the
+// options.
+func NewServerWithOptions(logger logrus.FieldLogger, port string, opts ServerOptions, apis ...BlockchainApi) *Server {
+	if opts.Stat == nil {
+		s := stat.New()
+		opts.Stat = &s
 	}
+	server := &Server{
+		port:     port,
+		logger:   logger,
+		opts:     opts,
+		stat:     opts.Stat,
+		recorder: opts.Stat,
+	}
+	if opts.Usage != nil {
+		server.recorder = stat.Recorders(opts.Stat, opts.Usage)
+	}
+	server.SetApis(apis...)
+	return server
 }
 
-// Run starts the server.
-func (server *Server) Run() {
-	// Add handlers for each blockchain.
-	r := mux.NewRouter().StrictSlash(true)
-	for _, api := range server.apis {
-		api.AddHandler(r, server.stat)
+// SetApis atomically replaces the blockchain APIs supported by the server. Requests which have already been routed to
+// the previous APIs are still handled by them.
+func (server *Server) SetApis(apis ...BlockchainApi) {
+	router := mux.NewRouter().StrictSlash(true)
+	for _, api := range apis {
+		api.AddHandler(router, server.recorder)
 	}
-	r.HandleFunc("/health", server.health()).Methods("GET")
-	r.HandleFunc("/stats", server.stats()).Methods("GET")
+	server.routes.Store(&routes{
+		apis:   apis,
+		router: router,
+	})
+}
 
-	// Use recovery handler and provide cross-origin support.
-	r.Use(server.recoveryHandler)
-	handler := cors.New(cors.Options{
-		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST"},
-	}).Handler(r)
+// Apis returns the blockchain APIs currently supported by the server.
+func (server *Server) Apis() []BlockchainApi {
+	return server.routes.Load().(*routes).apis
+}
 
+// Run starts the server.
+func (server *Server) Run() {
 	// Set-up request timeout and header size limit for the server.
 	httpServer := &http.Server{
 		Addr:              fmt.Sprintf(":%v", server.port),
-		Handler:           handler,
+		Handler:           server.Handler(),
 		ReadTimeout:       5 * time.Second,
 		ReadHeaderTimeout: 3 * time.Second,
 		WriteTimeout:      5 * time.Second,
@@ -72,17 +133,87 @@ func (server *Server) Run() {
 	}
 }
 
+// Handler returns the handler which serves the blockchain APIs and the endpoints of the server.
+func (server *Server) Handler() http.Handler {
+	r := mux.NewRouter().StrictSlash(true)
+	r.HandleFunc("/health", server.health()).Methods("GET")
+	r.HandleFunc("/stats", server.stats()).Methods("GET")
+	r.HandleFunc("/stats/usage", server.providerUsage()).Methods("GET")
+	if server.opts.AdminToken != "" {
+		admin := r.PathPrefix("/admin").Subrouter()
+		admin.Use(server.adminHandler)
+		if server.opts.Usage != nil {
+			admin.HandleFunc("/usage", server.usageReport()).Methods("GET")
+		}
+		if server.opts.Reload != nil {
+			admin.HandleFunc("/reload", server.reload()).Methods("POST")
+		}
+	}
+
+	// Route the remaining requests using the handlers of the current blockchain APIs.
+	r.PathPrefix("/").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.routes.Load().(*routes).router.ServeHTTP(w, r)
+	}))
+
+	// Use recovery handler and provide cross-origin support.
+	r.Use(server.recoveryHandler)
+	return cors.New(cors.Options{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST"},
+	}).Handler(r)
+}
+
 func (server *Server) health() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		health := map[string]proxy.Health{}
+		for _, api := range server.Apis() {
+			if reporter, ok := api.(HealthReporter); ok {
+				network := reporter.Network()
+				health[fmt.Sprintf("%s/%s", network.Chain(), network)] = reporter.Health()
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(health)
 	}
 }
 
+// stats returns the statistics of the requests which match the `window`, `network` and `method` query parameters.
 func (server *Server) stats() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		data := server.stat.Get()
-		json.NewEncoder(w).Encode(data)
+		query := r.URL.Query()
+		filter := stat.Filter{
+			Network: query.Get("network"),
+			Method:  query.Get("method"),
+		}
+		if window := query.Get("window"); window != "" {
+			var err error
+			if filter.Window, err = time.ParseDuration(window); err != nil || filter.Window <= 0 {
+				http.Error(w, fmt.Sprintf("invalid window: %s", window), http.StatusBadRequest)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(server.stat.Get(filter))
+	}
+}
+
+func (server *Server) providerUsage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usage := map[string]rpc.Usage{}
+		for _, api := range server.Apis() {
+			if reporter, ok := api.(UsageReporter); ok {
+				network := reporter.Network()
+				if networkUsage := reporter.Usage(); len(networkUsage.Keys) > 0 {
+					usage[fmt.Sprintf("%s/%s", network.Chain(), network)] = networkUsage
+				}
+			}
+		}
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(usage)
 	}
 }
 
@@ -3,13 +3,11 @@ package api_test
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
 	"net/http"
-	"os"
 	"time"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 	"github.com/renproject/kv"
 	"github.com/renproject/mercury/api"
 	. "github.com/renproject/mercury/api"
@@ -17,6 +15,7 @@ import (
 	"github.com/renproject/mercury/proxy"
 	"github.com/renproject/mercury/rpc"
 	"github.com/renproject/mercury/rpcclient/btcrpcclient"
+	"github.com/renproject/mercury/testutil/fakenode"
 	"github.com/renproject/mercury/types/btctypes"
 	"github.com/renproject/phi"
 	"github.com/sirupsen/logrus"
@@ -26,11 +25,12 @@ var _ = Describe("Server", func() {
 	Context("when sending concurrent requests", func() {
 		It("should not fail on concurrent requests", func() {
 			// Initialise Bitcoin API.
-			btcTestnetURL := os.Getenv("BITCOIN_TESTNET_RPC_URL")
-			btcTestnetUser := os.Getenv("BITCOIN_TESTNET_RPC_USERNAME")
-			btcTestnetPassword := os.Getenv("BITCOIN_TESTNET_RPC_PASSWORD")
+			node := fakenode.New()
+			defer node.Close()
+			node.SetLatency(100 * time.Millisecond)
+			Expect(node.Respond("listunspent", btcrpcclient.ListUnspentResponse{{TxID: "aa", Amount: 0.1}})).To(Succeed())
 			logger := logrus.StandardLogger()
-			btcTestnetNodeClient := rpc.NewClient(btcTestnetURL, btcTestnetUser, btcTestnetPassword)
+			btcTestnetNodeClient := rpc.NewClient(node.URL, "", "")
 			btcTestnetProxy := proxy.NewProxy(btcTestnetNodeClient)
 			store := kv.NewTable(kv.NewMemDB(kv.JSONCodec), "test")
 			btcCache := cache.New(store, logger)
@@ -40,20 +40,24 @@ var _ = Describe("Server", func() {
 			time.Sleep(2 * time.Second)
 
 			phi.ParForAll(5, func(i int) {
+				defer GinkgoRecover()
+
 				buf := bytes.NewBuffer([]byte(`{"jsonrpc": "2.0", "method": "listunspent", "id": 1, "params": [0, 999999, "mwdXtp8ow61jcG1EXYVy5aZqksxvtrnNsL"]}`))
-				resp, err := http.Post("http://127.0.0.1:5000/btc/testnet/", "application/json", buf)
-				if err != nil {
-					logger.Println(err)
-					return
-				}
-				luResp := btcrpcclient.ListUnspentResponse{}
-				respBytes, err := ioutil.ReadAll(resp.Body)
-				if err := json.NewDecoder(resp.Body).Decode(&luResp); err != nil {
-					logger.Println(err)
-					return
-				}
-				fmt.Println(string(respBytes))
+				resp, err := http.Post("http://127.0.0.1:5000/btc/testnet", "application/json", buf)
+				Expect(err).NotTo(HaveOccurred())
+				defer resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+				luResp := struct {
+					Result btcrpcclient.ListUnspentResponse `json:"result"`
+				}{}
+				Expect(json.NewDecoder(resp.Body).Decode(&luResp)).To(Succeed())
+				Expect(luResp.Result).To(HaveLen(1))
+				Expect(luResp.Result[0].TxID).To(Equal("aa"))
 			})
+
+			// The results of listunspent are not cached, so every request is sent to the node.
+			Expect(node.Calls("listunspent")).To(Equal(5))
 		})
 	})
 })
@@ -2,9 +2,77 @@ package api
 
 import "github.com/renproject/mercury/types"
 
+// ethWhitelist is the access level of each whitelisted Ethereum method.
+var ethWhitelist = map[string]types.AccessLevel{}
+
+// btcWhitelist is the access level of each whitelisted method of Bitcoin and its forks (ZCash, Bitcoin Cash, Litecoin
+// and Dogecoin).
+var btcWhitelist = map[string]types.AccessLevel{}
+
+func init() {
+	for _, method := range []string{
+		"eth_gasPrice", "eth_blockNumber", "eth_getBalance", "eth_getBlockByNumber", "eth_getTransactionCount",
+		"eth_call", "eth_estimateGas", "eth_pendingTransactions", "eth_getFilterChanges", "eth_getFilterLogs",
+		"eth_getLogs", "eth_getWork", "eth_getProof",
+	} {
+		ethWhitelist[method] = types.FullAccess
+	}
+	for _, method := range []string{
+		"net_version", "eth_chainId", "eth_getBlockTransactionCountByHash", "eth_getBlockTransactionCountByNumber", "eth_getStorageAt",
+		"eth_getUncleCountByBlockHash", "eth_getUncleCountByBlockNumber", "eth_getUncleByBlockHashAndIndex",
+		"eth_getUncleByBlockNumberAndIndex", "eth_sign", "eth_getCode", "eth_sendTransaction", "eth_sendRawTransaction",
+		"eth_getBlockByHash", "eth_getTransactionByHash", "eth_getTransactionByBlockHashAndIndex",
+		"eth_getTransactionByBlockNumberAndIndex", "eth_getTransactionReceipt", "eth_newFilter", "eth_newBlockFilter",
+		"eth_newPendingTransactionFilter", "eth_uninstallFilter", "eth_submitWork", "eth_submitHashrate",
+	} {
+		ethWhitelist[method] = types.CachedAccess
+	}
+
+	for _, method := range []string{
+		"listunspent", "gettxout", "getrawtransaction", "getblockcount", "getbestblockhash", "getblockheader",
+		"getblock", "estimatesmartfee", "getmempoolentry", "testmempoolaccept",
+	} {
+		btcWhitelist[method] = types.FullAccess
+	}
+	for _, method := range []string{"sendrawtransaction", "decoderawtransaction"} {
+		btcWhitelist[method] = types.CachedAccess
+	}
+}
+
+// Whitelist returns the access level of each method which is available on the network.
+func Whitelist(network types.Network) map[string]types.AccessLevel {
+	var whitelist map[string]types.AccessLevel
+	switch network.Chain() {
+	case types.Bitcoin, types.ZCash, types.BitcoinCash, types.Litecoin, types.Dogecoin:
+		whitelist = btcWhitelist
+	case types.Ethereum:
+		whitelist = ethWhitelist
+	}
+
+	levels := make(map[string]types.AccessLevel, len(whitelist))
+	for method, level := range whitelist {
+		levels[method] = level
+	}
+	return levels
+}
+
+// MergeWhitelist returns the default whitelist of the network with the access level of the given methods overridden.
+// Methods with the `NoAccess` level are removed from the whitelist.
+func MergeWhitelist(network types.Network, overrides map[string]types.AccessLevel) map[string]types.AccessLevel {
+	whitelist := Whitelist(network)
+	for method, level := range overrides {
+		if level == types.NoAccess {
+			delete(whitelist, method)
+			continue
+		}
+		whitelist[method] = level
+	}
+	return whitelist
+}
+
 func WhitelistLevel(network types.Network, method string) types.AccessLevel {
 	switch network.Chain() {
-	case types.Bitcoin, types.ZCash, types.BitcoinCash:
+	case types.Bitcoin, types.ZCash, types.BitcoinCash, types.Litecoin, types.Dogecoin:
 		return BtcWhitelistLevel(method)
 	case types.Ethereum:
 		return EthWhitelistLevel(method)
@@ -14,30 +82,9 @@ func WhitelistLevel(network types.Network, method string) types.AccessLevel {
 }
 
 func EthWhitelistLevel(method string) types.AccessLevel {
-	switch method {
-	case "eth_gasPrice", "eth_blockNumber", "eth_getBalance", "eth_getBlockByNumber", "eth_getTransactionCount",
-		"eth_call", "eth_estimateGas", "eth_pendingTransactions", "eth_getFilterChanges", "eth_getFilterLogs",
-		"eth_getLogs", "eth_getWork", "eth_getProof":
-		return types.FullAccess
-	case "net_version", "eth_chainId", "eth_getBlockTransactionCountByHash", "eth_getBlockTransactionCountByNumber", "eth_getStorageAt",
-		"eth_getUncleCountByBlockHash", "eth_getUncleCountByBlockNumber", "eth_getUncleByBlockHashAndIndex",
-		"eth_getUncleByBlockNumberAndIndex", "eth_sign", "eth_getCode", "eth_sendTransaction", "eth_sendRawTransaction",
-		"eth_getBlockByHash", "eth_getTransactionByHash", "eth_getTransactionByBlockHashAndIndex",
-		"eth_getTransactionByBlockNumberAndIndex", "eth_getTransactionReceipt", "eth_newFilter", "eth_newBlockFilter",
-		"eth_newPendingTransactionFilter", "eth_uninstallFilter", "eth_submitWork", "eth_submitHashrate":
-		return types.CachedAccess
-	default:
-		return types.NoAccess
-	}
+	return ethWhitelist[method]
 }
 
 func BtcWhitelistLevel(method string) types.AccessLevel {
-	switch method {
-	case "listunspent", "gettxout", "getrawtransaction":
-		return types.FullAccess
-	case "sendrawtransaction":
-		return types.CachedAccess
-	default:
-		return types.NoAccess
-	}
+	return btcWhitelist[method]
 }
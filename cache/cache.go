@@ -7,6 +7,7 @@ package cache
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/renproject/kv"
 	"github.com/renproject/mercury/types"
@@ -22,29 +23,68 @@ type Cache struct {
 	locks  sync.Map
 	store  kv.Table
 	logger logrus.FieldLogger
+	opts   Options
 }
 
-// New returns a new Cache.
+// Options configure how results are cached.
+type Options struct {
+	// TTL is how long results are cached for. Results never expire if it is zero.
+	TTL time.Duration
+}
+
+// New returns a new Cache which never expires results.
 func New(store kv.Table, logger logrus.FieldLogger) *Cache {
+	return NewWithOptions(store, logger, Options{})
+}
+
+// NewWithOptions returns a new Cache which caches results using the given options.
+func NewWithOptions(store kv.Table, logger logrus.FieldLogger, opts Options) *Cache {
 	return &Cache{
 		locks:  sync.Map{},
 		store:  store,
 		logger: logger,
+		opts:   opts,
 	}
 }
 
+// entry is a result in the store and when it was stored.
+type entry struct {
+	Data     []byte `json:"data"`
+	StoredAt int64  `json:"storedAt"`
+}
+
+// Outcome describes how the result of a request was retrieved.
+type Outcome string
+
+const (
+	// Bypass is the outcome of requests which are not cached.
+	Bypass Outcome = "bypass"
+	// Hit is the outcome of requests whose result was already in the store.
+	Hit Outcome = "hit"
+	// Miss is the outcome of requests whose result was retrieved using f().
+	Miss Outcome = "miss"
+	// Shared is the outcome of requests which waited for another request to retrieve the result.
+	Shared Outcome = "shared"
+)
+
 // Get checks if the data for a given hash exists in the store, and if not, uses f() to retrieve the result. Any
 // requests that are sent while the result is being retrieved, wait until the first function call returns. This prevents
 // the function f() from being called multiple times for the same request.
 func (cache *Cache) Get(level types.AccessLevel, hash string, f func() ([]byte, error)) ([]byte, error) {
+	data, _, err := cache.Lookup(level, hash, f)
+	return data, err
+}
+
+// Lookup is the same as Get, but it also returns how the result was retrieved.
+func (cache *Cache) Lookup(level types.AccessLevel, hash string, f func() ([]byte, error)) ([]byte, Outcome, error) {
 	if level == 2 {
-		return f()
+		data, err := f()
+		return data, Bypass, err
 	}
 
 	// Check if the result already exists in the store.
-	var data []byte
-	if err := cache.store.Get(hash, &data); err == nil {
-		return data, nil
+	if data, ok := cache.get(hash); ok {
+		return data, Hit, nil
 	}
 
 	// If not, check to see if a mutex exists.
@@ -60,24 +100,36 @@ func (cache *Cache) Get(level types.AccessLevel, hash string, f func() ([]byte,
 
 		data, err := f()
 		if err != nil {
-			return nil, err
+			return nil, Miss, err
 		}
 
-		if err := cache.store.Insert(hash, data); err != nil {
+		if err := cache.store.Insert(hash, entry{Data: data, StoredAt: time.Now().UnixNano()}); err != nil {
 			cache.logger.Errorf("cannot store response data: %v", err)
 		}
 
-		return data, nil
+		return data, Miss, nil
 	}
 
 	// Wait for the response to be written to the store.
 	mu := v.(*sync.RWMutex)
 	mu.RLock()
-	if err := cache.store.Get(hash, &data); err != nil {
-		mu.RUnlock()
-		return nil, ErrNoResponse
-	}
+	data, ok := cache.get(hash)
 	mu.RUnlock()
+	if !ok {
+		return nil, Shared, ErrNoResponse
+	}
 
-	return data, nil
+	return data, Shared, nil
+}
+
+// get returns the result for the hash if it is in the store and has not expired.
+func (cache *Cache) get(hash string) ([]byte, bool) {
+	var e entry
+	if err := cache.store.Get(hash, &e); err != nil {
+		return nil, false
+	}
+	if cache.opts.TTL > 0 && time.Since(time.Unix(0, e.StoredAt)) > cache.opts.TTL {
+		return nil, false
+	}
+	return e.Data, true
 }
@@ -3,6 +3,7 @@ package cache_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 	. "github.com/renproject/mercury/cache"
 
 	"github.com/renproject/kv"
+	"github.com/renproject/mercury/testutil/fakenode"
 	"github.com/renproject/phi"
 	"github.com/sirupsen/logrus"
 )
@@ -127,6 +129,138 @@ var _ = Describe("Cache", func() {
 			})
 		})
 	})
+
+	Context("when looking up requests", func() {
+		It("should return how the result was retrieved", func() {
+			store := kv.NewTable(kv.NewMemDB(kv.JSONCodec), "test")
+			cache := New(store, logrus.StandardLogger())
+			f := func() ([]byte, error) { return []byte("response"), nil }
+
+			_, outcome, err := cache.Lookup(2, "hash", f)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outcome).To(Equal(Bypass))
+
+			_, outcome, err = cache.Lookup(1, "hash", f)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outcome).To(Equal(Miss))
+
+			resp, outcome, err := cache.Lookup(1, "hash", f)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outcome).To(Equal(Hit))
+			Expect(resp).To(Equal([]byte("response")))
+		})
+
+		It("should retrieve the result again once it has expired", func() {
+			store := kv.NewTable(kv.NewMemDB(kv.JSONCodec), "test")
+			cache := NewWithOptions(store, logrus.StandardLogger(), Options{TTL: 100 * time.Millisecond})
+			numRequests := 0
+			f := func() ([]byte, error) {
+				numRequests++
+				return []byte("response"), nil
+			}
+
+			_, outcome, err := cache.Lookup(1, "hash", f)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outcome).To(Equal(Miss))
+			_, outcome, err = cache.Lookup(1, "hash", f)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outcome).To(Equal(Hit))
+
+			time.Sleep(200 * time.Millisecond)
+			_, outcome, err = cache.Lookup(1, "hash", f)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outcome).To(Equal(Miss))
+			Expect(numRequests).To(Equal(2))
+		})
+	})
+
+	Context("when the node is faulty", func() {
+		// fetch returns a function which sends a request to the node, and returns an error if the node does not
+		// respond with a 200 status code.
+		fetch := func(node *fakenode.Node) func() ([]byte, error) {
+			return func() ([]byte, error) {
+				resp, err := http.Post(node.URL, "application/json", bytes.NewBufferString(`{"id":1,"method":"getblockcount"}`))
+				if err != nil {
+					return nil, err
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+				}
+				return ioutil.ReadAll(resp.Body)
+			}
+		}
+
+		It("should coalesce concurrent requests into a single request", func() {
+			cache := New(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "test"), logrus.StandardLogger())
+			node := fakenode.New()
+			defer node.Close()
+			node.SetLatency(500 * time.Millisecond)
+			Expect(node.Respond("getblockcount", 100)).To(Succeed())
+
+			outcomes := make([]Outcome, 8)
+			phi.ParForAll(outcomes, func(i int) {
+				defer GinkgoRecover()
+
+				// Wait for the first request to start before sending the others.
+				if i > 0 {
+					time.Sleep(100 * time.Millisecond)
+				}
+				resp, outcome, err := cache.Lookup(1, "hash", fetch(node))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp).To(MatchJSON(`{"result":100,"error":null,"id":1}`))
+				outcomes[i] = outcome
+			})
+			Expect(node.Requests()).To(Equal(1))
+			Expect(outcomes[0]).To(Equal(Miss))
+			for _, outcome := range outcomes[1:] {
+				Expect(outcome).To(Equal(Shared))
+			}
+		})
+
+		It("should not cache failed requests", func() {
+			cache := New(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "test"), logrus.StandardLogger())
+			node := fakenode.New()
+			defer node.Close()
+			Expect(node.Respond("getblockcount", 100)).To(Succeed())
+			node.Inject(fakenode.TooManyRequests, 1)
+			node.Inject(fakenode.ServerError, 1)
+
+			for i := 0; i < 2; i++ {
+				_, outcome, err := cache.Lookup(1, "hash", fetch(node))
+				Expect(err).To(HaveOccurred())
+				Expect(outcome).To(Equal(Miss))
+			}
+			_, outcome, err := cache.Lookup(1, "hash", fetch(node))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outcome).To(Equal(Miss))
+			_, outcome, err = cache.Lookup(1, "hash", fetch(node))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outcome).To(Equal(Hit))
+			Expect(node.Requests()).To(Equal(3))
+		})
+
+		It("should fail requests which are waiting for a request which fails", func() {
+			cache := New(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "test"), logrus.StandardLogger())
+			node := fakenode.New()
+			defer node.Close()
+			node.SetLatency(500 * time.Millisecond)
+			node.Inject(fakenode.BadGateway, 1)
+
+			errs := make([]error, 4)
+			phi.ParForAll(errs, func(i int) {
+				if i > 0 {
+					time.Sleep(100 * time.Millisecond)
+				}
+				_, _, errs[i] = cache.Lookup(1, "hash", fetch(node))
+			})
+			Expect(errs[0]).To(MatchError("unexpected status code: 502"))
+			for _, err := range errs[1:] {
+				Expect(err).To(Equal(ErrNoResponse))
+			}
+			Expect(node.Requests()).To(Equal(1))
+		})
+	})
 })
 
 func getResponse(url string, numRequests *int) func() ([]byte, error) {
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/renproject/mercury/proxy"
+	"github.com/renproject/mercury/rpc"
+	"github.com/renproject/mercury/stat"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+	"github.com/renproject/mercury/types/ethtypes"
+)
+
+// The kinds of upstream clients.
+const (
+	kindNode     = "node"
+	kindEsplora  = "esplora"
+	kindElectrum = "electrum"
+	kindInfura   = "infura"
+)
+
+// archiveGroup is the proxy group of the archive nodes, which historical state queries are routed to.
+const archiveGroup = "archive"
+
+// upstreamConfig is an upstream node, indexer or provider of a network.
+type upstreamConfig struct {
+	Kind string `json:"kind"`
+
+	// Address is the URL of a node or an Esplora indexer, or the host and port of an Electrum server. It is not used by
+	// Infura.
+	Address  string `json:"address"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+
+	// Group is the proxy group of the upstream. It is empty for the default clients.
+	Group string `json:"group"`
+}
+
+// String returns a description of the upstream which does not contain any credentials.
+func (upstream upstreamConfig) String() string {
+	if upstream.Kind == kindInfura {
+		return kindInfura
+	}
+	if u, err := url.Parse(upstream.Address); err == nil && u.User != nil {
+		u.User = nil
+		return u.String()
+	}
+	return upstream.Address
+}
+
+// networkConfig is a network served by mercury and its upstreams.
+type networkConfig struct {
+	Network   types.Network
+	Cache     string
+	CacheTTL  time.Duration
+	MaxLag    uint64
+	Upstreams []upstreamConfig
+
+	// Whitelist overrides the access level of methods of the default whitelist.
+	Whitelist map[string]types.AccessLevel
+}
+
+// Name returns the name of the network used in the paths of the server (e.g. btc/mainnet).
+func (network networkConfig) Name() string {
+	return networkName(network.Network)
+}
+
+// config is the configuration of the server, which is read from the environment and the optional configuration file.
+// Only the networks and the callers whose usage is persisted are reloaded while the server is running.
+type config struct {
+	Networks       []networkConfig
+	TaggedKeys     map[string][]string
+	UsageKeys      []string
+	StatsRetention time.Duration
+	UsageDBPath    string
+	AdminToken     string
+}
+
+// fileConfig is the configuration file, which overrides the configuration of the networks read from the environment.
+type fileConfig struct {
+	Networks map[string]fileNetworkConfig `json:"networks"`
+}
+
+// fileNetworkConfig overrides the configuration of a network. The upstreams replace the upstreams read from the
+// environment if any are given.
+type fileNetworkConfig struct {
+	Disabled  bool                         `json:"disabled"`
+	Upstreams []upstreamConfig             `json:"upstreams"`
+	Whitelist map[string]types.AccessLevel `json:"whitelist"`
+	CacheTTL  string                       `json:"cacheTTL"`
+}
+
+// configFlag adds the flag for the path of the configuration file, which defaults to the MERCURY_CONFIG environment
+// variable.
+func configFlag(flags *flag.FlagSet) *string {
+	return flags.String("config", os.Getenv("MERCURY_CONFIG"), "path of the JSON configuration file")
+}
+
+// loadConfig reads the configuration from the environment, and applies the configuration file if a path is given.
+func loadConfig(path string) (config, error) {
+	cfg := config{
+		Networks: []networkConfig{
+			btcNetworkConfig(btctypes.BtcMainnet, "BITCOIN_MAINNET", "btc", btcMaxLag),
+			btcNetworkConfig(btctypes.ZecMainnet, "ZCASH_MAINNET", "zec", btcMaxLag),
+			btcNetworkConfig(btctypes.BchMainnet, "BCASH_MAINNET", "bch", btcMaxLag),
+			btcNetworkConfig(btctypes.LtcMainnet, "LITECOIN_MAINNET", "ltc", ltcMaxLag),
+			btcNetworkConfig(btctypes.DogeMainnet, "DOGECOIN_MAINNET", "doge", dogeMaxLag),
+			btcNetworkConfig(btctypes.BtcTestnet, "BITCOIN_TESTNET", "btcTest", btcMaxLag),
+			btcNetworkConfig(btctypes.ZecTestnet, "ZCASH_TESTNET", "zecTest", btcMaxLag),
+			btcNetworkConfig(btctypes.BchTestnet, "BCASH_TESTNET", "bchTest", btcMaxLag),
+			btcNetworkConfig(btctypes.LtcTestnet, "LITECOIN_TESTNET", "ltcTest", ltcMaxLag),
+			btcNetworkConfig(btctypes.DogeTestnet, "DOGECOIN_TESTNET", "dogeTest", dogeMaxLag),
+			btcNetworkConfig(btctypes.BtcSignet, "BITCOIN_SIGNET", "btcSignet", btcMaxLag),
+			btcNetworkConfig(btctypes.BtcRegtest, "BITCOIN_REGTEST", "btcRegtest", btcMaxLag),
+			btcNetworkConfig(btctypes.ZecRegnet, "ZCASH_REGTEST", "zecRegtest", btcMaxLag),
+			btcNetworkConfig(btctypes.BchRegtest, "BCASH_REGTEST", "bchRegtest", btcMaxLag),
+			ethNetworkConfig(ethtypes.Mainnet, "ETH_MAINNET", "eth"),
+			ethNetworkConfig(ethtypes.Kovan, "ETH_KOVAN", "ethKovan"),
+			ethNetworkConfig(ethtypes.Rinkeby, "ETH_RINKEBY", "ethRinkeby"),
+		},
+		// Each tag can have several comma separated keys, which are rotated when one of them is rate limited.
+		TaggedKeys: map[string][]string{
+			"":         apiKeys(os.Getenv("INFURA_KEY_DEFAULT")),
+			"swapperd": apiKeys(os.Getenv("INFURA_KEY_SWAPPERD")),
+			"darknode": apiKeys(os.Getenv("INFURA_KEY_DARKNODE")),
+			"renex":    apiKeys(os.Getenv("INFURA_KEY_RENEX")),
+			"renex-ui": apiKeys(os.Getenv("INFURA_KEY_RENEX_UI")),
+			"dcc":      apiKeys(os.Getenv("INFURA_KEY_DCC")),
+		},
+		// The usage of callers identified by these API keys is persisted separately, in addition to the usage of the tags.
+		UsageKeys:      apiKeys(os.Getenv("USAGE_API_KEYS")),
+		StatsRetention: stat.DefaultRetention,
+		UsageDBPath:    os.Getenv("USAGE_DB_PATH"),
+		AdminToken:     os.Getenv("ADMIN_TOKEN"),
+	}
+
+	// Keep request statistics for the configured retention period.
+	if statsRetention := os.Getenv("STATS_RETENTION"); statsRetention != "" {
+		var err error
+		if cfg.StatsRetention, err = time.ParseDuration(statsRetention); err != nil {
+			return config{}, fmt.Errorf("invalid STATS_RETENTION: %v", err)
+		}
+		if cfg.StatsRetention <= 0 {
+			return config{}, fmt.Errorf("invalid STATS_RETENTION: %v is not positive", cfg.StatsRetention)
+		}
+	}
+
+	if path != "" {
+		if err := cfg.load(path); err != nil {
+			return config{}, err
+		}
+	}
+
+	// Networks without any upstreams are not served.
+	networks := make([]networkConfig, 0, len(cfg.Networks))
+	for _, network := range cfg.Networks {
+		if len(network.Upstreams) > 0 {
+			networks = append(networks, network)
+		}
+	}
+	cfg.Networks = networks
+	return cfg, nil
+}
+
+// load applies the configuration file at the path.
+func (cfg *config) load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open config file: %v", err)
+	}
+	defer file.Close()
+	fileCfg := fileConfig{}
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fileCfg); err != nil {
+		return fmt.Errorf("cannot decode config file: %v", err)
+	}
+	if err := cfg.apply(fileCfg); err != nil {
+		return fmt.Errorf("invalid config file: %v", err)
+	}
+	return nil
+}
+
+// apply overrides the configuration of the networks using the configuration file.
+func (cfg *config) apply(fileCfg fileConfig) error {
+	networks := make([]networkConfig, 0, len(cfg.Networks))
+	for name := range fileCfg.Networks {
+		if _, err := cfg.network(name); err != nil {
+			return err
+		}
+	}
+	for _, network := range cfg.Networks {
+		override, ok := fileCfg.Networks[network.Name()]
+		if !ok {
+			networks = append(networks, network)
+			continue
+		}
+		if override.Disabled {
+			continue
+		}
+		if len(override.Upstreams) > 0 {
+			network.Upstreams = override.Upstreams
+		}
+		network.Whitelist = override.Whitelist
+		if override.CacheTTL != "" {
+			ttl, err := time.ParseDuration(override.CacheTTL)
+			if err != nil || ttl < 0 {
+				return fmt.Errorf("%s: invalid cache ttl: %s", network.Name(), override.CacheTTL)
+			}
+			network.CacheTTL = ttl
+		}
+		networks = append(networks, network)
+	}
+	cfg.Networks = networks
+	return nil
+}
+
+// btcNetworkConfig reads the upstream of a Bitcoin-like network from the environment variables with the given prefix.
+// Bitcoin networks can use an Esplora indexer or an Electrum server instead of a node. The network has no upstreams if
+// none of the variables are set.
+func btcNetworkConfig(network btctypes.Network, prefix, cache string, maxLag uint64) networkConfig {
+	upstream := upstreamConfig{
+		Kind:     kindNode,
+		Address:  os.Getenv(prefix + "_RPC_URL"),
+		User:     os.Getenv(prefix + "_RPC_USERNAME"),
+		Password: os.Getenv(prefix + "_RPC_PASSWORD"),
+	}
+	if network.Chain() == types.Bitcoin {
+		if esploraURL := os.Getenv(prefix + "_ESPLORA_URL"); esploraURL != "" {
+			upstream = upstreamConfig{Kind: kindEsplora, Address: esploraURL}
+		} else if electrumAddr := os.Getenv(prefix + "_ELECTRUM_ADDRESS"); electrumAddr != "" {
+			upstream = upstreamConfig{Kind: kindElectrum, Address: electrumAddr}
+		}
+	}
+	upstreams := []upstreamConfig{}
+	if upstream.Address != "" {
+		upstreams = append(upstreams, upstream)
+	}
+	return networkConfig{
+		Network:   network,
+		Cache:     cache,
+		MaxLag:    maxLag,
+		Upstreams: upstreams,
+	}
+}
+
+// ethNetworkConfig reads the upstreams of an Ethereum network from the environment variables with the given prefix.
+// Infura is used unless a node is given, and historical state queries are routed to an archive node if one is given.
+func ethNetworkConfig(network ethtypes.Network, prefix, cache string) networkConfig {
+	upstream := upstreamConfig{Kind: kindInfura}
+	if nodeURL := os.Getenv(prefix + "_RPC_URL"); nodeURL != "" {
+		upstream = upstreamConfig{
+			Kind:     kindNode,
+			Address:  nodeURL,
+			User:     os.Getenv(prefix + "_RPC_USERNAME"),
+			Password: os.Getenv(prefix + "_RPC_PASSWORD"),
+		}
+	}
+	upstreams := []upstreamConfig{upstream}
+	if archiveURL := os.Getenv(prefix + "_ARCHIVE_RPC_URL"); archiveURL != "" {
+		upstreams = append(upstreams, upstreamConfig{
+			Kind:     kindNode,
+			Address:  archiveURL,
+			User:     os.Getenv(prefix + "_ARCHIVE_RPC_USERNAME"),
+			Password: os.Getenv(prefix + "_ARCHIVE_RPC_PASSWORD"),
+			Group:    archiveGroup,
+		})
+	}
+	return networkConfig{
+		Network:   network,
+		Cache:     cache,
+		MaxLag:    ethMaxLag,
+		Upstreams: upstreams,
+	}
+}
+
+// validate returns the problems with the configuration. The server is not started or reloaded if there are problems.
+func (cfg config) validate() []error {
+	errs := []error{}
+	for _, network := range cfg.Networks {
+		for _, upstream := range network.Upstreams {
+			if err := cfg.validateUpstream(network.Network, upstream); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid %s upstream: %v", network.Name(), upstream.Kind, err))
+			}
+		}
+	}
+	if cfg.StatsRetention <= 0 {
+		errs = append(errs, fmt.Errorf("stats retention must be positive: %v", cfg.StatsRetention))
+	}
+	return errs
+}
+
+func (cfg config) validateUpstream(network types.Network, upstream upstreamConfig) error {
+	switch upstream.Kind {
+	case kindNode, kindEsplora:
+		if upstream.Kind == kindEsplora && network.Chain() != types.Bitcoin {
+			return fmt.Errorf("unsupported chain: %s", network.Chain())
+		}
+		if upstream.Address == "" {
+			return fmt.Errorf("missing url")
+		}
+		u, err := url.Parse(upstream.Address)
+		if err != nil {
+			return fmt.Errorf("cannot parse url: %v", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be http(s)://host[:port][/path]: %s", upstream)
+		}
+	case kindElectrum:
+		if network.Chain() != types.Bitcoin {
+			return fmt.Errorf("unsupported chain: %s", network.Chain())
+		}
+		if _, _, err := net.SplitHostPort(upstream.Address); err != nil {
+			return fmt.Errorf("address must be host:port: %v", err)
+		}
+	case kindInfura:
+		if network.Chain() != types.Ethereum {
+			return fmt.Errorf("unsupported chain: %s", network.Chain())
+		}
+		if len(cfg.TaggedKeys[""]) == 0 {
+			return fmt.Errorf("missing API key for the default tag (INFURA_KEY_DEFAULT)")
+		}
+	default:
+		return fmt.Errorf("unknown kind")
+	}
+	return nil
+}
+
+// client returns the client for the upstream of the network. An error is returned if the kind of the upstream does not
+// support the chain of the network.
+func (cfg config) client(network types.Network, upstream upstreamConfig) (rpc.Client, error) {
+	switch upstream.Kind {
+	case kindEsplora, kindElectrum:
+		btcNetwork, ok := network.(btctypes.Network)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported %s upstream", networkName(network), upstream.Kind)
+		}
+		if upstream.Kind == kindEsplora {
+			return rpc.NewEsploraClient(upstream.Address, btcNetwork), nil
+		}
+		return rpc.NewElectrumClient(upstream.Address, btcNetwork), nil
+	case kindInfura:
+		ethNetwork, ok := network.(ethtypes.Network)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported %s upstream", networkName(network), upstream.Kind)
+		}
+		return rpc.NewInfuraClient(ethNetwork, cfg.TaggedKeys), nil
+	default:
+		return rpc.NewClient(upstream.Address, upstream.User, upstream.Password), nil
+	}
+}
+
+// proxy returns a proxy for the upstreams of the network. Historical state queries are routed to the archive group if
+// the network has one, and fallback to the default clients.
+func (cfg config) proxy(network networkConfig) (*proxy.Proxy, error) {
+	clients := []rpc.Client{}
+	groups := map[string]proxy.Group{}
+	for _, upstream := range network.Upstreams {
+		client, err := cfg.client(network.Network, upstream)
+		if err != nil {
+			return nil, err
+		}
+		if upstream.Group == "" {
+			clients = append(clients, client)
+			continue
+		}
+		group := groups[upstream.Group]
+		group.Clients = append(group.Clients, client)
+		group.Fallbacks = []string{proxy.DefaultGroup}
+		groups[upstream.Group] = group
+	}
+	if len(groups) == 0 {
+		return proxy.NewProxy(clients...), nil
+	}
+
+	rules := []proxy.Rule{}
+	if _, ok := groups[archiveGroup]; ok {
+		rules = append(rules,
+			proxy.NewRule("eth_getBalance", archiveGroup, proxy.HistoricalBlock(1)),
+			proxy.NewRule("eth_getTransactionCount", archiveGroup, proxy.HistoricalBlock(1)),
+			proxy.NewRule("eth_getCode", archiveGroup, proxy.HistoricalBlock(1)),
+			proxy.NewRule("eth_call", archiveGroup, proxy.HistoricalBlock(1)),
+			proxy.NewRule("eth_getStorageAt", archiveGroup, proxy.HistoricalBlock(2)),
+			proxy.NewRule("eth_getProof", archiveGroup, proxy.HistoricalBlock(2)),
+		)
+	}
+	return proxy.NewRoutedProxy(clients, groups, rules), nil
+}
+
+// network returns the configured network with the given name (e.g. btc/mainnet).
+func (cfg config) network(name string) (networkConfig, error) {
+	names := make([]string, len(cfg.Networks))
+	for i, network := range cfg.Networks {
+		if network.Name() == strings.ToLower(name) {
+			return network, nil
+		}
+		names[i] = network.Name()
+	}
+	return networkConfig{}, fmt.Errorf("unknown network %s, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+// networkName returns the name of the network used in the paths of the server (e.g. btc/mainnet).
+func networkName(network types.Network) string {
+	return fmt.Sprintf("%s/%s", network.Chain(), network)
+}
+
+// apiKeys splits a comma separated list of API keys.
+func apiKeys(keys string) []string {
+	apiKeys := []string{}
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			apiKeys = append(apiKeys, key)
+		}
+	}
+	return apiKeys
+}
@@ -1,112 +1,118 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"time"
 
-	"github.com/renproject/kv"
-	"github.com/renproject/mercury/api"
-	"github.com/renproject/mercury/cache"
-	"github.com/renproject/mercury/proxy"
-	"github.com/renproject/mercury/rpc"
-	"github.com/renproject/mercury/types/btctypes"
-	"github.com/renproject/mercury/types/ethtypes"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// heightPollInterval is how often the height of each upstream node is checked.
+	heightPollInterval = 30 * time.Second
+
+	// The number of blocks an upstream node can be behind the tip before it is removed from rotation. Chains with shorter
+	// block times allow more lag.
+	btcMaxLag  = 2
+	ltcMaxLag  = 4
+	dogeMaxLag = 10
+	ethMaxLag  = 10
+
+	// usageFlushInterval is how often the usage of each caller is persisted.
+	usageFlushInterval = time.Minute
+)
+
+const usageText = `Usage: mercury <command> [flags]
+
+Commands:
+  serve                 start the server (default)
+  config validate       check the configuration read from the environment
+  upstreams check       print the height and latency of each upstream
+  whitelist list        print the whitelisted methods of each network
+  usage export          export the usage of each caller
+
+Run 'mercury <command> -h' for the flags of a command.
+`
+
 func main() {
 	// Initialise logger.
 	logger := logrus.StandardLogger()
 
-	db := kv.NewMemDB(kv.JSONCodec)
-
-	// Initialise stores.
-	ethRinkebyCache := cache.New(kv.NewTable(db, "ethRinkeby"), logger)
-	ethKovanCache := cache.New(kv.NewTable(db, "ethKovan"), logger)
-	btcTestCache := cache.New(kv.NewTable(db, "btcTest"), logger)
-	zecTestCache := cache.New(kv.NewTable(db, "zecTest"), logger)
-	bchTestCache := cache.New(kv.NewTable(db, "bchTest"), logger)
-	ethCache := cache.New(kv.NewTable(db, "eth"), logger)
-	btcCache := cache.New(kv.NewTable(db, "btc"), logger)
-	zecCache := cache.New(kv.NewTable(db, "zec"), logger)
-	bchCache := cache.New(kv.NewTable(db, "bch"), logger)
-
-	// Initialise Bitcoin API.
-	btcTestnetURL := os.Getenv("BITCOIN_TESTNET_RPC_URL")
-	btcTestnetUser := os.Getenv("BITCOIN_TESTNET_RPC_USERNAME")
-	btcTestnetPassword := os.Getenv("BITCOIN_TESTNET_RPC_PASSWORD")
-	btcTestnetNodeClient := rpc.NewClient(btcTestnetURL, btcTestnetUser, btcTestnetPassword)
-	btcTestnetProxy := proxy.NewProxy(btcTestnetNodeClient)
-	btcTestnetAPI := api.NewApi(btctypes.BtcTestnet, btcTestnetProxy, btcTestCache, logger)
-
-	btcMainnetURL := os.Getenv("BITCOIN_MAINNET_RPC_URL")
-	btcMainnetUser := os.Getenv("BITCOIN_MAINNET_RPC_USERNAME")
-	btcMainnetPassword := os.Getenv("BITCOIN_MAINNET_RPC_PASSWORD")
-	btcMainnetNodeClient := rpc.NewClient(btcMainnetURL, btcMainnetUser, btcMainnetPassword)
-	btcMainnetProxy := proxy.NewProxy(btcMainnetNodeClient)
-	btcMainnetAPI := api.NewApi(btctypes.BtcMainnet, btcMainnetProxy, btcCache, logger)
-
-	// Initialise ZCash API.
-	zecTestnetURL := os.Getenv("ZCASH_TESTNET_RPC_URL")
-	zecTestnetUser := os.Getenv("ZCASH_TESTNET_RPC_USERNAME")
-	zecTestnetPassword := os.Getenv("ZCASH_TESTNET_RPC_PASSWORD")
-	zecTestnetNodeClient := rpc.NewClient(zecTestnetURL, zecTestnetUser, zecTestnetPassword)
-	zecTestnetProxy := proxy.NewProxy(zecTestnetNodeClient)
-	zecTestnetAPI := api.NewApi(btctypes.ZecTestnet, zecTestnetProxy, zecTestCache, logger)
-
-	zecMainnetURL := os.Getenv("ZCASH_MAINNET_RPC_URL")
-	zecMainnetUser := os.Getenv("ZCASH_MAINNET_RPC_USERNAME")
-	zecMainnetPassword := os.Getenv("ZCASH_MAINNET_RPC_PASSWORD")
-	zecMainnetNodeClient := rpc.NewClient(zecMainnetURL, zecMainnetUser, zecMainnetPassword)
-	zecMainnetProxy := proxy.NewProxy(zecMainnetNodeClient)
-	zecMainnetAPI := api.NewApi(btctypes.ZecMainnet, zecMainnetProxy, zecCache, logger)
-
-	// Initialise BCash API.
-	bchTestnetURL := os.Getenv("BCASH_TESTNET_RPC_URL")
-	bchTestnetUser := os.Getenv("BCASH_TESTNET_RPC_USERNAME")
-	bchTestnetPassword := os.Getenv("BCASH_TESTNET_RPC_PASSWORD")
-	bchTestnetNodeClient := rpc.NewClient(bchTestnetURL, bchTestnetUser, bchTestnetPassword)
-	bchTestnetProxy := proxy.NewProxy(bchTestnetNodeClient)
-	bchTestnetAPI := api.NewApi(btctypes.BchTestnet, bchTestnetProxy, bchTestCache, logger)
-
-	bchMainnetURL := os.Getenv("BCASH_MAINNET_RPC_URL")
-	bchMainnetUser := os.Getenv("BCASH_MAINNET_RPC_USERNAME")
-	bchMainnetPassword := os.Getenv("BCASH_MAINNET_RPC_PASSWORD")
-	bchMainnetNodeClient := rpc.NewClient(bchMainnetURL, bchMainnetUser, bchMainnetPassword)
-	bchMainnetProxy := proxy.NewProxy(bchMainnetNodeClient)
-	bchMainnetAPI := api.NewApi(btctypes.BchMainnet, bchMainnetProxy, bchCache, logger)
-
-	// Initialize Ethereum API.
-	taggedKeys := map[string]string{
-		"":         os.Getenv("INFURA_KEY_DEFAULT"),
-		"swapperd": os.Getenv("INFURA_KEY_SWAPPERD"),
-		"darknode": os.Getenv("INFURA_KEY_DARKNODE"),
-		"renex":    os.Getenv("INFURA_KEY_RENEX"),
-		"renex-ui": os.Getenv("INFURA_KEY_RENEX_UI"),
-		"dcc":      os.Getenv("INFURA_KEY_DCC"),
+	if err := run(os.Args[1:], os.Stdout, logger); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		logger.Fatalf("%v", err)
 	}
-	infuraMainnetClient := rpc.NewInfuraClient(ethtypes.Mainnet, taggedKeys)
-	ethMainnetProxy := proxy.NewProxy(infuraMainnetClient)
-	ethMainnetAPI := api.NewApi(ethtypes.Mainnet, ethMainnetProxy, ethCache, logger)
-
-	infuraRinkebyClient := rpc.NewInfuraClient(ethtypes.Rinkeby, taggedKeys)
-	ethRinkebyProxy := proxy.NewProxy(infuraRinkebyClient)
-	ethRinkebyAPI := api.NewApi(ethtypes.Rinkeby, ethRinkebyProxy, ethRinkebyCache, logger)
-
-	var testnetClient rpc.Client
-	ethKovanRPCURL := os.Getenv("ETH_KOVAN_RPC_URL")
-	if ethKovanRPCURL == "" {
-		logger.Infof("Using Infura")
-		testnetClient = rpc.NewInfuraClient(ethtypes.Kovan, taggedKeys)
-	} else {
-		logger.Infof("Using local ETH node at: %s", ethKovanRPCURL)
-		ethKovanUser := os.Getenv("ETH_KOVAN_RPC_USERNAME")
-		ethKovanPassword := os.Getenv("ETH_KOVAN_RPC_PASSWORD")
-		testnetClient = rpc.NewClient(ethKovanRPCURL, ethKovanUser, ethKovanPassword)
+}
+
+// run executes the command given by the arguments. The server is started if no command is given.
+func run(args []string, w io.Writer, logger logrus.FieldLogger) error {
+	if len(args) == 0 {
+		return serve(args, logger)
 	}
-	ethTestnetProxy := proxy.NewProxy(testnetClient)
-	ethTestnetAPI := api.NewApi(ethtypes.Kovan, ethTestnetProxy, ethKovanCache, logger)
 
-	// Set-up and start the server.
-	server := api.NewServer(logger, "5000", btcMainnetAPI, zecMainnetAPI, bchMainnetAPI, btcTestnetAPI, zecTestnetAPI, bchTestnetAPI, ethMainnetAPI, ethTestnetAPI, ethRinkebyAPI)
-	server.Run()
+	command, args := args[0], args[1:]
+	switch command {
+	case "serve":
+		return serve(args, logger)
+	case "config":
+		return subcommand(args, "validate", func(args []string) error { return validateConfig(args, w) })
+	case "upstreams":
+		return subcommand(args, "check", func(args []string) error { return checkUpstreams(args, w) })
+	case "whitelist":
+		return subcommand(args, "list", func(args []string) error { return listWhitelist(args, w) })
+	case "usage":
+		return subcommand(args, "export", func(args []string) error {
+			if err := exportUsage(args, w); err != nil {
+				return fmt.Errorf("cannot export usage: %v", err)
+			}
+			return nil
+		})
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(w, usageText)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usageText)
+		return fmt.Errorf("unknown command: %s", command)
+	}
+}
+
+// subcommand runs f with the remaining arguments if the first argument is the expected subcommand.
+func subcommand(args []string, name string, f func(args []string) error) error {
+	if len(args) == 0 || args[0] != name {
+		fmt.Fprint(os.Stderr, usageText)
+		return fmt.Errorf("expected subcommand: %s", name)
+	}
+	return f(args[1:])
+}
+
+// validateConfig writes the problems with the configuration, or a summary of the networks if it is valid. It returns an
+// error if there are any problems.
+func validateConfig(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	configPath := configFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if errs := cfg.validate(); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintln(w, err)
+		}
+		return fmt.Errorf("found %d problems in the configuration", len(errs))
+	}
+
+	for _, network := range cfg.Networks {
+		fmt.Fprintf(w, "%s: %d upstreams\n", network.Name(), len(network.Upstreams))
+	}
+	fmt.Fprintln(w, "configuration is valid")
+	return nil
 }
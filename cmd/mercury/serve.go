@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/renproject/kv"
+	"github.com/renproject/mercury/api"
+	"github.com/renproject/mercury/cache"
+	"github.com/renproject/mercury/proxy"
+	"github.com/renproject/mercury/stat"
+	"github.com/renproject/mercury/usage"
+	"github.com/sirupsen/logrus"
+)
+
+// serve starts the server using the configuration from the environment and the configuration file. The networks are
+// reloaded from the configuration when the process receives SIGHUP or the reload admin endpoint is called.
+func serve(args []string, logger logrus.FieldLogger) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	configPath := configFlag(flags)
+	port := flags.String("port", "5000", "port to listen on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	db := kv.NewMemDB(kv.JSONCodec)
+
+	// Persist the usage of each caller if a database path is given.
+	usageStore := usage.NewWithOptions(kv.NewTable(db, "usage"), usageOptions(cfg))
+	if cfg.UsageDBPath != "" {
+		logger.Infof("Persisting usage at: %s", cfg.UsageDBPath)
+		usageStore = usage.NewWithOptions(kv.NewTable(kv.NewLevelDB(cfg.UsageDBPath, kv.JSONCodec), "usage"), usageOptions(cfg))
+	}
+	go func() {
+		if err := usageStore.Run(context.Background(), usageFlushInterval); err != nil {
+			logger.Errorf("cannot persist usage: %v", err)
+		}
+	}()
+
+	// The APIs are replaced on every reload. The upstreams of the previous APIs stop being monitored once they have been
+	// replaced, but requests which are in-flight still finish using them.
+	var server *api.Server
+	var mu sync.Mutex
+	stopMonitoring := func() {}
+	reload := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		// The current APIs are kept if the configuration is invalid.
+		ctx, cancel := context.WithCancel(context.Background())
+		apis, err := newApis(ctx, cfg, db, logger)
+		if err != nil {
+			cancel()
+			return err
+		}
+		server.SetApis(apis...)
+		usageStore.SetOptions(usageOptions(cfg))
+		stopMonitoring()
+		stopMonitoring = cancel
+		return nil
+	}
+
+	// Set-up and start the server.
+	s := stat.NewWithRetention(cfg.StatsRetention)
+	opts := api.ServerOptions{
+		Stat:       &s,
+		Usage:      usageStore,
+		AdminToken: cfg.AdminToken,
+		Reload:     reload,
+	}
+	server = api.NewServerWithOptions(logger, *port, opts)
+	if err := reload(); err != nil {
+		return err
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			logger.Infof("Reloading configuration...")
+			if err := reload(); err != nil {
+				logger.Errorf("cannot reload configuration: %v", err)
+			}
+		}
+	}()
+
+	server.Run()
+	return nil
+}
+
+// usageOptions returns the options of the usage store. Only the configured tags and API keys are persisted, so that
+// callers cannot create an unlimited number of records.
+func usageOptions(cfg config) usage.Options {
+	opts := usage.DefaultOptions()
+	for tag := range cfg.TaggedKeys {
+		if tag != "" {
+			opts.Callers = append(opts.Callers, api.TagCaller(tag))
+		}
+	}
+	for _, apiKey := range cfg.UsageKeys {
+		opts.Callers = append(opts.Callers, api.KeyCaller(apiKey))
+	}
+	return opts
+}
+
+// newApis returns the APIs of the configured networks. The upstreams of each network are monitored until the context
+// is done. An error is returned if the configuration is invalid.
+func newApis(ctx context.Context, cfg config, db kv.DB, logger logrus.FieldLogger) ([]api.BlockchainApi, error) {
+	if errs := cfg.validate(); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		return nil, fmt.Errorf("invalid configuration: %s", strings.Join(msgs, "; "))
+	}
+
+	// Build every proxy before monitoring any of them, so that nothing is started if one of them is invalid.
+	proxies := make([]*proxy.Proxy, len(cfg.Networks))
+	for i, network := range cfg.Networks {
+		networkProxy, err := cfg.proxy(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: %v", err)
+		}
+		proxies[i] = networkProxy
+	}
+
+	apis := make([]api.BlockchainApi, 0, len(cfg.Networks))
+	for i, network := range cfg.Networks {
+		for _, upstream := range network.Upstreams {
+			logger.Infof("Using %s upstream for %s: %s", upstream.Kind, network.Name(), upstream)
+		}
+		networkProxy := proxies[i]
+
+		// Cached results are kept across reloads as the cache of each network uses the same table.
+		networkCache := cache.NewWithOptions(kv.NewTable(db, network.Cache), logger, cache.Options{TTL: network.CacheTTL})
+		apis = append(apis, api.NewApiWithOptions(network.Network, networkProxy, networkCache, logger, api.ApiOptions{
+			Whitelist: network.Whitelist,
+		}))
+
+		// Remove upstream nodes which are lagging behind the tip from rotation.
+		go networkProxy.Monitor(ctx, network.Network, heightPollInterval, network.MaxLag)
+	}
+	return apis, nil
+}
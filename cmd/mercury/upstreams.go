@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/renproject/mercury/proxy"
+)
+
+// upstreamCheck is the result of requesting the height of an upstream.
+type upstreamCheck struct {
+	network  string
+	upstream upstreamConfig
+	height   uint64
+	latency  time.Duration
+	err      error
+}
+
+// checkUpstreams requests the height of every configured upstream in parallel and writes the height and latency of
+// each of them. It returns an error if any of the upstreams failed.
+func checkUpstreams(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("upstreams check", flag.ContinueOnError)
+	configPath := configFlag(flags)
+	networkName := flags.String("network", "", "only check the upstreams of this network (e.g. btc/mainnet)")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of each request")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	networks := cfg.Networks
+	if *networkName != "" {
+		network, err := cfg.network(*networkName)
+		if err != nil {
+			return err
+		}
+		networks = []networkConfig{network}
+	}
+
+	checks := []*upstreamCheck{}
+	var wg sync.WaitGroup
+	for _, network := range networks {
+		method := proxy.HeightMethod(network.Network.Chain())
+		for _, upstream := range network.Upstreams {
+			check := &upstreamCheck{network: network.Name(), upstream: upstream}
+			checks = append(checks, check)
+			if err := cfg.validateUpstream(network.Network, upstream); err != nil {
+				check.err = err
+				continue
+			}
+
+			client, err := cfg.client(network.Network, upstream)
+			if err != nil {
+				check.err = err
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+				defer cancel()
+
+				start := time.Now()
+				check.height, check.err = proxy.Height(ctx, client, method)
+				check.latency = time.Since(start)
+			}()
+		}
+	}
+	wg.Wait()
+
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NETWORK\tKIND\tGROUP\tUPSTREAM\tHEIGHT\tLATENCY\tERROR")
+	for _, check := range checks {
+		group := check.upstream.Group
+		if group == "" {
+			group = "default"
+		}
+		if check.err != nil {
+			failed++
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t-\t-\t%v\n", check.network, check.upstream.Kind, group, check.upstream, check.err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%v\t\n", check.network, check.upstream.Kind, group, check.upstream, check.height, check.latency.Round(time.Millisecond))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d upstreams failed", failed, len(checks))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/renproject/kv"
+	"github.com/renproject/mercury/usage"
+)
+
+// exportUsage writes the usage of each caller to the writer. The records are read from the database if a path is
+// given, and from the admin endpoint of a running server otherwise.
+func exportUsage(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("usage export", flag.ContinueOnError)
+	from := flags.String("from", "", "first day to export (YYYY-MM-DD)")
+	to := flags.String("to", "", "last day to export (YYYY-MM-DD)")
+	format := flags.String("format", usage.FormatCSV, "export format (csv or json)")
+	dbPath := flags.String("db", "", "path of the usage database, which cannot be used by a running server")
+	addr := flags.String("addr", "http://localhost:5000", "address of the running server")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *dbPath != "" {
+		db := kv.NewLevelDB(*dbPath, kv.JSONCodec)
+		defer db.Close()
+		records, err := usage.Records(kv.NewTable(db, "usage"), *from, *to)
+		if err != nil {
+			return err
+		}
+		return usage.Export(w, records, *format)
+	}
+
+	query := url.Values{}
+	query.Set("from", *from)
+	query.Set("to", *to)
+	query.Set("format", *format)
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/admin/usage?%s", *addr, query.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("ADMIN_TOKEN"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot query server: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, body)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
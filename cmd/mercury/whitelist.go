@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/renproject/mercury/api"
+)
+
+// listWhitelist writes the whitelisted methods of each network and their access level.
+func listWhitelist(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("whitelist list", flag.ContinueOnError)
+	configPath := configFlag(flags)
+	networkName := flags.String("network", "", "only list the methods of this network (e.g. btc/mainnet)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	networks := cfg.Networks
+	if *networkName != "" {
+		network, err := cfg.network(*networkName)
+		if err != nil {
+			return err
+		}
+		networks = []networkConfig{network}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NETWORK\tMETHOD\tACCESS")
+	for _, network := range networks {
+		whitelist := api.MergeWhitelist(network.Network, network.Whitelist)
+		methods := make([]string, 0, len(whitelist))
+		for method := range whitelist {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", network.Name(), method, whitelist[method])
+		}
+	}
+	return tw.Flush()
+}
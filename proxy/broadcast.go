@@ -0,0 +1,218 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/renproject/mercury/rpc"
+	"github.com/renproject/mercury/types"
+)
+
+// Policy defines how a request is sent to the upstream clients.
+type Policy uint8
+
+const (
+	// Failover sends the request to each client in turn until one of them succeeds.
+	Failover Policy = 0
+	// Broadcast sends the request to every client in parallel and succeeds if any of them succeeds.
+	Broadcast Policy = 1
+)
+
+// DefaultPolicies returns the policies used by a new Proxy. Transactions are broadcast to every upstream so that they
+// propagate even if some of the nodes are poorly connected.
+func DefaultPolicies() map[string]Policy {
+	return map[string]Policy{
+		"sendrawtransaction":     Broadcast,
+		"eth_sendRawTransaction": Broadcast,
+	}
+}
+
+// benignErrors are error messages returned by nodes when a transaction has already been submitted. Broadcasting a
+// transaction to a node that returns one of these errors is considered successful.
+var benignErrors = []string{
+	"already in mempool",
+	"already known",
+	"known transaction",
+	"transaction already in block chain",
+	"txn-already-in-mempool",
+	"txn-already-known",
+}
+
+// BroadcastResult is the result of broadcasting a request to a single upstream client.
+type BroadcastResult struct {
+	Upstream   int              `json:"upstream"`
+	StatusCode int              `json:"statusCode,omitempty"`
+	Result     json.RawMessage  `json:"result,omitempty"`
+	Error      *types.JSONError `json:"error,omitempty"`
+	Err        string           `json:"transportError,omitempty"`
+	Success    bool             `json:"success"`
+}
+
+// BroadcastResponse is a JSON-RPC response which includes the result from each of the upstream clients.
+type BroadcastResponse struct {
+	types.JSONResponse
+	Broadcast []BroadcastResult `json:"broadcast"`
+}
+
+// broadcast sends the request to all clients in parallel. If any of the clients succeeds (or returns a benign error),
+// the response contains the successful result. The results from each of the clients are included in the response for
+// diagnostics.
+func (proxy *Proxy) broadcast(ctx context.Context, clients []rpc.Client, r *http.Request, data []byte) (*http.Response, error) {
+	results := make([]BroadcastResult, len(clients))
+	responses := make([]types.JSONResponse, len(clients))
+	errs := types.NewErrList(len(clients))
+
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], responses[i], errs[i] = broadcastTo(ctx, clients[i], i, r, data)
+		}(i)
+	}
+	wg.Wait()
+
+	// Find the best response to return, preferring results over benign errors and benign errors over other errors.
+	best := -1
+	for i := range results {
+		if errs[i] == nil && (best == -1 || rank(results[i]) > rank(results[best])) {
+			best = i
+		}
+	}
+	if best == -1 {
+		return nil, errs
+	}
+
+	resp := BroadcastResponse{
+		JSONResponse: responses[best],
+		Broadcast:    results,
+	}
+	statusCode := results[best].StatusCode
+	if results[best].Success && resp.Error != nil {
+		// The transaction has already been accepted, so we return its hash instead of the benign error.
+		hash, err := txHash(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compute transaction hash: %v", err)
+		}
+		resp.Error = nil
+		resp.Result = hash
+		statusCode = http.StatusOK
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+// broadcastTo sends the request to a single client and decodes its response.
+func broadcastTo(ctx context.Context, client rpc.Client, i int, r *http.Request, data []byte) (BroadcastResult, types.JSONResponse, error) {
+	result := BroadcastResult{Upstream: i}
+	response := types.JSONResponse{}
+
+	resp, err := client.HandleRequest(ctx, r, data)
+	if err != nil {
+		result.Err = err.Error()
+		return result, response, err
+	}
+	defer resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		result.Err = err.Error()
+		return result, response, err
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		err = fmt.Errorf("cannot decode response body = %s, err = %v", body, err)
+		result.Err = err.Error()
+		return result, response, err
+	}
+
+	result.Result = response.Result
+	result.Error = response.Error
+	result.Success = response.Error == nil && resp.StatusCode == http.StatusOK || isBenign(response.Error)
+	return result, response, nil
+}
+
+// rank orders the results by how useful they are to the caller.
+func rank(result BroadcastResult) int {
+	switch {
+	case result.Success && result.Error == nil:
+		return 2
+	case result.Success:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// isBenign returns true if the error indicates that the node already knows about the transaction.
+func isBenign(err *types.JSONError) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Message)
+	for _, benign := range benignErrors {
+		if strings.Contains(msg, benign) {
+			return true
+		}
+	}
+	return false
+}
+
+// txHash returns the JSON encoded hash of the raw transaction being submitted by the request.
+func txHash(data []byte) (json.RawMessage, error) {
+	req := struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	var param string
+	if len(req.Params) == 0 || json.Unmarshal(req.Params[0], &param) != nil {
+		return nil, fmt.Errorf("missing raw transaction")
+	}
+
+	switch req.Method {
+	case "eth_sendRawTransaction":
+		rawTx, err := hexutil.Decode(param)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(crypto.Keccak256Hash(rawTx).Hex())
+	case "sendrawtransaction":
+		rawTx, err := hex.DecodeString(param)
+		if err != nil {
+			return nil, err
+		}
+		// The hash of a SegWit transaction does not include the witness data. Other transactions (including ZCash
+		// transactions which cannot be decoded here) are hashed as they are.
+		msgTx := new(wire.MsgTx)
+		if err := msgTx.Deserialize(bytes.NewReader(rawTx)); err == nil && msgTx.HasWitness() {
+			return json.Marshal(msgTx.TxHash().String())
+		}
+		return json.Marshal(chainhash.DoubleHashH(rawTx).String())
+	default:
+		return nil, fmt.Errorf("unsupported method: %s", req.Method)
+	}
+}
@@ -0,0 +1,127 @@
+package proxy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/proxy"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/mercury/rpc"
+)
+
+var _ = Describe("Broadcasting", func() {
+	rawTx := "0100000002b1112299cc324d935316497343d3657ef52d87fa05aa7ae98093bbd4800e9e4b0000000000ffffffffb4e9e0084dbb39089ea7aa50af78d25fe7563d343794613539d423cc9922111b0100000000ffffffff02409c0000000000001976a914bc6baeb5b0b5daa34c2318cc647a911dfe40f0b488ac08e80000000000001976a914a4e1dbf6f6c7404ee1d685e6128f449eb9ca263288ac00000000"
+	data := []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"sendrawtransaction","params":["%s",false]}`, rawTx))
+
+	broadcast := func(clients ...rpc.Client) (BroadcastResponse, int, error) {
+		req, err := http.NewRequest("POST", "", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := NewProxy(clients...).ProxyRequest(context.Background(), req, data)
+		if err != nil {
+			return BroadcastResponse{}, 0, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		broadcastResp := BroadcastResponse{}
+		Expect(json.Unmarshal(body, &broadcastResp)).To(Succeed())
+		return broadcastResp, resp.StatusCode, nil
+	}
+
+	Context("when submitting a transaction", func() {
+		It("should send the transaction to every client", func() {
+			var calls int64
+			result := `{"jsonrpc":"2.0","id":1,"result":"abcd","error":null}`
+			client := NewJSONClient(http.StatusOK, result, &calls)
+
+			resp, statusCode, err := broadcast(client, client, client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(calls).To(Equal(int64(3)))
+			Expect(resp.Error).To(BeNil())
+			Expect(string(resp.Result)).To(Equal(`"abcd"`))
+			Expect(resp.Broadcast).To(HaveLen(3))
+			for i, result := range resp.Broadcast {
+				Expect(result.Upstream).To(Equal(i))
+				Expect(result.Success).To(BeTrue())
+			}
+		})
+
+		It("should succeed if any of the clients succeeds", func() {
+			rejected := NewJSONClient(http.StatusInternalServerError, `{"result":null,"error":{"code":-26,"message":"min relay fee not met"},"id":1}`, nil)
+			accepted := NewJSONClient(http.StatusOK, `{"result":"abcd","error":null,"id":1}`, nil)
+
+			resp, statusCode, err := broadcast(NewMockErrorClient(), rejected, accepted)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(string(resp.Result)).To(Equal(`"abcd"`))
+			Expect(resp.Broadcast[0].Success).To(BeFalse())
+			Expect(resp.Broadcast[0].Err).To(Equal("error"))
+			Expect(resp.Broadcast[1].Success).To(BeFalse())
+			Expect(resp.Broadcast[1].Error.Code).To(Equal(-26))
+			Expect(resp.Broadcast[2].Success).To(BeTrue())
+		})
+
+		It("should treat benign errors as success and return the transaction hash", func() {
+			known := NewJSONClient(http.StatusInternalServerError, `{"result":null,"error":{"code":-27,"message":"Transaction already in block chain"},"id":1}`, nil)
+			inMempool := NewJSONClient(http.StatusInternalServerError, `{"result":null,"error":{"code":-26,"message":"txn-already-in-mempool"},"id":1}`, nil)
+
+			resp, statusCode, err := broadcast(known, inMempool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(resp.Error).To(BeNil())
+			Expect(resp.Broadcast[0].Success).To(BeTrue())
+			Expect(resp.Broadcast[1].Success).To(BeTrue())
+
+			txBytes, err := hex.DecodeString(rawTx)
+			Expect(err).ToNot(HaveOccurred())
+			msgTx := new(wire.MsgTx)
+			Expect(msgTx.Deserialize(bytes.NewReader(txBytes))).To(Succeed())
+			Expect(string(resp.Result)).To(Equal(fmt.Sprintf(`"%s"`, msgTx.TxHash().String())))
+		})
+
+		It("should return the error if every client rejects the transaction", func() {
+			rejected := NewJSONClient(http.StatusInternalServerError, `{"result":null,"error":{"code":-25,"message":"Missing inputs"},"id":1}`, nil)
+
+			resp, statusCode, err := broadcast(rejected, rejected)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statusCode).To(Equal(http.StatusInternalServerError))
+			Expect(resp.Error).ToNot(BeNil())
+			Expect(resp.Error.Message).To(Equal("Missing inputs"))
+		})
+
+		It("should return an error if no client responds", func() {
+			_, _, err := broadcast(NewMockErrorClient(), NewMockErrorClient())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+type jsonClient struct {
+	statusCode int
+	body       string
+	calls      *int64
+}
+
+func NewJSONClient(statusCode int, body string, calls *int64) rpc.Client {
+	return jsonClient{statusCode, body, calls}
+}
+
+func (client jsonClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
+	if client.calls != nil {
+		atomic.AddInt64(client.calls, 1)
+	}
+	return &http.Response{
+		StatusCode: client.statusCode,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(client.body)),
+	}, nil
+}
@@ -0,0 +1,256 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/renproject/mercury/rpc"
+	"github.com/renproject/mercury/types"
+)
+
+// Health is the status of the upstream clients of a Proxy. If none of the upstreams are healthy, requests are still sent
+// to all of them rather than failing, and `Fallback` is set.
+type Health struct {
+	Tip       uint64           `json:"tip"`
+	MaxLag    uint64           `json:"maxLag"`
+	Fallback  bool             `json:"fallback"`
+	Upstreams []UpstreamHealth `json:"upstreams"`
+}
+
+// UpstreamHealth is the status of a single upstream client. Clients are unhealthy if they cannot return their height, or
+// if they are more than `MaxLag` blocks behind the best known tip.
+type UpstreamHealth struct {
+	Upstream  int       `json:"upstream"`
+	Height    uint64    `json:"height"`
+	Lag       uint64    `json:"lag"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// monitor keeps track of the heights of the upstream clients. Clients which have not been polled yet are healthy.
+type monitor struct {
+	mu      *sync.RWMutex
+	tip     uint64
+	maxLag  uint64
+	heights map[rpc.Client]*UpstreamHealth
+}
+
+func newMonitor() monitor {
+	return monitor{
+		mu:      new(sync.RWMutex),
+		heights: map[rpc.Client]*UpstreamHealth{},
+	}
+}
+
+// HeightMethod returns the JSON-RPC method used to get the height of a node for the given chain.
+func HeightMethod(chain types.Chain) string {
+	switch chain {
+	case types.Ethereum:
+		return "eth_blockNumber"
+	default:
+		return "getblockcount"
+	}
+}
+
+// Monitor polls the height of every upstream client once per interval and tracks the best known tip of the network.
+// Clients which are more than maxLag blocks behind the tip are removed from rotation until they catch up. It blocks
+// until the context is done. Clients are identified by equality, so they need to be comparable (e.g. pointers).
+func (proxy *Proxy) Monitor(ctx context.Context, network types.Network, interval time.Duration, maxLag uint64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	method := HeightMethod(network.Chain())
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, interval)
+		proxy.PollHeights(pollCtx, method, maxLag)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PollHeights requests the height of every upstream client using the given method and updates their health.
+func (proxy *Proxy) PollHeights(ctx context.Context, method string, maxLag uint64) {
+	clients := proxy.allClients()
+	heights := make([]uint64, len(clients))
+	errs := make([]error, len(clients))
+
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			heights[i], errs[i] = Height(ctx, clients[i], method)
+		}(i)
+	}
+	wg.Wait()
+
+	proxy.monitor.mu.Lock()
+	defer proxy.monitor.mu.Unlock()
+
+	proxy.monitor.maxLag = maxLag
+	if tip := tip(heights, errs, maxLag); tip > proxy.monitor.tip {
+		proxy.monitor.tip = tip
+	}
+
+	now := time.Now()
+	proxy.monitor.heights = map[rpc.Client]*UpstreamHealth{}
+	for i, client := range clients {
+		status := &UpstreamHealth{
+			Upstream:  i,
+			UpdatedAt: now,
+		}
+		if errs[i] != nil {
+			status.Error = errs[i].Error()
+		} else {
+			status.Height = heights[i]
+			if heights[i] < proxy.monitor.tip {
+				status.Lag = proxy.monitor.tip - heights[i]
+			}
+			status.Healthy = status.Lag <= maxLag
+		}
+		proxy.monitor.heights[client] = status
+	}
+}
+
+// tip returns the highest height which was returned without an error. Heights which are more than maxLag blocks above
+// the median (the higher of the two middle heights if there is an even number of them) are ignored, so that a single
+// client reporting a bogus height cannot mark the others as lagging once a majority of the clients agree.
+func tip(heights []uint64, errs []error, maxLag uint64) uint64 {
+	polled := make([]uint64, 0, len(heights))
+	for i := range heights {
+		if errs[i] == nil {
+			polled = append(polled, heights[i])
+		}
+	}
+	if len(polled) == 0 {
+		return 0
+	}
+	sort.Slice(polled, func(i, j int) bool { return polled[i] < polled[j] })
+	median := polled[len(polled)/2]
+	for i := len(polled) - 1; i >= 0; i-- {
+		if polled[i] <= median+maxLag {
+			return polled[i]
+		}
+	}
+	return median
+}
+
+// Health returns the status of the upstream clients. The index of each upstream is its position in the default clients
+// followed by the clients of each group.
+func (proxy *Proxy) Health() Health {
+	proxy.monitor.mu.RLock()
+	defer proxy.monitor.mu.RUnlock()
+
+	health := Health{
+		Tip:       proxy.monitor.tip,
+		MaxLag:    proxy.monitor.maxLag,
+		Upstreams: []UpstreamHealth{},
+	}
+	for i, client := range proxy.allClients() {
+		status, ok := proxy.monitor.heights[client]
+		if !ok {
+			health.Upstreams = append(health.Upstreams, UpstreamHealth{Upstream: i, Healthy: true})
+			continue
+		}
+		health.Upstreams = append(health.Upstreams, *status)
+	}
+	health.Fallback = len(health.Upstreams) > 0
+	for _, status := range health.Upstreams {
+		if status.Healthy {
+			health.Fallback = false
+		}
+	}
+	return health
+}
+
+// healthy removes the unhealthy clients from the list. If none of the clients are healthy, the list is returned as it is
+// so that requests can still be attempted (see `Health.Fallback`).
+func (proxy *Proxy) healthy(clients []rpc.Client) []rpc.Client {
+	proxy.monitor.mu.RLock()
+	defer proxy.monitor.mu.RUnlock()
+
+	healthy := make([]rpc.Client, 0, len(clients))
+	for _, client := range clients {
+		if status, ok := proxy.monitor.heights[client]; !ok || status.Healthy {
+			healthy = append(healthy, client)
+		}
+	}
+	if len(healthy) == 0 {
+		return clients
+	}
+	return healthy
+}
+
+// allClients returns the unique clients of the Proxy: the default clients followed by the clients of each group (sorted
+// by name).
+func (proxy *Proxy) allClients() []rpc.Client {
+	clients := []rpc.Client{}
+	seen := map[rpc.Client]bool{}
+	add := func(client rpc.Client) {
+		if !seen[client] {
+			seen[client] = true
+			clients = append(clients, client)
+		}
+	}
+
+	for _, client := range proxy.Clients {
+		add(client)
+	}
+	for _, name := range proxy.groupNames() {
+		for _, client := range proxy.Groups[name].Clients {
+			add(client)
+		}
+	}
+	return clients
+}
+
+// Height returns the height reported by the client using the given method (see `HeightMethod`).
+func Height(ctx context.Context, client rpc.Client, method string) (uint64, error) {
+	r, err := http.NewRequest("POST", "/", nil)
+	if err != nil {
+		return 0, err
+	}
+	data := []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"%s","params":[]}`, method))
+
+	resp, err := client.HandleRequest(ctx, r, data)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	response := types.JSONResponse{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, fmt.Errorf("cannot decode response body = %s, err = %v", body, err)
+	}
+	if response.Error != nil {
+		return 0, fmt.Errorf("cannot get height: %s", response.Error.Message)
+	}
+
+	// Bitcoin nodes return the height as a number, while Ethereum nodes return a hex encoded string.
+	var height uint64
+	if err := json.Unmarshal(response.Result, &height); err == nil {
+		return height, nil
+	}
+	var hexHeight string
+	if err := json.Unmarshal(response.Result, &hexHeight); err != nil {
+		return 0, fmt.Errorf("cannot decode height = %s, err = %v", response.Result, err)
+	}
+	return hexutil.DecodeUint64(hexHeight)
+}
@@ -0,0 +1,171 @@
+package proxy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/proxy"
+
+	"github.com/renproject/mercury/rpc"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+var _ = Describe("Health", func() {
+	upstream := func(proxy *Proxy) string {
+		req, err := http.NewRequest("POST", "", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := proxy.ProxyRequest(context.Background(), req, []byte(`{"jsonrpc":"2.0","id":1,"method":"getblockcount","params":[]}`))
+		Expect(err).ToNot(HaveOccurred())
+		return resp.Header.Get("Upstream")
+	}
+
+	Context("when polling the height of the upstream clients", func() {
+		It("should remove lagging clients from rotation", func() {
+			behind := NewHeightClient("behind", "100")
+			synced := NewHeightClient("synced", "110")
+			proxy := NewProxy(behind, synced)
+
+			// Clients are healthy until they have been polled.
+			Expect(upstream(proxy)).To(Equal("behind"))
+
+			proxy.PollHeights(context.Background(), "getblockcount", 5)
+			Expect(upstream(proxy)).To(Equal("synced"))
+
+			health := proxy.Health()
+			Expect(health.Tip).To(Equal(uint64(110)))
+			Expect(health.MaxLag).To(Equal(uint64(5)))
+			Expect(health.Upstreams).To(HaveLen(2))
+			Expect(health.Upstreams[0].Height).To(Equal(uint64(100)))
+			Expect(health.Upstreams[0].Lag).To(Equal(uint64(10)))
+			Expect(health.Upstreams[0].Healthy).To(BeFalse())
+			Expect(health.Upstreams[1].Lag).To(Equal(uint64(0)))
+			Expect(health.Upstreams[1].Healthy).To(BeTrue())
+		})
+
+		It("should return lagging clients to rotation once they catch up", func() {
+			behind := &heightClient{name: "behind", height: "100"}
+			synced := &heightClient{name: "synced", height: "110"}
+			proxy := NewProxy(behind, synced)
+
+			proxy.PollHeights(context.Background(), "getblockcount", 5)
+			Expect(upstream(proxy)).To(Equal("synced"))
+
+			behind.height = "108"
+			proxy.PollHeights(context.Background(), "getblockcount", 5)
+			Expect(upstream(proxy)).To(Equal("behind"))
+			Expect(proxy.Health().Upstreams[0].Lag).To(Equal(uint64(2)))
+		})
+
+		It("should keep the best known tip when the synced clients fail", func() {
+			behind := &heightClient{name: "behind", height: "100"}
+			synced := &heightClient{name: "synced", height: "110"}
+			proxy := NewProxy(behind, synced)
+
+			proxy.PollHeights(context.Background(), "getblockcount", 5)
+			Expect(proxy.Health().Tip).To(Equal(uint64(110)))
+
+			// The lagging client is not returned to rotation because the synced client cannot return its height.
+			synced.height = `"abc"`
+			proxy.PollHeights(context.Background(), "getblockcount", 5)
+			health := proxy.Health()
+			Expect(health.Tip).To(Equal(uint64(110)))
+			Expect(health.Upstreams[0].Lag).To(Equal(uint64(10)))
+			Expect(health.Upstreams[0].Healthy).To(BeFalse())
+			Expect(health.Fallback).To(BeTrue())
+
+			behind.height = "108"
+			proxy.PollHeights(context.Background(), "getblockcount", 5)
+			Expect(proxy.Health().Upstreams[0].Healthy).To(BeTrue())
+			Expect(proxy.Health().Fallback).To(BeFalse())
+			Expect(upstream(proxy)).To(Equal("behind"))
+		})
+
+		It("should not let a single client with a bogus height mark the others as lagging", func() {
+			first := &heightClient{name: "first", height: "110"}
+			second := &heightClient{name: "second", height: "109"}
+			proxy := NewProxy(first, second, NewHeightClient("bogus", "1000000"))
+			proxy.PollHeights(context.Background(), "getblockcount", 5)
+
+			health := proxy.Health()
+			Expect(health.Tip).To(Equal(uint64(110)))
+			Expect(health.Upstreams[0].Healthy).To(BeTrue())
+			Expect(health.Upstreams[1].Healthy).To(BeTrue())
+
+			// The tip keeps following the other clients.
+			first.height, second.height = "112", "112"
+			proxy.PollHeights(context.Background(), "getblockcount", 5)
+			Expect(proxy.Health().Tip).To(Equal(uint64(112)))
+		})
+
+		It("should decode hex encoded heights", func() {
+			proxy := NewProxy(NewHeightClient("eth", `"0x6e"`))
+			proxy.PollHeights(context.Background(), HeightMethod(types.Ethereum), 5)
+			Expect(proxy.Health().Tip).To(Equal(uint64(110)))
+		})
+
+		It("should mark clients which cannot return their height as unhealthy", func() {
+			errClient := NewMockErrorClient()
+			proxy := NewProxy(errClient, NewHeightClient("synced", "110"))
+			proxy.PollHeights(context.Background(), "getblockcount", 5)
+
+			health := proxy.Health()
+			Expect(health.Upstreams[0].Healthy).To(BeFalse())
+			Expect(health.Upstreams[0].Error).ToNot(BeEmpty())
+			Expect(upstream(proxy)).To(Equal("synced"))
+		})
+
+		It("should keep using the clients if none of them are healthy", func() {
+			proxy := NewProxy(NewHeightClient("broken", `"abc"`))
+			proxy.PollHeights(context.Background(), "getblockcount", 5)
+			Expect(proxy.Health().Upstreams[0].Healthy).To(BeFalse())
+			Expect(proxy.Health().Fallback).To(BeTrue())
+			Expect(upstream(proxy)).To(Equal("broken"))
+		})
+
+		It("should report the clients of each group", func() {
+			archive := NewHeightClient("archive", "110")
+			proxy := NewRoutedProxy([]rpc.Client{NewHeightClient("pruned", "110")}, map[string]Group{
+				"archive": {Clients: []rpc.Client{archive}},
+			}, nil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				proxy.Monitor(ctx, btctypes.BtcTestnet, time.Hour, 5)
+			}()
+			Eventually(func() uint64 { return proxy.Health().Tip }).Should(Equal(uint64(110)))
+			cancel()
+			Eventually(done).Should(BeClosed())
+
+			Expect(proxy.Health().Upstreams).To(HaveLen(2))
+		})
+	})
+})
+
+type heightClient struct {
+	name   string
+	height string
+}
+
+func NewHeightClient(name, height string) rpc.Client {
+	return &heightClient{name, height}
+}
+
+func (client *heightClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
+	header := http.Header{}
+	header.Set("Upstream", client.name)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":%s}`, client.height))),
+	}, nil
+}
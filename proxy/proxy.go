@@ -1,5 +1,8 @@
 // Package proxy proxies requests to given clients. If a client returns an error for a given request, the next client is
-// used. If all clients return errors, it returns each of the errors concatenated.
+// used. If all clients return errors, it returns each of the errors concatenated. Clients which are lagging behind the
+// tip of the chain are skipped while `Monitor` is running. Requests can also be routed to named
+// groups of clients based on their method and params (e.g. historical queries can be sent to archive nodes), and methods
+// with the `Broadcast` policy (e.g. transaction submission) are sent to every client in parallel.
 package proxy
 
 import (
@@ -12,25 +15,52 @@ import (
 
 // Proxy proxies the request to different clients.
 type Proxy struct {
-	Clients []rpc.Client
+	Clients  []rpc.Client
+	Groups   map[string]Group
+	Rules    []Rule
+	Policies map[string]Policy
+
+	monitor monitor
 }
 
 // NewProxy returns a new Proxy.
 func NewProxy(clients ...rpc.Client) *Proxy {
 	return &Proxy{
-		Clients: clients,
+		Clients:  clients,
+		Groups:   map[string]Group{},
+		Rules:    []Rule{},
+		Policies: DefaultPolicies(),
+		monitor:  newMonitor(),
+	}
+}
+
+// NewRoutedProxy returns a new Proxy which routes requests to the groups using the first matching rule. Requests which
+// do not match any of the rules are sent to the default clients.
+func NewRoutedProxy(clients []rpc.Client, groups map[string]Group, rules []Rule) *Proxy {
+	return &Proxy{
+		Clients:  clients,
+		Groups:   groups,
+		Rules:    rules,
+		Policies: DefaultPolicies(),
+		monitor:  newMonitor(),
 	}
 }
 
 func (proxy *Proxy) ProxyRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
-	errs := types.NewErrList(len(proxy.Clients))
+	method, clients := proxy.route(data)
+	clients = proxy.healthy(clients)
+	if proxy.Policies[method] == Broadcast {
+		return proxy.broadcast(ctx, clients, r, data)
+	}
+
+	errs := types.NewErrList(len(clients))
 	for {
-		for i, client := range proxy.Clients {
+		for i, client := range clients {
 			select {
 			case <-ctx.Done():
 				return nil, errs
 			default:
-				response, err := client.HandleRequest(r, data)
+				response, err := client.HandleRequest(ctx, r, data)
 				if err != nil {
 					errs[i] = err
 					continue
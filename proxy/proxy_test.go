@@ -1,8 +1,10 @@
 package proxy_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	. "github.com/renproject/mercury/proxy"
 
 	"github.com/renproject/mercury/rpc"
+	"github.com/renproject/mercury/testutil/fakenode"
 )
 
 var _ = Describe("Proxies", func() {
@@ -68,6 +71,81 @@ var _ = Describe("Proxies", func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+
+	Context("when the nodes are faulty", func() {
+		data := []byte(`{"jsonrpc":"1.0","id":1,"method":"getblockcount","params":[]}`)
+
+		// newClient returns a client of the node which times out quickly.
+		newClient := func(node *fakenode.Node) rpc.Client {
+			opts := rpc.DefaultOptions()
+			opts.Timeout = 100 * time.Millisecond
+			return rpc.NewClientWithOptions(node.URL, "", "", opts)
+		}
+
+		// send proxies the request and returns the status code and body of the response.
+		send := func(proxy *Proxy) (int, string) {
+			req, err := http.NewRequest("POST", "", bytes.NewBuffer(data))
+			Expect(err).ToNot(HaveOccurred())
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			resp, err := proxy.ProxyRequest(ctx, req, data)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return resp.StatusCode, string(body)
+		}
+
+		It("should fail over to the next node if a node hangs or is unreachable", func() {
+			hanging := fakenode.New()
+			defer hanging.Close()
+			hanging.Inject(fakenode.Hang, 1)
+			unreachable := fakenode.New()
+			unreachable.Close()
+			working := fakenode.New()
+			defer working.Close()
+			Expect(working.Respond("getblockcount", 100)).To(Succeed())
+
+			proxy := NewProxy(newClient(hanging), newClient(unreachable), newClient(working))
+			status, body := send(proxy)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(MatchJSON(`{"result":100,"error":null,"id":1}`))
+			Expect(hanging.Requests()).To(Equal(1))
+			Expect(working.Requests()).To(Equal(1))
+		})
+
+		It("should retry the nodes until one of them responds", func() {
+			node := fakenode.New()
+			defer node.Close()
+			node.Inject(fakenode.Hang, 3)
+			Expect(node.Respond("getblockcount", 100)).To(Succeed())
+
+			status, _ := send(NewProxy(newClient(node)))
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(node.Requests()).To(Equal(4))
+		})
+
+		It("should return the responses of nodes which respond with errors", func() {
+			node := fakenode.New()
+			defer node.Close()
+			node.Inject(fakenode.TooManyRequests, 1)
+			node.Inject(fakenode.BadGateway, 1)
+			node.Inject(fakenode.Malformed, 1)
+			other := fakenode.New()
+			defer other.Close()
+
+			// The proxy only fails over when a request cannot be sent, so the responses of the first node are returned.
+			proxy := NewProxy(newClient(node), newClient(other))
+			status, _ := send(proxy)
+			Expect(status).To(Equal(http.StatusTooManyRequests))
+			status, _ = send(proxy)
+			Expect(status).To(Equal(http.StatusBadGateway))
+			status, body := send(proxy)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(Equal(`{"result":`))
+			Expect(other.Requests()).To(Equal(0))
+		})
+	})
 })
 
 type mockClient struct {
@@ -77,7 +155,7 @@ func NewMockClient() rpc.Client {
 	return mockClient{}
 }
 
-func (mockClient) HandleRequest(r *http.Request, data []byte) (*http.Response, error) {
+func (mockClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
 	return &http.Response{
 		StatusCode: http.StatusOK,
 	}, nil
@@ -90,7 +168,7 @@ func NewMockErrorClient() rpc.Client {
 	return mockErrorClient{}
 }
 
-func (mockErrorClient) HandleRequest(r *http.Request, data []byte) (*http.Response, error) {
+func (mockErrorClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
 	return &http.Response{
 		StatusCode: http.StatusInternalServerError,
 	}, errors.New("error")
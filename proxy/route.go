@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/renproject/mercury/rpc"
+)
+
+// DefaultGroup is the name of the group containing the clients given to `NewProxy`. Requests which do not match any
+// rule are sent to this group.
+const DefaultGroup = ""
+
+// Group is a named set of clients. If every client in the group fails to handle a request, the request is retried
+// using the clients of each fallback group (in order).
+type Group struct {
+	Clients   []rpc.Client
+	Fallbacks []string
+}
+
+// ParamMatcher reports whether the params of a JSON-RPC request match a pattern.
+type ParamMatcher func(params []json.RawMessage) bool
+
+// Rule routes requests for the given method to a group. If matchers are provided, all of them need to match the request
+// params for the rule to apply.
+type Rule struct {
+	Method   string
+	Matchers []ParamMatcher
+	Group    string
+}
+
+// NewRule returns a new Rule.
+func NewRule(method, group string, matchers ...ParamMatcher) Rule {
+	return Rule{
+		Method:   method,
+		Matchers: matchers,
+		Group:    group,
+	}
+}
+
+// Match reports whether the rule applies to a request with the given method and params.
+func (rule Rule) Match(method string, params []json.RawMessage) bool {
+	if rule.Method != method {
+		return false
+	}
+	for _, match := range rule.Matchers {
+		if !match(params) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParamEquals matches requests where the param at index i is the given string.
+func ParamEquals(i int, value string) ParamMatcher {
+	return func(params []json.RawMessage) bool {
+		var param string
+		if i >= len(params) || json.Unmarshal(params[i], &param) != nil {
+			return false
+		}
+		return param == value
+	}
+}
+
+// HistoricalBlock matches requests where the block parameter at index i refers to a block other than the chain tip:
+// a hex encoded block number, "earliest", or an EIP-1898 object with a block hash or block number. A missing block
+// parameter defaults to "latest" and does not match, and neither do other tags or invalid parameters. These requests
+// usually need to be handled by an archive node.
+func HistoricalBlock(i int) ParamMatcher {
+	return func(params []json.RawMessage) bool {
+		if i >= len(params) {
+			return false
+		}
+		var block string
+		if err := json.Unmarshal(params[i], &block); err == nil {
+			return historicalBlockNumber(block)
+		}
+
+		// Blocks are also allowed to be given as JSON objects (EIP-1898).
+		var blockOrHash struct {
+			BlockNumber *string `json:"blockNumber"`
+			BlockHash   *string `json:"blockHash"`
+		}
+		if err := json.Unmarshal(params[i], &blockOrHash); err != nil {
+			return false
+		}
+		switch {
+		case blockOrHash.BlockHash != nil:
+			hash, err := hexutil.Decode(*blockOrHash.BlockHash)
+			return err == nil && len(hash) == 32
+		case blockOrHash.BlockNumber != nil:
+			return historicalBlockNumber(*blockOrHash.BlockNumber)
+		default:
+			return false
+		}
+	}
+}
+
+// historicalBlockNumber returns whether the block number is hex encoded or "earliest".
+func historicalBlockNumber(block string) bool {
+	if block == "earliest" {
+		return true
+	}
+	_, err := hexutil.DecodeUint64(block)
+	return err == nil
+}
+
+// route returns the method of the request and the clients which should handle it, in the order they should be tried.
+func (proxy *Proxy) route(data []byte) (string, []rpc.Client) {
+	req := struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return "", proxy.Clients
+	}
+
+	for _, rule := range proxy.Rules {
+		if rule.Match(req.Method, req.Params) {
+			if clients := proxy.groupClients(rule.Group, map[string]bool{}); len(clients) > 0 {
+				return req.Method, clients
+			}
+			break
+		}
+	}
+	return req.Method, proxy.Clients
+}
+
+// groupClients returns the clients of the group, followed by the clients of its fallback groups. Each group is only
+// visited once.
+func (proxy *Proxy) groupClients(name string, visited map[string]bool) []rpc.Client {
+	if visited[name] {
+		return nil
+	}
+	visited[name] = true
+
+	if name == DefaultGroup {
+		return proxy.Clients
+	}
+	group, ok := proxy.Groups[name]
+	if !ok {
+		return nil
+	}
+	clients := append([]rpc.Client{}, group.Clients...)
+	for _, fallback := range group.Fallbacks {
+		clients = append(clients, proxy.groupClients(fallback, visited)...)
+	}
+	return clients
+}
+
+// groupNames returns the names of the groups in sorted order.
+func (proxy *Proxy) groupNames() []string {
+	names := make([]string, 0, len(proxy.Groups))
+	for name := range proxy.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,131 @@
+package proxy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/proxy"
+
+	"github.com/renproject/mercury/rpc"
+)
+
+var _ = Describe("Routing", func() {
+	newRoutedProxy := func() *Proxy {
+		groups := map[string]Group{
+			"archive": {
+				Clients:   []rpc.Client{NewNamedClient("archive")},
+				Fallbacks: []string{DefaultGroup},
+			},
+			"broken": {
+				Clients:   []rpc.Client{NewMockErrorClient()},
+				Fallbacks: []string{"archive"},
+			},
+		}
+		rules := []Rule{
+			NewRule("eth_getStorageAt", "archive", HistoricalBlock(2)),
+			NewRule("eth_getProof", "archive", HistoricalBlock(2)),
+			NewRule("getrawtransaction", "broken"),
+			NewRule("eth_getCode", "unknown"),
+		}
+		return NewRoutedProxy([]rpc.Client{NewNamedClient("pruned")}, groups, rules)
+	}
+
+	upstream := func(proxy *Proxy, data string) string {
+		req, err := http.NewRequest("POST", "", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		resp, err := proxy.ProxyRequest(ctx, req, []byte(data))
+		Expect(err).ToNot(HaveOccurred())
+		return resp.Header.Get("Upstream")
+	}
+
+	Context("when routing requests by method", func() {
+		It("should send unmatched methods to the default clients", func() {
+			proxy := newRoutedProxy()
+			Expect(upstream(proxy, `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)).To(Equal("pruned"))
+		})
+
+		It("should send historical queries to the matching group", func() {
+			proxy := newRoutedProxy()
+			Expect(upstream(proxy, `{"jsonrpc":"2.0","id":1,"method":"eth_getStorageAt","params":["0x0","0x0","0x10"]}`)).To(Equal("archive"))
+			Expect(upstream(proxy, `{"jsonrpc":"2.0","id":1,"method":"eth_getProof","params":["0x0",[],"earliest"]}`)).To(Equal("archive"))
+		})
+
+		It("should only match hex block numbers, earliest and EIP-1898 blocks", func() {
+			matcher := HistoricalBlock(0)
+			match := func(param string) bool {
+				return matcher([]json.RawMessage{json.RawMessage(param)})
+			}
+			Expect(match(`"0x10"`)).To(BeTrue())
+			Expect(match(`"earliest"`)).To(BeTrue())
+			Expect(match(`{"blockNumber":"0x10"}`)).To(BeTrue())
+			Expect(match(`{"blockHash":"0x` + strings.Repeat("ab", 32) + `","requireCanonical":true}`)).To(BeTrue())
+
+			Expect(match(`"latest"`)).To(BeFalse())
+			Expect(match(`"pending"`)).To(BeFalse())
+			Expect(match(`"safe"`)).To(BeFalse())
+			Expect(match(`"finalized"`)).To(BeFalse())
+			Expect(match(`"16"`)).To(BeFalse())
+			Expect(match(`16`)).To(BeFalse())
+			Expect(match(`{"blockNumber":"latest"}`)).To(BeFalse())
+			Expect(match(`{"blockHash":"0xabcd"}`)).To(BeFalse())
+			Expect(match(`{}`)).To(BeFalse())
+			Expect(match(`null`)).To(BeFalse())
+		})
+
+		It("should send queries for the chain tip to the default clients", func() {
+			proxy := newRoutedProxy()
+			Expect(upstream(proxy, `{"jsonrpc":"2.0","id":1,"method":"eth_getStorageAt","params":["0x0","0x0","latest"]}`)).To(Equal("pruned"))
+			Expect(upstream(proxy, `{"jsonrpc":"2.0","id":1,"method":"eth_getStorageAt","params":["0x0","0x0"]}`)).To(Equal("pruned"))
+		})
+
+		It("should use the fallback groups if every client in the group fails", func() {
+			proxy := newRoutedProxy()
+			Expect(upstream(proxy, `{"jsonrpc":"2.0","id":1,"method":"getrawtransaction","params":["abcd",1]}`)).To(Equal("archive"))
+		})
+
+		It("should send requests routed to an unknown group to the default clients", func() {
+			proxy := newRoutedProxy()
+			Expect(upstream(proxy, `{"jsonrpc":"2.0","id":1,"method":"eth_getCode","params":["0x0","latest"]}`)).To(Equal("pruned"))
+		})
+	})
+
+	Context("when matching params", func() {
+		It("should match params with the expected value", func() {
+			rule := NewRule("getblock", "archive", ParamEquals(0, "abcd"))
+			Expect(rule.Match("getblock", nil)).To(BeFalse())
+			Expect(rule.Match("getblockhash", nil)).To(BeFalse())
+
+			proxy := NewRoutedProxy([]rpc.Client{NewNamedClient("pruned")}, map[string]Group{
+				"archive": {Clients: []rpc.Client{NewNamedClient("archive")}},
+			}, []Rule{rule})
+			Expect(upstream(proxy, `{"jsonrpc":"2.0","id":1,"method":"getblock","params":["abcd"]}`)).To(Equal("archive"))
+			Expect(upstream(proxy, `{"jsonrpc":"2.0","id":1,"method":"getblock","params":["efgh"]}`)).To(Equal("pruned"))
+		})
+	})
+})
+
+type namedClient struct {
+	name string
+}
+
+func NewNamedClient(name string) rpc.Client {
+	return namedClient{name}
+}
+
+func (client namedClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
+	header := http.Header{}
+	header.Set("Upstream", client.name)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+	}, nil
+}
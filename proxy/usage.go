@@ -0,0 +1,17 @@
+package proxy
+
+import "github.com/renproject/mercury/rpc"
+
+// Usage returns the combined usage of the upstream clients which count the requests sent with each API key.
+func (proxy *Proxy) Usage() rpc.Usage {
+	usage := rpc.Usage{
+		Tags: map[string]uint64{},
+		Keys: []rpc.KeyUsage{},
+	}
+	for _, client := range proxy.allClients() {
+		if reporter, ok := client.(rpc.UsageReporter); ok {
+			usage = usage.Merge(reporter.Usage())
+		}
+	}
+	return usage
+}
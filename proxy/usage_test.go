@@ -0,0 +1,47 @@
+package proxy_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/proxy"
+
+	"github.com/renproject/mercury/rpc"
+)
+
+var _ = Describe("Usage", func() {
+	Context("when the clients count their requests", func() {
+		It("should combine the usage of every client", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+			}))
+			defer server.Close()
+
+			newClient := func(key string) rpc.Client {
+				return rpc.NewProviderClient(rpc.ProviderConfig{
+					URL:        server.URL + "/{key}",
+					TaggedKeys: map[string][]string{"": {key}},
+				})
+			}
+			proxy := NewRoutedProxy([]rpc.Client{newClient("default-key"), NewMockClient()}, map[string]Group{
+				"archive": {Clients: []rpc.Client{newClient("archive-key")}},
+			}, []Rule{NewRule("eth_getCode", "archive")})
+
+			for _, method := range []string{"eth_blockNumber", "eth_getCode"} {
+				req, err := http.NewRequest("POST", "http://0.0.0.0:5000/eth/mainnet", nil)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = proxy.ProxyRequest(context.Background(), req, []byte(`{"jsonrpc":"2.0","id":1,"method":"`+method+`","params":[]}`))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			usage := proxy.Usage()
+			Expect(usage.Tags).To(Equal(map[string]uint64{"": 2}))
+			Expect(usage.Keys).To(HaveLen(2))
+			Expect(usage.Keys[0].Requests).To(Equal(uint64(1)))
+			Expect(usage.Keys[1].Requests).To(Equal(uint64(1)))
+		})
+	})
+})
@@ -0,0 +1,284 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/renproject/mercury/types"
+)
+
+// Error codes returned by adapters which translate bitcoind JSON-RPC requests for other backends. The codes match the
+// ones returned by bitcoind.
+const (
+	ErrorCodeParse             = -32700
+	ErrorCodeMethodNotFound    = -32601
+	ErrorCodeInvalidParams     = -32602
+	ErrorCodeInvalidAddress    = -5
+	ErrorCodeNoSuchTransaction = -5
+	ErrorCodeDeserialization   = -22
+	ErrorCodeVerifyError       = -25
+	ErrorCodeVerifyRejected    = -26
+	ErrorCodeAlreadyInChain    = -27
+)
+
+// errRPC is an application level error which is returned to the caller as a JSON-RPC error. Other errors returned by
+// adapters are treated as transport errors, so that the proxy can try another client.
+type errRPC struct {
+	types.JSONError
+}
+
+func newErrRPC(code int, format string, args ...interface{}) error {
+	return &errRPC{
+		JSONError: types.JSONError{
+			Code:    code,
+			Message: fmt.Sprintf(format, args...),
+		},
+	}
+}
+
+func (err *errRPC) Error() string {
+	return fmt.Sprintf("code = %v, message = %v", err.Code, err.Message)
+}
+
+// broadcastErrorCodes are the bitcoind error codes of the messages returned when a transaction is rejected.
+var broadcastErrorCodes = []struct {
+	message string
+	code    int
+}{
+	{"already in block chain", ErrorCodeAlreadyInChain},
+	{"missing inputs", ErrorCodeVerifyError},
+	{"missingorspent", ErrorCodeVerifyError},
+	{"tx decode failed", ErrorCodeDeserialization},
+}
+
+// broadcastErrorCode matches the code of bitcoind errors which are embedded in the messages of other backends (e.g.
+// "{'code': -25, 'message': 'bad-txns-inputs-missingorspent'}").
+var broadcastErrorCode = regexp.MustCompile(`['"]code['"]\s*:\s*(-\d+)`)
+
+// newErrBroadcast returns the error for a rejected transaction using the bitcoind code of the message. Messages which
+// are not known are rejected by the network rules.
+func newErrBroadcast(message string) error {
+	if match := broadcastErrorCode.FindStringSubmatch(message); match != nil {
+		if code, err := strconv.Atoi(match[1]); err == nil {
+			return newErrRPC(code, "%s", message)
+		}
+	}
+	lower := strings.ToLower(message)
+	for _, known := range broadcastErrorCodes {
+		if strings.Contains(lower, known.message) {
+			return newErrRPC(known.code, "%s", message)
+		}
+	}
+	return newErrRPC(ErrorCodeVerifyRejected, "%s", message)
+}
+
+// adapterRequest is a JSON-RPC request with its params decoded into a list.
+type adapterRequest struct {
+	ID     interface{}
+	Method string
+	Params []json.RawMessage
+}
+
+// decodeAdapterRequest decodes a JSON-RPC request which has its params given as a list.
+func decodeAdapterRequest(data []byte) (adapterRequest, error) {
+	req := types.JSONRequest{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return adapterRequest{}, newErrRPC(ErrorCodeParse, "cannot decode request: %v", err)
+	}
+	params := []json.RawMessage{}
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return adapterRequest{ID: req.ID, Method: req.Method}, newErrRPC(ErrorCodeInvalidParams, "params must be a list: %v", err)
+		}
+	}
+	return adapterRequest{
+		ID:     req.ID,
+		Method: req.Method,
+		Params: params,
+	}, nil
+}
+
+// param decodes the param at index i into v. Missing or null params are left unchanged, unless they are required.
+func (req adapterRequest) param(i int, v interface{}, required bool) error {
+	if i >= len(req.Params) || string(req.Params[i]) == "null" {
+		if required {
+			return newErrRPC(ErrorCodeInvalidParams, "missing param %d", i)
+		}
+		return nil
+	}
+	if err := json.Unmarshal(req.Params[i], v); err != nil {
+		return newErrRPC(ErrorCodeInvalidParams, "invalid param %d: %v", i, err)
+	}
+	return nil
+}
+
+// verbose decodes the verbose flag at index i, which bitcoind accepts as either a boolean or a number.
+func (req adapterRequest) verbose(i int) (bool, error) {
+	verbose := json.RawMessage("false")
+	if err := req.param(i, &verbose, false); err != nil {
+		return false, err
+	}
+	switch string(verbose) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	level, err := strconv.Atoi(string(verbose))
+	if err != nil {
+		return false, newErrRPC(ErrorCodeInvalidParams, "invalid param %d: %s", i, verbose)
+	}
+	return level != 0, nil
+}
+
+// txid decodes the transaction hash at index i. The hash must be validated before it is used in the paths of requests
+// to other backends.
+func (req adapterRequest) txid(i int) (string, error) {
+	txid := ""
+	if err := req.param(i, &txid, true); err != nil {
+		return "", err
+	}
+	// Shorter strings are padded by `chainhash.NewHashFromStr`, but are not valid hashes.
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil || len(txid) != chainhash.MaxHashStringSize {
+		return "", newErrRPC(ErrorCodeInvalidParams, "invalid param %d: %s is not a transaction hash", i, txid)
+	}
+	return hash.String(), nil
+}
+
+// adapterResponse returns a bitcoind-style HTTP response for the result of a request. Transport errors are returned as
+// they are, while errors of type `errRPC` are encoded in the JSON-RPC response.
+func adapterResponse(id interface{}, result interface{}, err error) (*http.Response, error) {
+	// Unlike `types.JSONResponse`, bitcoind always includes both the result and the error.
+	resp := struct {
+		Result json.RawMessage  `json:"result"`
+		Error  *types.JSONError `json:"error"`
+		ID     interface{}      `json:"id"`
+	}{
+		Result: json.RawMessage("null"),
+		ID:     id,
+	}
+
+	statusCode := http.StatusOK
+	if err != nil {
+		rpcErr, ok := err.(*errRPC)
+		if !ok {
+			return nil, err
+		}
+		resp.Error = &rpcErr.JSONError
+		statusCode = http.StatusInternalServerError
+		if rpcErr.Code == ErrorCodeMethodNotFound {
+			statusCode = http.StatusNotFound
+		}
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		resp.Result = data
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+// scriptTypes maps the script types used by indexers to the ones used by bitcoind.
+var scriptTypes = map[string]string{
+	"p2pk":                 "pubkey",
+	"p2pkh":                "pubkeyhash",
+	"p2sh":                 "scripthash",
+	"v0_p2wpkh":            "witness_v0_keyhash",
+	"v0_p2wsh":             "witness_v0_scripthash",
+	"v1_p2tr":              "witness_v1_taproot",
+	"op_return":            "nulldata",
+	"multisig":             "multisig",
+	"unknown":              "nonstandard",
+	"provably_unspendable": "nonstandard",
+}
+
+// ScriptPubKeyResult is the script of an output as returned by bitcoind.
+type ScriptPubKeyResult struct {
+	Asm     string `json:"asm"`
+	Hex     string `json:"hex"`
+	Type    string `json:"type"`
+	Address string `json:"address,omitempty"`
+}
+
+// TxOutResult is the result of the `gettxout` method.
+type TxOutResult struct {
+	BestBlock     string             `json:"bestblock"`
+	Confirmations int64              `json:"confirmations"`
+	Value         float64            `json:"value"`
+	ScriptPubKey  ScriptPubKeyResult `json:"scriptPubKey"`
+	Coinbase      bool               `json:"coinbase"`
+}
+
+// UnspentResult is an element of the result of the `listunspent` method.
+type UnspentResult struct {
+	TxID          string  `json:"txid"`
+	Vout          uint32  `json:"vout"`
+	Address       string  `json:"address"`
+	ScriptPubKey  string  `json:"scriptPubKey"`
+	Amount        float64 `json:"amount"`
+	Confirmations int64   `json:"confirmations"`
+	Spendable     bool    `json:"spendable"`
+	Solvable      bool    `json:"solvable"`
+	Safe          bool    `json:"safe"`
+}
+
+// ScriptSigResult is the signature script of an input as returned by bitcoind.
+type ScriptSigResult struct {
+	Asm string `json:"asm"`
+	Hex string `json:"hex"`
+}
+
+// VinResult is an input of a verbose transaction as returned by bitcoind.
+type VinResult struct {
+	Coinbase    string           `json:"coinbase,omitempty"`
+	TxID        string           `json:"txid,omitempty"`
+	Vout        uint32           `json:"vout"`
+	ScriptSig   *ScriptSigResult `json:"scriptSig,omitempty"`
+	TxInWitness []string         `json:"txinwitness,omitempty"`
+	Sequence    uint32           `json:"sequence"`
+}
+
+// VoutResult is an output of a verbose transaction as returned by bitcoind.
+type VoutResult struct {
+	Value        float64            `json:"value"`
+	N            uint32             `json:"n"`
+	ScriptPubKey ScriptPubKeyResult `json:"scriptPubKey"`
+}
+
+// RawTransactionResult is the result of the `getrawtransaction` method in verbose mode.
+type RawTransactionResult struct {
+	Hex           string       `json:"hex"`
+	TxID          string       `json:"txid"`
+	Hash          string       `json:"hash"`
+	Size          int64        `json:"size"`
+	VSize         int64        `json:"vsize"`
+	Weight        int64        `json:"weight"`
+	Version       int32        `json:"version"`
+	LockTime      uint32       `json:"locktime"`
+	Vin           []VinResult  `json:"vin"`
+	Vout          []VoutResult `json:"vout"`
+	BlockHash     string       `json:"blockhash,omitempty"`
+	Confirmations int64        `json:"confirmations,omitempty"`
+	Time          int64        `json:"time,omitempty"`
+	BlockTime     int64        `json:"blocktime,omitempty"`
+}
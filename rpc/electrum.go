@@ -0,0 +1,444 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+// ElectrumOptions define how connections to an Electrum server are made.
+type ElectrumOptions struct {
+	// Timeout limits the time taken to handle a request, including dialing the server.
+	Timeout time.Duration
+
+	// TLS is used to encrypt the connection if it is not nil.
+	TLS *tls.Config
+}
+
+// DefaultElectrumOptions returns the default options for Electrum clients, which connect without TLS.
+func DefaultElectrumOptions() ElectrumOptions {
+	return ElectrumOptions{
+		Timeout: 30 * time.Second,
+	}
+}
+
+// electrumClient implements the `Client` interface by translating bitcoind JSON-RPC requests into requests for an
+// Electrum server (such as ElectrumX or Fulcrum), which indexes outputs by the hash of their script.
+type electrumClient struct {
+	addr    string
+	network btctypes.Network
+	opts    ElectrumOptions
+}
+
+// NewElectrumClient returns a new client for the Electrum server at the given address (host:port).
+func NewElectrumClient(addr string, network btctypes.Network) Client {
+	return NewElectrumClientWithOptions(addr, network, DefaultElectrumOptions())
+}
+
+// NewElectrumClientWithOptions returns a new client for the Electrum server at the given address (host:port) which
+// connects using the given options.
+func NewElectrumClientWithOptions(addr string, network btctypes.Network, opts ElectrumOptions) Client {
+	return &electrumClient{
+		addr:    addr,
+		network: network,
+		opts:    opts,
+	}
+}
+
+// HandleRequest implements the `Client` interface. A new connection is used for each request.
+func (client *electrumClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
+	req, err := decodeAdapterRequest(data)
+	if err != nil {
+		return adapterResponse(req.ID, nil, err)
+	}
+
+	switch req.Method {
+	case "getblockcount", "listunspent", "gettxout", "getrawtransaction", "sendrawtransaction":
+	default:
+		return adapterResponse(req.ID, nil, newErrRPC(ErrorCodeMethodNotFound, "Method not found"))
+	}
+
+	conn, err := client.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var result interface{}
+	switch req.Method {
+	case "getblockcount":
+		result, err = conn.tipHeight()
+	case "listunspent":
+		result, err = client.listUnspent(conn, req)
+	case "gettxout":
+		result, err = client.getTxOut(conn, req)
+	case "getrawtransaction":
+		result, err = client.getRawTransaction(conn, req)
+	case "sendrawtransaction":
+		result, err = client.sendRawTransaction(conn, req)
+	}
+	return adapterResponse(req.ID, result, err)
+}
+
+func (client *electrumClient) listUnspent(conn *electrumConn, req adapterRequest) (interface{}, error) {
+	minConf, maxConf, addrs := int64(1), int64(9999999), []string{}
+	if err := req.param(0, &minConf, false); err != nil {
+		return nil, err
+	}
+	if err := req.param(1, &maxConf, false); err != nil {
+		return nil, err
+	}
+	// Electrum servers do not have a wallet, so the addresses must always be given.
+	if err := req.param(2, &addrs, true); err != nil {
+		return nil, err
+	}
+
+	tip, err := conn.tipHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []UnspentResult{}
+	for _, addr := range addrs {
+		address, err := btctypes.AddressFromBase58(addr, client.network)
+		if err != nil {
+			return nil, newErrRPC(ErrorCodeInvalidAddress, "Invalid address: %s", addr)
+		}
+		script, err := btctypes.PayToAddrScript(address, client.network)
+		if err != nil {
+			return nil, newErrRPC(ErrorCodeInvalidAddress, "Invalid address: %s", addr)
+		}
+
+		utxos, err := conn.listUnspent(script)
+		if err != nil {
+			return nil, err
+		}
+		for _, utxo := range utxos {
+			confs := utxo.confirmations(tip)
+			if confs < minConf || confs > maxConf {
+				continue
+			}
+			results = append(results, UnspentResult{
+				TxID:          utxo.TxHash,
+				Vout:          utxo.TxPos,
+				Address:       addr,
+				ScriptPubKey:  hex.EncodeToString(script),
+				Amount:        btcutil.Amount(utxo.Value).ToBTC(),
+				Confirmations: confs,
+				Safe:          confs > 0,
+			})
+		}
+	}
+	return results, nil
+}
+
+func (client *electrumClient) getTxOut(conn *electrumConn, req adapterRequest) (interface{}, error) {
+	txid, n, includeMempool := "", uint32(0), true
+	if err := req.param(0, &txid, true); err != nil {
+		return nil, err
+	}
+	if err := req.param(1, &n, true); err != nil {
+		return nil, err
+	}
+	if err := req.param(2, &includeMempool, false); err != nil {
+		return nil, err
+	}
+
+	// bitcoind returns null for outputs which do not exist or have been spent.
+	txHex := ""
+	if err := conn.call("blockchain.transaction.get", &txHex, txid, false); err != nil {
+		if _, ok := err.(*errRPC); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	msgTx, err := decodeTx(txHex)
+	if err != nil {
+		return nil, err
+	}
+	if int(n) >= len(msgTx.TxOut) {
+		return nil, nil
+	}
+	txOut := msgTx.TxOut[n]
+
+	// Electrum servers only index outputs by script, so the output is unspent if it is returned for its script.
+	utxos, err := conn.listUnspent(txOut.PkScript)
+	if err != nil {
+		return nil, err
+	}
+	for _, utxo := range utxos {
+		if utxo.TxHash != txid || utxo.TxPos != n {
+			continue
+		}
+		if utxo.Height <= 0 && !includeMempool {
+			return nil, nil
+		}
+		header, err := conn.tip()
+		if err != nil {
+			return nil, err
+		}
+		bestBlock, err := header.hash()
+		if err != nil {
+			return nil, err
+		}
+		return TxOutResult{
+			BestBlock:     bestBlock,
+			Confirmations: utxo.confirmations(header.Height),
+			Value:         btcutil.Amount(txOut.Value).ToBTC(),
+			ScriptPubKey:  client.scriptPubKey(txOut.PkScript),
+			Coinbase:      isCoinbase(msgTx),
+		}, nil
+	}
+	return nil, nil
+}
+
+func (client *electrumClient) getRawTransaction(conn *electrumConn, req adapterRequest) (interface{}, error) {
+	txid := ""
+	if err := req.param(0, &txid, true); err != nil {
+		return nil, err
+	}
+	verbose, err := req.verbose(1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Electrum servers return the verbose transaction of their node as it is.
+	result := json.RawMessage{}
+	if err := conn.call("blockchain.transaction.get", &result, txid, verbose); err != nil {
+		if _, ok := err.(*errRPC); ok {
+			return nil, newErrRPC(ErrorCodeNoSuchTransaction, "No such mempool or blockchain transaction. Use gettransaction for wallet transactions.")
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+func (client *electrumClient) sendRawTransaction(conn *electrumConn, req adapterRequest) (interface{}, error) {
+	txHex := ""
+	if err := req.param(0, &txHex, true); err != nil {
+		return nil, err
+	}
+
+	txid := ""
+	if err := conn.call("blockchain.transaction.broadcast", &txid, txHex); err != nil {
+		if rpcErr, ok := err.(*errRPC); ok {
+			return nil, newErrBroadcast(rpcErr.Message)
+		}
+		return nil, err
+	}
+	return txid, nil
+}
+
+// scriptPubKey returns the bitcoind representation of an output script.
+func (client *electrumClient) scriptPubKey(script []byte) ScriptPubKeyResult {
+	class, addrs, _, _ := txscript.ExtractPkScriptAddrs(script, client.network.Params())
+	result := ScriptPubKeyResult{
+		Asm:  disasm(hex.EncodeToString(script)),
+		Hex:  hex.EncodeToString(script),
+		Type: class.String(),
+	}
+	// Addresses of other chains are not encoded using the bitcoin format.
+	if client.network.Chain() == types.Bitcoin && len(addrs) == 1 {
+		result.Address = addrs[0].EncodeAddress()
+	}
+	return result
+}
+
+// dial opens a connection to the server. The connection is closed once the context is done.
+func (client *electrumClient) dial(ctx context.Context) (*electrumConn, error) {
+	deadline, ok := ctx.Deadline()
+	if client.opts.Timeout > 0 {
+		if timeout := time.Now().Add(client.opts.Timeout); !ok || timeout.Before(deadline) {
+			deadline = timeout
+		}
+	}
+
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", client.addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to electrum server: %v", err)
+	}
+	if client.opts.TLS != nil {
+		conn = tls.Client(conn, client.opts.TLS)
+	}
+	if !deadline.IsZero() {
+		conn.SetDeadline(deadline)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	electrumConn := &electrumConn{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		done:   done,
+	}
+	if err := electrumConn.call("server.version", nil, "mercury", "1.4"); err != nil {
+		electrumConn.Close()
+		return nil, err
+	}
+	return electrumConn, nil
+}
+
+// electrumConn is a connection to an Electrum server, which sends newline delimited JSON-RPC messages.
+type electrumConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	done   chan struct{}
+	id     int64
+}
+
+// call sends a request to the server and decodes the result into v. Errors returned by the server are returned as
+// errors of type `errRPC`.
+func (conn *electrumConn) call(method string, v interface{}, params ...interface{}) error {
+	conn.id++
+	data, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      conn.id,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode electrum request: %v", err)
+	}
+	if _, err := conn.conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("cannot send electrum request: %v", err)
+	}
+
+	// Skip notifications, which do not have an id.
+	for {
+		line, err := conn.reader.ReadBytes('\n')
+		if err != nil {
+			return fmt.Errorf("cannot read electrum response: %v", err)
+		}
+		resp := struct {
+			Result json.RawMessage  `json:"result"`
+			Error  *types.JSONError `json:"error"`
+			ID     *int64           `json:"id"`
+		}{}
+		if err := json.Unmarshal(line, &resp); err != nil {
+			return fmt.Errorf("cannot decode electrum response: %v", err)
+		}
+		if resp.ID == nil || *resp.ID != conn.id {
+			continue
+		}
+		if resp.Error != nil {
+			return &errRPC{JSONError: *resp.Error}
+		}
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, v); err != nil {
+			return fmt.Errorf("cannot decode electrum result: %v", err)
+		}
+		return nil
+	}
+}
+
+// Close closes the connection.
+func (conn *electrumConn) Close() error {
+	close(conn.done)
+	return conn.conn.Close()
+}
+
+// electrumHeader is the latest block header returned by the server.
+type electrumHeader struct {
+	Height int64  `json:"height"`
+	Hex    string `json:"hex"`
+}
+
+// hash returns the block hash of the header.
+func (header electrumHeader) hash() (string, error) {
+	data, err := hex.DecodeString(header.Hex)
+	if err != nil {
+		return "", fmt.Errorf("cannot decode block header: %v", err)
+	}
+	return chainhash.DoubleHashH(data).String(), nil
+}
+
+func (conn *electrumConn) tip() (electrumHeader, error) {
+	header := electrumHeader{}
+	if err := conn.call("blockchain.headers.subscribe", &header); err != nil {
+		return header, err
+	}
+	return header, nil
+}
+
+func (conn *electrumConn) tipHeight() (int64, error) {
+	header, err := conn.tip()
+	return header.Height, err
+}
+
+// electrumUTXO is an unspent output returned by the server. Unconfirmed outputs have a height of zero or less.
+type electrumUTXO struct {
+	TxHash string `json:"tx_hash"`
+	TxPos  uint32 `json:"tx_pos"`
+	Height int64  `json:"height"`
+	Value  int64  `json:"value"`
+}
+
+func (utxo electrumUTXO) confirmations(tip int64) int64 {
+	if utxo.Height <= 0 {
+		return 0
+	}
+	return tip - utxo.Height + 1
+}
+
+func (conn *electrumConn) listUnspent(script []byte) ([]electrumUTXO, error) {
+	utxos := []electrumUTXO{}
+	if err := conn.call("blockchain.scripthash.listunspent", &utxos, ScriptHash(script)); err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+// ScriptHash returns the hash used by Electrum servers to index the given output script.
+func ScriptHash(script []byte) string {
+	hash := sha256.Sum256(script)
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+	return hex.EncodeToString(hash[:])
+}
+
+// decodeTx decodes a hex encoded transaction.
+func decodeTx(txHex string) (*wire.MsgTx, error) {
+	data, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, newErrRPC(ErrorCodeDeserialization, "TX decode failed: %v", err)
+	}
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	if err := msgTx.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, newErrRPC(ErrorCodeDeserialization, "TX decode failed: %v", err)
+	}
+	return msgTx, nil
+}
+
+func isCoinbase(msgTx *wire.MsgTx) bool {
+	if len(msgTx.TxIn) != 1 {
+		return false
+	}
+	prevOut := msgTx.TxIn[0].PreviousOutPoint
+	return prevOut.Index == wire.MaxPrevOutIndex && prevOut.Hash == chainhash.Hash{}
+}
@@ -0,0 +1,350 @@
+package rpc_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/rpc"
+
+	"github.com/renproject/mercury/rpcclient/btcrpcclient"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+// electrumServer is a fake Electrum server which stores a single transaction with two outputs to the same address. The
+// first output is confirmed at height 91 and the second one is in the mempool.
+type electrumServer struct {
+	listener net.Listener
+	txHex    string
+	txid     string
+	script   []byte
+	header   string
+}
+
+func newElectrumServer(script []byte) *electrumServer {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), []byte{txscript.OP_TRUE}, nil))
+	msgTx.AddTxOut(wire.NewTxOut(50000, script))
+	msgTx.AddTxOut(wire.NewTxOut(20000, script))
+	buf := new(bytes.Buffer)
+	if err := msgTx.Serialize(buf); err != nil {
+		panic(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	server := &electrumServer{
+		listener: listener,
+		txHex:    hex.EncodeToString(buf.Bytes()),
+		txid:     msgTx.TxHash().String(),
+		script:   script,
+		header:   hex.EncodeToString(make([]byte, 80)),
+	}
+	go server.serve()
+	return server
+}
+
+func (server *electrumServer) serve() {
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				line, err := reader.ReadBytes('\n')
+				if err != nil {
+					return
+				}
+				req := struct {
+					ID     int64             `json:"id"`
+					Method string            `json:"method"`
+					Params []json.RawMessage `json:"params"`
+				}{}
+				if err := json.Unmarshal(line, &req); err != nil {
+					return
+				}
+
+				// Send a notification before every response, which should be skipped by the client.
+				conn.Write([]byte(`{"jsonrpc":"2.0","method":"blockchain.headers.subscribe","params":[{"height":100}]}` + "\n"))
+
+				result, rpcErr := server.handle(req.Method, req.Params)
+				resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+				if rpcErr != nil {
+					resp["error"] = rpcErr
+				} else {
+					resp["result"] = result
+				}
+				data, _ := json.Marshal(resp)
+				conn.Write(append(data, '\n'))
+			}
+		}()
+	}
+}
+
+func (server *electrumServer) handle(method string, params []json.RawMessage) (interface{}, *types.JSONError) {
+	switch method {
+	case "server.version":
+		return []string{"Fake 1.0", "1.4"}, nil
+	case "blockchain.headers.subscribe":
+		return map[string]interface{}{"height": 100, "hex": server.header}, nil
+	case "blockchain.scripthash.listunspent":
+		scriptHash := ""
+		json.Unmarshal(params[0], &scriptHash)
+		if scriptHash != ScriptHash(server.script) {
+			return []interface{}{}, nil
+		}
+		return []map[string]interface{}{
+			{"tx_hash": server.txid, "tx_pos": 0, "height": 91, "value": 50000},
+			{"tx_hash": server.txid, "tx_pos": 1, "height": 0, "value": 20000},
+		}, nil
+	case "blockchain.transaction.get":
+		txid, verbose := "", false
+		json.Unmarshal(params[0], &txid)
+		json.Unmarshal(params[1], &verbose)
+		if txid != server.txid {
+			return nil, &types.JSONError{Code: 2, Message: "daemon error: No such mempool or blockchain transaction."}
+		}
+		if verbose {
+			return map[string]interface{}{"txid": server.txid, "hex": server.txHex, "confirmations": 10}, nil
+		}
+		return server.txHex, nil
+	case "blockchain.transaction.broadcast":
+		txHex := ""
+		json.Unmarshal(params[0], &txHex)
+		switch txHex {
+		case server.txHex:
+			return server.txid, nil
+		case "aaaa":
+			return nil, &types.JSONError{Code: 1, Message: "the transaction was rejected by network rules.\n\ntransaction already in block chain"}
+		case "bbbb":
+			return nil, &types.JSONError{Code: 1, Message: "the transaction was rejected by network rules.\n\n{'code': -25, 'message': 'bad-txns-inputs-missingorspent'}"}
+		default:
+			return nil, &types.JSONError{Code: 1, Message: "the transaction was rejected by network rules."}
+		}
+	default:
+		return nil, &types.JSONError{Code: -32601, Message: "unknown method"}
+	}
+}
+
+func (server *electrumServer) Close() {
+	server.listener.Close()
+}
+
+var _ = Describe("Electrum RPC client", func() {
+	address, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), &chaincfg.TestNet3Params)
+	if err != nil {
+		panic(err)
+	}
+	script, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		panic(err)
+	}
+
+	send := func(client Client, method string, params string, result interface{}) (int, *types.JSONError) {
+		r, err := http.NewRequest("POST", "http://0.0.0.0:5000/btc/testnet", nil)
+		Expect(err).ToNot(HaveOccurred())
+		data := fmt.Sprintf(`{"jsonrpc":"1.0","id":1,"method":"%s","params":%s}`, method, params)
+		resp, err := client.HandleRequest(context.Background(), r, []byte(data))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		body := struct {
+			Result json.RawMessage  `json:"result"`
+			Error  *types.JSONError `json:"error"`
+		}{}
+		Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+		if result != nil {
+			Expect(json.Unmarshal(body.Result, result)).To(Succeed())
+		}
+		return resp.StatusCode, body.Error
+	}
+
+	Context("when getting the block count", func() {
+		It("should return the height of the tip", func() {
+			server := newElectrumServer(script)
+			defer server.Close()
+			client := NewElectrumClient(server.listener.Addr().String(), btctypes.BtcTestnet)
+
+			height := int64(0)
+			_, rpcErr := send(client, "getblockcount", `[]`, &height)
+			Expect(rpcErr).To(BeNil())
+			Expect(height).To(Equal(int64(100)))
+		})
+	})
+
+	Context("when listing unspent outputs", func() {
+		It("should return the outputs of any address", func() {
+			server := newElectrumServer(script)
+			defer server.Close()
+			client := NewElectrumClient(server.listener.Addr().String(), btctypes.BtcTestnet)
+
+			utxos := btcrpcclient.ListUnspentResponse{}
+			_, rpcErr := send(client, "listunspent", fmt.Sprintf(`[0, 999999, ["%s"]]`, address.EncodeAddress()), &utxos)
+			Expect(rpcErr).To(BeNil())
+			Expect(utxos).To(HaveLen(2))
+			Expect(utxos[0].TxID).To(Equal(server.txid))
+			Expect(utxos[0].Amount).To(Equal(0.0005))
+			Expect(utxos[0].Confirmations).To(Equal(int64(10)))
+			Expect(utxos[0].ScriptPubKey).To(Equal(hex.EncodeToString(script)))
+			Expect(utxos[1].Confirmations).To(Equal(int64(0)))
+
+			utxos = btcrpcclient.ListUnspentResponse{}
+			_, rpcErr = send(client, "listunspent", fmt.Sprintf(`[1, 9999999, ["%s"]]`, address.EncodeAddress()), &utxos)
+			Expect(rpcErr).To(BeNil())
+			Expect(utxos).To(HaveLen(1))
+		})
+
+		It("should return an error for invalid addresses", func() {
+			server := newElectrumServer(script)
+			defer server.Close()
+			client := NewElectrumClient(server.listener.Addr().String(), btctypes.BtcTestnet)
+
+			_, rpcErr := send(client, "listunspent", `[0, 999999, ["invalid"]]`, nil)
+			Expect(rpcErr.Code).To(Equal(ErrorCodeInvalidAddress))
+		})
+	})
+
+	Context("when getting transaction outputs", func() {
+		It("should return unspent outputs", func() {
+			server := newElectrumServer(script)
+			defer server.Close()
+			client := NewElectrumClient(server.listener.Addr().String(), btctypes.BtcTestnet)
+
+			txOut := btcrpcclient.GetTxOutResponse{}
+			_, rpcErr := send(client, "gettxout", fmt.Sprintf(`["%s", 0]`, server.txid), &txOut)
+			Expect(rpcErr).To(BeNil())
+			Expect(txOut.Confirmations).To(Equal(int64(10)))
+			Expect(txOut.Value).To(Equal(0.0005))
+			Expect(txOut.ScriptPubKey.Hex).To(Equal(hex.EncodeToString(script)))
+
+			result := TxOutResult{}
+			_, rpcErr = send(client, "gettxout", fmt.Sprintf(`["%s", 1]`, server.txid), &result)
+			Expect(rpcErr).To(BeNil())
+			Expect(result.Confirmations).To(Equal(int64(0)))
+			Expect(result.ScriptPubKey.Type).To(Equal("pubkeyhash"))
+			Expect(result.ScriptPubKey.Address).To(Equal(address.EncodeAddress()))
+			Expect(result.BestBlock).To(Equal(chainhash.DoubleHashH(make([]byte, 80)).String()))
+		})
+
+		It("should return null for missing outputs", func() {
+			server := newElectrumServer(script)
+			defer server.Close()
+			client := NewElectrumClient(server.listener.Addr().String(), btctypes.BtcTestnet)
+
+			var result interface{} = "not null"
+			_, rpcErr := send(client, "gettxout", fmt.Sprintf(`["%s", 2]`, server.txid), &result)
+			Expect(rpcErr).To(BeNil())
+			Expect(result).To(BeNil())
+
+			result = "not null"
+			_, rpcErr = send(client, "gettxout", fmt.Sprintf(`["%s", 1, false]`, server.txid), &result)
+			Expect(rpcErr).To(BeNil())
+			Expect(result).To(BeNil())
+
+			result = "not null"
+			_, rpcErr = send(client, "gettxout", `["abcd", 0]`, &result)
+			Expect(rpcErr).To(BeNil())
+			Expect(result).To(BeNil())
+		})
+	})
+
+	Context("when getting raw transactions", func() {
+		It("should return the transaction", func() {
+			server := newElectrumServer(script)
+			defer server.Close()
+			client := NewElectrumClient(server.listener.Addr().String(), btctypes.BtcTestnet)
+
+			txHex := ""
+			_, rpcErr := send(client, "getrawtransaction", fmt.Sprintf(`["%s"]`, server.txid), &txHex)
+			Expect(rpcErr).To(BeNil())
+			Expect(txHex).To(Equal(server.txHex))
+
+			tx := btcrpcclient.RawTransactionVerbose{}
+			_, rpcErr = send(client, "getrawtransaction", fmt.Sprintf(`["%s", 1]`, server.txid), &tx)
+			Expect(rpcErr).To(BeNil())
+			Expect(tx.TxID).To(Equal(server.txid))
+			Expect(tx.Confirmations).To(Equal(uint32(10)))
+		})
+
+		It("should return an error for missing transactions", func() {
+			server := newElectrumServer(script)
+			defer server.Close()
+			client := NewElectrumClient(server.listener.Addr().String(), btctypes.BtcTestnet)
+
+			_, rpcErr := send(client, "getrawtransaction", `["abcd", true]`, nil)
+			Expect(rpcErr.Code).To(Equal(ErrorCodeNoSuchTransaction))
+		})
+	})
+
+	Context("when sending raw transactions", func() {
+		It("should return the transaction hash", func() {
+			server := newElectrumServer(script)
+			defer server.Close()
+			client := NewElectrumClient(server.listener.Addr().String(), btctypes.BtcTestnet)
+
+			txid := ""
+			_, rpcErr := send(client, "sendrawtransaction", fmt.Sprintf(`["%s"]`, server.txHex), &txid)
+			Expect(rpcErr).To(BeNil())
+			Expect(txid).To(Equal(server.txid))
+		})
+
+		It("should return an error if the transaction is rejected", func() {
+			server := newElectrumServer(script)
+			defer server.Close()
+			client := NewElectrumClient(server.listener.Addr().String(), btctypes.BtcTestnet)
+
+			_, rpcErr := send(client, "sendrawtransaction", `["abcd"]`, nil)
+			Expect(rpcErr.Code).To(Equal(ErrorCodeVerifyRejected))
+			Expect(rpcErr.Message).To(ContainSubstring("rejected"))
+
+			// Known rejections keep the code returned by bitcoind.
+			_, rpcErr = send(client, "sendrawtransaction", `["aaaa"]`, nil)
+			Expect(rpcErr.Code).To(Equal(ErrorCodeAlreadyInChain))
+			_, rpcErr = send(client, "sendrawtransaction", `["bbbb"]`, nil)
+			Expect(rpcErr.Code).To(Equal(ErrorCodeVerifyError))
+			Expect(rpcErr.Message).To(ContainSubstring("missingorspent"))
+		})
+	})
+
+	Context("when the server is unavailable", func() {
+		It("should return an error so that another client can be tried", func() {
+			server := newElectrumServer(script)
+			addr := server.listener.Addr().String()
+			server.Close()
+			client := NewElectrumClientWithOptions(addr, btctypes.BtcTestnet, ElectrumOptions{Timeout: time.Second})
+
+			r, err := http.NewRequest("POST", "http://0.0.0.0:5000/btc/testnet", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.HandleRequest(context.Background(), r, []byte(`{"jsonrpc":"1.0","id":1,"method":"getblockcount","params":[]}`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when calling unsupported methods", func() {
+		It("should return a method not found error", func() {
+			client := NewElectrumClient("127.0.0.1:0", btctypes.BtcTestnet)
+			status, rpcErr := send(client, "getblockchaininfo", `[]`, nil)
+			Expect(status).To(Equal(http.StatusNotFound))
+			Expect(rpcErr.Code).To(Equal(ErrorCodeMethodNotFound))
+		})
+	})
+})
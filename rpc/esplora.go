@@ -0,0 +1,420 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+// esploraClient implements the `Client` interface by translating bitcoind JSON-RPC requests into requests for an
+// Esplora-compatible REST indexer. Unlike a node, an indexer can return the UTXOs of any address.
+type esploraClient struct {
+	url     string
+	network btctypes.Network
+	client  *http.Client
+}
+
+// NewEsploraClient returns a new client for the Esplora indexer at the given URL.
+func NewEsploraClient(url string, network btctypes.Network) Client {
+	return NewEsploraClientWithOptions(url, network, DefaultOptions())
+}
+
+// NewEsploraClientWithOptions returns a new client for the Esplora indexer at the given URL which sends requests using
+// the given options.
+func NewEsploraClientWithOptions(url string, network btctypes.Network, opts Options) Client {
+	return &esploraClient{
+		url:     strings.TrimSuffix(url, "/"),
+		network: network,
+		client:  NewHTTPClient(opts),
+	}
+}
+
+// HandleRequest implements the `Client` interface.
+func (client *esploraClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
+	req, err := decodeAdapterRequest(data)
+	if err != nil {
+		return adapterResponse(req.ID, nil, err)
+	}
+
+	var result interface{}
+	switch req.Method {
+	case "getblockcount":
+		result, err = client.tipHeight(ctx)
+	case "listunspent":
+		result, err = client.listUnspent(ctx, req)
+	case "gettxout":
+		result, err = client.getTxOut(ctx, req)
+	case "getrawtransaction":
+		result, err = client.getRawTransaction(ctx, req)
+	case "sendrawtransaction":
+		result, err = client.sendRawTransaction(ctx, req)
+	default:
+		err = newErrRPC(ErrorCodeMethodNotFound, "Method not found")
+	}
+	return adapterResponse(req.ID, result, err)
+}
+
+// maxAddresses is the maximum number of addresses in a `listunspent` request, since the UTXOs of each address are
+// requested from the indexer separately.
+const maxAddresses = 20
+
+func (client *esploraClient) listUnspent(ctx context.Context, req adapterRequest) (interface{}, error) {
+	minConf, maxConf, addrs := int64(1), int64(9999999), []string{}
+	if err := req.param(0, &minConf, false); err != nil {
+		return nil, err
+	}
+	if err := req.param(1, &maxConf, false); err != nil {
+		return nil, err
+	}
+	// Indexers do not have a wallet, so the addresses must always be given.
+	if err := req.param(2, &addrs, true); err != nil {
+		return nil, err
+	}
+	if len(addrs) > maxAddresses {
+		return nil, newErrRPC(ErrorCodeInvalidParams, "too many addresses: expected at most %d, got %d", maxAddresses, len(addrs))
+	}
+
+	tip, err := client.tipHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []UnspentResult{}
+	for _, addr := range addrs {
+		address, err := btctypes.AddressFromBase58(addr, client.network)
+		if err != nil {
+			return nil, newErrRPC(ErrorCodeInvalidAddress, "Invalid address: %s", addr)
+		}
+		script, err := btctypes.PayToAddrScript(address, client.network)
+		if err != nil {
+			return nil, newErrRPC(ErrorCodeInvalidAddress, "Invalid address: %s", addr)
+		}
+
+		utxos := []esploraUTXO{}
+		if _, err := client.getJSON(ctx, fmt.Sprintf("/address/%s/utxo", addr), &utxos); err != nil {
+			return nil, err
+		}
+		for _, utxo := range utxos {
+			confs := confirmations(tip, utxo.Status)
+			if confs < minConf || confs > maxConf {
+				continue
+			}
+			results = append(results, UnspentResult{
+				TxID:          utxo.TxID,
+				Vout:          utxo.Vout,
+				Address:       addr,
+				ScriptPubKey:  hex.EncodeToString(script),
+				Amount:        btcutil.Amount(utxo.Value).ToBTC(),
+				Confirmations: confs,
+				Spendable:     false,
+				Solvable:      false,
+				Safe:          confs > 0,
+			})
+		}
+	}
+	return results, nil
+}
+
+func (client *esploraClient) getTxOut(ctx context.Context, req adapterRequest) (interface{}, error) {
+	txid, err := req.txid(0)
+	if err != nil {
+		return nil, err
+	}
+	n, includeMempool := uint32(0), true
+	if err := req.param(1, &n, true); err != nil {
+		return nil, err
+	}
+	if err := req.param(2, &includeMempool, false); err != nil {
+		return nil, err
+	}
+
+	// bitcoind returns null for outputs which do not exist or have been spent.
+	tx := esploraTx{}
+	found, err := client.getJSON(ctx, fmt.Sprintf("/tx/%s", txid), &tx)
+	if err != nil || !found {
+		return nil, err
+	}
+	if int(n) >= len(tx.Vout) || (!tx.Status.Confirmed && !includeMempool) {
+		return nil, nil
+	}
+	outspend := struct {
+		Spent bool `json:"spent"`
+	}{}
+	if _, err := client.getJSON(ctx, fmt.Sprintf("/tx/%s/outspend/%d", txid, n), &outspend); err != nil {
+		return nil, err
+	}
+	if outspend.Spent {
+		return nil, nil
+	}
+
+	tip, err := client.tipHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bestBlock, _, err := client.get(ctx, "/blocks/tip/hash")
+	if err != nil {
+		return nil, err
+	}
+	return TxOutResult{
+		BestBlock:     strings.TrimSpace(string(bestBlock)),
+		Confirmations: confirmations(tip, tx.Status),
+		Value:         btcutil.Amount(tx.Vout[n].Value).ToBTC(),
+		ScriptPubKey:  tx.Vout[n].scriptPubKey(),
+		Coinbase:      len(tx.Vin) > 0 && tx.Vin[0].IsCoinbase,
+	}, nil
+}
+
+func (client *esploraClient) getRawTransaction(ctx context.Context, req adapterRequest) (interface{}, error) {
+	txid, err := req.txid(0)
+	if err != nil {
+		return nil, err
+	}
+	isVerbose, err := req.verbose(1)
+	if err != nil {
+		return nil, err
+	}
+
+	txHex, status, err := client.get(ctx, fmt.Sprintf("/tx/%s/hex", txid))
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound || status == http.StatusBadRequest {
+		return nil, newErrRPC(ErrorCodeNoSuchTransaction, "No such mempool or blockchain transaction. Use gettransaction for wallet transactions.")
+	}
+	if !isVerbose {
+		return strings.TrimSpace(string(txHex)), nil
+	}
+
+	tx := esploraTx{}
+	if _, err := client.getJSON(ctx, fmt.Sprintf("/tx/%s", txid), &tx); err != nil {
+		return nil, err
+	}
+	tip, err := client.tipHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tx.result(strings.TrimSpace(string(txHex)), tip), nil
+}
+
+// esploraError matches the errors returned by Esplora when the node rejects a transaction.
+var esploraError = regexp.MustCompile(`sendrawtransaction RPC error: (\{.*\})`)
+
+func (client *esploraClient) sendRawTransaction(ctx context.Context, req adapterRequest) (interface{}, error) {
+	txHex := ""
+	if err := req.param(0, &txHex, true); err != nil {
+		return nil, err
+	}
+
+	body, status, err := client.do(ctx, "POST", "/tx", []byte(txHex))
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		rpcErr := &errRPC{}
+		if match := esploraError.FindSubmatch(body); match != nil && json.Unmarshal(match[1], &rpcErr.JSONError) == nil {
+			return nil, rpcErr
+		}
+		return nil, newErrBroadcast(strings.TrimSpace(string(body)))
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// tipHeight returns the height of the latest block known to the indexer.
+func (client *esploraClient) tipHeight(ctx context.Context) (int64, error) {
+	body, _, err := client.get(ctx, "/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	height, err := strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse tip height: %v", err)
+	}
+	return height, nil
+}
+
+// getJSON decodes the response for the given path into v. It returns false if the resource does not exist.
+func (client *esploraClient) getJSON(ctx context.Context, path string, v interface{}) (bool, error) {
+	body, status, err := client.get(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	if status == http.StatusNotFound || status == http.StatusBadRequest {
+		return false, nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return false, fmt.Errorf("cannot decode esplora response: %v", err)
+	}
+	return true, nil
+}
+
+func (client *esploraClient) get(ctx context.Context, path string) ([]byte, int, error) {
+	return client.do(ctx, "GET", path, nil)
+}
+
+// do sends a request to the indexer and returns the response body and status code. Unexpected status codes are
+// returned as errors so that the proxy can try another client.
+func (client *esploraClient) do(ctx context.Context, method, path string, data []byte) ([]byte, int, error) {
+	req, err := http.NewRequest(method, client.url+path, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot construct request for esplora: %v", err)
+	}
+	req = req.WithContext(ctx)
+	if data != nil {
+		req.Header.Set("Content-Type", "text/plain")
+	}
+
+	resp, err := client.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot read esplora response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusBadRequest {
+		return nil, 0, fmt.Errorf("unexpected status code %d from esplora: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, resp.StatusCode, nil
+}
+
+type esploraStatus struct {
+	Confirmed   bool   `json:"confirmed"`
+	BlockHeight int64  `json:"block_height"`
+	BlockHash   string `json:"block_hash"`
+	BlockTime   int64  `json:"block_time"`
+}
+
+// confirmations returns the number of confirmations for the given status.
+func confirmations(tip int64, status esploraStatus) int64 {
+	if !status.Confirmed {
+		return 0
+	}
+	return tip - status.BlockHeight + 1
+}
+
+type esploraUTXO struct {
+	TxID   string        `json:"txid"`
+	Vout   uint32        `json:"vout"`
+	Status esploraStatus `json:"status"`
+	Value  int64         `json:"value"`
+}
+
+type esploraVin struct {
+	TxID       string   `json:"txid"`
+	Vout       uint32   `json:"vout"`
+	ScriptSig  string   `json:"scriptsig"`
+	Witness    []string `json:"witness"`
+	IsCoinbase bool     `json:"is_coinbase"`
+	Sequence   uint32   `json:"sequence"`
+}
+
+type esploraVout struct {
+	ScriptPubKey        string `json:"scriptpubkey"`
+	ScriptPubKeyType    string `json:"scriptpubkey_type"`
+	ScriptPubKeyAddress string `json:"scriptpubkey_address"`
+	Value               int64  `json:"value"`
+}
+
+func (vout esploraVout) scriptPubKey() ScriptPubKeyResult {
+	scriptType, ok := scriptTypes[vout.ScriptPubKeyType]
+	if !ok {
+		scriptType = "nonstandard"
+	}
+	return ScriptPubKeyResult{
+		Asm:     disasm(vout.ScriptPubKey),
+		Hex:     vout.ScriptPubKey,
+		Type:    scriptType,
+		Address: vout.ScriptPubKeyAddress,
+	}
+}
+
+type esploraTx struct {
+	TxID     string        `json:"txid"`
+	Version  int32         `json:"version"`
+	LockTime uint32        `json:"locktime"`
+	Vin      []esploraVin  `json:"vin"`
+	Vout     []esploraVout `json:"vout"`
+	Size     int64         `json:"size"`
+	Weight   int64         `json:"weight"`
+	Status   esploraStatus `json:"status"`
+}
+
+// result converts the transaction to the format returned by bitcoind.
+func (tx esploraTx) result(txHex string, tip int64) RawTransactionResult {
+	result := RawTransactionResult{
+		Hex:      txHex,
+		TxID:     tx.TxID,
+		Hash:     tx.TxID,
+		Size:     tx.Size,
+		VSize:    (tx.Weight + 3) / 4,
+		Weight:   tx.Weight,
+		Version:  tx.Version,
+		LockTime: tx.LockTime,
+		Vin:      make([]VinResult, len(tx.Vin)),
+		Vout:     make([]VoutResult, len(tx.Vout)),
+	}
+
+	// The witness hash is not returned by Esplora, so it is computed from the raw transaction when possible.
+	if raw, err := hex.DecodeString(txHex); err == nil {
+		msgTx := wire.NewMsgTx(wire.TxVersion)
+		if err := msgTx.Deserialize(bytes.NewReader(raw)); err == nil {
+			result.Hash = msgTx.WitnessHash().String()
+		}
+	}
+
+	for i, vin := range tx.Vin {
+		result.Vin[i] = VinResult{
+			TxInWitness: vin.Witness,
+			Sequence:    vin.Sequence,
+		}
+		if vin.IsCoinbase {
+			result.Vin[i].Coinbase = vin.ScriptSig
+			continue
+		}
+		result.Vin[i].TxID = vin.TxID
+		result.Vin[i].Vout = vin.Vout
+		result.Vin[i].ScriptSig = &ScriptSigResult{
+			Asm: disasm(vin.ScriptSig),
+			Hex: vin.ScriptSig,
+		}
+	}
+	for i, vout := range tx.Vout {
+		result.Vout[i] = VoutResult{
+			Value:        btcutil.Amount(vout.Value).ToBTC(),
+			N:            uint32(i),
+			ScriptPubKey: vout.scriptPubKey(),
+		}
+	}
+
+	if tx.Status.Confirmed {
+		result.BlockHash = tx.Status.BlockHash
+		result.Confirmations = confirmations(tip, tx.Status)
+		result.Time = tx.Status.BlockTime
+		result.BlockTime = tx.Status.BlockTime
+	}
+	return result
+}
+
+// disasm returns the bitcoind representation of a hex encoded script.
+func disasm(script string) string {
+	data, err := hex.DecodeString(script)
+	if err != nil {
+		return ""
+	}
+	asm, _ := txscript.DisasmString(data)
+	return asm
+}
@@ -0,0 +1,333 @@
+package rpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/rpc"
+
+	"github.com/renproject/mercury/rpcclient/btcrpcclient"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+var _ = Describe("Esplora RPC client", func() {
+	address, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), &chaincfg.TestNet3Params)
+	if err != nil {
+		panic(err)
+	}
+	script, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		panic(err)
+	}
+
+	// Build a transaction paying to the address so that the hex returned by the indexer can be decoded.
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), []byte{txscript.OP_TRUE}, nil))
+	msgTx.AddTxOut(wire.NewTxOut(50000, script))
+	buf := new(bytes.Buffer)
+	if err := msgTx.Serialize(buf); err != nil {
+		panic(err)
+	}
+	txHex := hex.EncodeToString(buf.Bytes())
+	txid := msgTx.TxHash().String()
+
+	esploraTx := fmt.Sprintf(`{
+		"txid": "%s",
+		"version": 1,
+		"locktime": 0,
+		"vin": [{"txid": "%s", "vout": 0, "scriptsig": "51", "is_coinbase": false, "sequence": 4294967295}],
+		"vout": [{"scriptpubkey": "%x", "scriptpubkey_type": "p2pkh", "scriptpubkey_address": "%s", "value": 50000}],
+		"size": %d,
+		"weight": %d,
+		"status": {"confirmed": true, "block_height": 91, "block_hash": "00ab", "block_time": 1500000000}
+	}`, txid, chainhash.Hash{1}.String(), script, address.EncodeAddress(), buf.Len(), 4*buf.Len())
+
+	// serve starts a fake Esplora indexer with a tip at height 100.
+	serve := func(spent bool) *httptest.Server {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/blocks/tip/height", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("100"))
+		})
+		mux.HandleFunc("/blocks/tip/hash", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("00cd"))
+		})
+		mux.HandleFunc("/address/"+address.EncodeAddress()+"/utxo", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `[
+				{"txid": "%s", "vout": 0, "status": {"confirmed": true, "block_height": 91}, "value": 50000},
+				{"txid": "%s", "vout": 1, "status": {"confirmed": false}, "value": 20000}
+			]`, txid, txid)
+		})
+		mux.HandleFunc("/tx/"+txid, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(esploraTx))
+		})
+		mux.HandleFunc("/tx/"+txid+"/hex", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(txHex))
+		})
+		mux.HandleFunc("/tx/"+txid+"/outspend/0", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"spent": %v}`, spent)
+		})
+		mux.HandleFunc("/tx", func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != txHex {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`sendrawtransaction RPC error: {"code":-22,"message":"TX decode failed"}`))
+				return
+			}
+			w.Write([]byte(txid))
+		})
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Transaction not found"))
+		})
+		return httptest.NewServer(mux)
+	}
+
+	send := func(client Client, method string, params string, result interface{}) (int, *types.JSONError) {
+		r, err := http.NewRequest("POST", "http://0.0.0.0:5000/btc/testnet", nil)
+		Expect(err).ToNot(HaveOccurred())
+		data := fmt.Sprintf(`{"jsonrpc":"1.0","id":1,"method":"%s","params":%s}`, method, params)
+		resp, err := client.HandleRequest(context.Background(), r, []byte(data))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		body := struct {
+			Result json.RawMessage  `json:"result"`
+			Error  *types.JSONError `json:"error"`
+			ID     int              `json:"id"`
+		}{}
+		Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+		Expect(body.ID).To(Equal(1))
+		if result != nil {
+			Expect(json.Unmarshal(body.Result, result)).To(Succeed())
+		}
+		return resp.StatusCode, body.Error
+	}
+
+	Context("when getting the block count", func() {
+		It("should return the height of the tip", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			height := int64(0)
+			_, rpcErr := send(client, "getblockcount", `[]`, &height)
+			Expect(rpcErr).To(BeNil())
+			Expect(height).To(Equal(int64(100)))
+		})
+	})
+
+	Context("when listing unspent outputs", func() {
+		It("should return the outputs of any address", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			utxos := btcrpcclient.ListUnspentResponse{}
+			_, rpcErr := send(client, "listunspent", fmt.Sprintf(`[0, 999999, ["%s"]]`, address.EncodeAddress()), &utxos)
+			Expect(rpcErr).To(BeNil())
+			Expect(utxos).To(HaveLen(2))
+			Expect(utxos[0].TxID).To(Equal(txid))
+			Expect(utxos[0].Amount).To(Equal(0.0005))
+			Expect(utxos[0].Confirmations).To(Equal(int64(10)))
+			Expect(utxos[0].ScriptPubKey).To(Equal(hex.EncodeToString(script)))
+			Expect(utxos[1].Confirmations).To(Equal(int64(0)))
+		})
+
+		It("should filter outputs by their confirmations", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			utxos := btcrpcclient.ListUnspentResponse{}
+			_, rpcErr := send(client, "listunspent", fmt.Sprintf(`[1, 9999999, ["%s"]]`, address.EncodeAddress()), &utxos)
+			Expect(rpcErr).To(BeNil())
+			Expect(utxos).To(HaveLen(1))
+		})
+
+		It("should return an error for invalid addresses", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			status, rpcErr := send(client, "listunspent", `[0, 999999, ["invalid"]]`, nil)
+			Expect(status).To(Equal(http.StatusInternalServerError))
+			Expect(rpcErr.Code).To(Equal(ErrorCodeInvalidAddress))
+		})
+
+		It("should return an error for too many addresses", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			addrs := make([]string, 21)
+			for i := range addrs {
+				addrs[i] = address.EncodeAddress()
+			}
+			params, err := json.Marshal([]interface{}{0, 999999, addrs})
+			Expect(err).ToNot(HaveOccurred())
+			_, rpcErr := send(client, "listunspent", string(params), nil)
+			Expect(rpcErr.Code).To(Equal(ErrorCodeInvalidParams))
+		})
+	})
+
+	Context("when getting transaction outputs", func() {
+		It("should return unspent outputs", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			txOut := btcrpcclient.GetTxOutResponse{}
+			_, rpcErr := send(client, "gettxout", fmt.Sprintf(`["%s", 0]`, txid), &txOut)
+			Expect(rpcErr).To(BeNil())
+			Expect(txOut.Confirmations).To(Equal(int64(10)))
+			Expect(txOut.Value).To(Equal(0.0005))
+			Expect(txOut.ScriptPubKey.Hex).To(Equal(hex.EncodeToString(script)))
+		})
+
+		It("should return null for spent or missing outputs", func() {
+			server := serve(true)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			var result interface{} = "not null"
+			_, rpcErr := send(client, "gettxout", fmt.Sprintf(`["%s", 0]`, txid), &result)
+			Expect(rpcErr).To(BeNil())
+			Expect(result).To(BeNil())
+
+			result = "not null"
+			_, rpcErr = send(client, "gettxout", fmt.Sprintf(`["%s", 1]`, txid), &result)
+			Expect(rpcErr).To(BeNil())
+			Expect(result).To(BeNil())
+
+			result = "not null"
+			_, rpcErr = send(client, "gettxout", fmt.Sprintf(`["%s", 0]`, chainhash.Hash{2}), &result)
+			Expect(rpcErr).To(BeNil())
+			Expect(result).To(BeNil())
+		})
+
+		It("should return an error for invalid transaction hashes", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			for _, invalid := range []string{"abcd", txid + "/outspend/1", "../blocks/tip/hash" + txid[18:]} {
+				_, rpcErr := send(client, "gettxout", fmt.Sprintf(`["%s", 0]`, invalid), nil)
+				Expect(rpcErr.Code).To(Equal(ErrorCodeInvalidParams))
+				_, rpcErr = send(client, "getrawtransaction", fmt.Sprintf(`["%s"]`, invalid), nil)
+				Expect(rpcErr.Code).To(Equal(ErrorCodeInvalidParams))
+			}
+		})
+	})
+
+	Context("when getting raw transactions", func() {
+		It("should return the transaction hex", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			result := ""
+			_, rpcErr := send(client, "getrawtransaction", fmt.Sprintf(`["%s"]`, txid), &result)
+			Expect(rpcErr).To(BeNil())
+			Expect(result).To(Equal(txHex))
+		})
+
+		It("should return the verbose transaction", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			for _, verbose := range []string{"true", "1"} {
+				tx := btcrpcclient.RawTransactionVerbose{}
+				_, rpcErr := send(client, "getrawtransaction", fmt.Sprintf(`["%s", %s]`, txid, verbose), &tx)
+				Expect(rpcErr).To(BeNil())
+				Expect(tx.TxID).To(Equal(txid))
+				Expect(tx.Confirmations).To(Equal(uint32(10)))
+			}
+
+			result := RawTransactionResult{}
+			_, rpcErr := send(client, "getrawtransaction", fmt.Sprintf(`["%s", true]`, txid), &result)
+			Expect(rpcErr).To(BeNil())
+			Expect(result.Hex).To(Equal(txHex))
+			Expect(result.Hash).To(Equal(txid))
+			Expect(result.VSize).To(Equal(int64(buf.Len())))
+			Expect(result.Vin[0].ScriptSig.Asm).To(Equal("1"))
+			Expect(result.Vout[0].ScriptPubKey.Type).To(Equal("pubkeyhash"))
+			Expect(result.Vout[0].ScriptPubKey.Address).To(Equal(address.EncodeAddress()))
+		})
+
+		It("should return an error for missing transactions", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			_, rpcErr := send(client, "getrawtransaction", fmt.Sprintf(`["%s", true]`, chainhash.Hash{2}), nil)
+			Expect(rpcErr.Code).To(Equal(ErrorCodeNoSuchTransaction))
+		})
+	})
+
+	Context("when sending raw transactions", func() {
+		It("should return the transaction hash", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			result := ""
+			_, rpcErr := send(client, "sendrawtransaction", fmt.Sprintf(`["%s"]`, txHex), &result)
+			Expect(rpcErr).To(BeNil())
+			Expect(result).To(Equal(txid))
+		})
+
+		It("should return the error of the node", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			_, rpcErr := send(client, "sendrawtransaction", `["abcd"]`, nil)
+			Expect(rpcErr.Code).To(Equal(ErrorCodeDeserialization))
+			Expect(rpcErr.Message).To(Equal("TX decode failed"))
+		})
+	})
+
+	Context("when the indexer is unavailable", func() {
+		It("should return an error so that another client can be tried", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			r, err := http.NewRequest("POST", "http://0.0.0.0:5000/btc/testnet", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = client.HandleRequest(context.Background(), r, []byte(`{"jsonrpc":"1.0","id":1,"method":"getrawtransaction","params":["`+txid+`"]}`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when calling unsupported methods", func() {
+		It("should return a method not found error", func() {
+			server := serve(false)
+			defer server.Close()
+			client := NewEsploraClient(server.URL, btctypes.BtcTestnet)
+
+			status, rpcErr := send(client, "getblockchaininfo", `[]`, nil)
+			Expect(status).To(Equal(http.StatusNotFound))
+			Expect(rpcErr.Code).To(Equal(ErrorCodeMethodNotFound))
+			Expect(strings.ToLower(rpcErr.Message)).To(ContainSubstring("not found"))
+		})
+	})
+})
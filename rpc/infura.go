@@ -1,40 +1,29 @@
 package rpc
 
 import (
-	"bytes"
-	"fmt"
-	"net/http"
-
 	"github.com/renproject/mercury/types/ethtypes"
 )
 
-// infuraClient implements the `Client` interface.
-type infuraClient struct {
-	network    ethtypes.Network
-	url        string
-	taggedKeys map[string]string
-}
+// InfuraURL is the URL template for Infura.
+const InfuraURL = "https://{network}.infura.io/v3/{key}"
 
-// NewInfuraClient returns a new infuraClient.
-func NewInfuraClient(network ethtypes.Network, taggedKeys map[string]string) Client {
-	return &infuraClient{
-		network:    network,
-		url:        fmt.Sprintf("https://%s.infura.io/v3", network.String()),
-		taggedKeys: taggedKeys,
+// InfuraConfig returns the provider config for Infura, which expects the API key in the URL path.
+func InfuraConfig(network ethtypes.Network, taggedKeys map[string][]string, opts Options) ProviderConfig {
+	return ProviderConfig{
+		URL:        InfuraURL,
+		Network:    network.String(),
+		Auth:       AuthURL,
+		TaggedKeys: taggedKeys,
+		Options:    opts,
 	}
 }
 
-// HandleRequest implements the `Client` interface.
-func (infura *infuraClient) HandleRequest(r *http.Request, data []byte) (*http.Response, error) {
-	tag := r.URL.Query().Get("tag")
-	apiKey := infura.taggedKeys[tag]
-	if apiKey == "" {
-		apiKey = infura.taggedKeys[""]
-	}
-	client := http.Client{}
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/%s", infura.url, apiKey), bytes.NewBuffer(data))
-	if err != nil {
-		return nil, fmt.Errorf("cannot construct post request for infura: %v", err)
-	}
-	return client.Do(req)
+// NewInfuraClient returns a new client for Infura.
+func NewInfuraClient(network ethtypes.Network, taggedKeys map[string][]string) Client {
+	return NewInfuraClientWithOptions(network, taggedKeys, DefaultOptions())
+}
+
+// NewInfuraClientWithOptions returns a new client for Infura which sends requests using the given options.
+func NewInfuraClientWithOptions(network ethtypes.Network, taggedKeys map[string][]string, opts Options) Client {
+	return NewProviderClient(InfuraConfig(network, taggedKeys, opts))
 }
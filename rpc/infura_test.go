@@ -2,6 +2,7 @@ package rpc_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -18,8 +19,8 @@ var _ = Describe("Infura RPC client", func() {
 	Context("when interacting with the infura client", func() {
 		It("should return the correct response", func() {
 			infuraAPIKey := os.Getenv("INFURA_KEY_DEFAULT")
-			client := NewInfuraClient(ethtypes.Kovan, map[string]string{
-				"": infuraAPIKey,
+			client := NewInfuraClient(ethtypes.Kovan, map[string][]string{
+				"": {infuraAPIKey},
 			})
 
 			r, err := http.NewRequest("POST", "http://0.0.0.0:5000/eth/kovan", nil)
@@ -27,7 +28,7 @@ var _ = Describe("Infura RPC client", func() {
 			data := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_gasPrice","params":[]}`)
 
 			// Handle request using Infura client.
-			resp, err := client.HandleRequest(r, data)
+			resp, err := client.HandleRequest(context.Background(), r, data)
 			Expect(err).ToNot(HaveOccurred())
 
 			respBytes, err := ioutil.ReadAll(resp.Body)
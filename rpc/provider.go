@@ -0,0 +1,321 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renproject/mercury/types"
+)
+
+// AuthStyle defines how the API key is sent to a hosted provider.
+type AuthStyle uint8
+
+const (
+	// AuthURL sends the API key as part of the URL using the `{key}` placeholder in the URL template.
+	AuthURL AuthStyle = 0
+	// AuthQuery sends the API key as a query parameter named `KeyName`.
+	AuthQuery AuthStyle = 1
+	// AuthHeader sends the API key in a header named `KeyName`.
+	AuthHeader AuthStyle = 2
+	// AuthBearer sends the API key as a bearer token in the `Authorization` header.
+	AuthBearer AuthStyle = 3
+)
+
+// ProviderConfig configures a client for a hosted provider or gateway.
+type ProviderConfig struct {
+	// URL is a template for the provider URL. The `{network}` and `{key}` placeholders are replaced by the network and
+	// (URL escaped) API key respectively.
+	URL     string
+	Network string
+
+	// Auth defines how the API key is sent, and KeyName is the name of the query parameter or header for the
+	// `AuthQuery` and `AuthHeader` styles.
+	Auth    AuthStyle
+	KeyName string
+
+	// TaggedKeys maps the `tag` query parameter of incoming requests to API keys. Requests with an unknown tag use the
+	// keys of the empty tag. If a key is rate limited, the next key of the tag is used until the Cooldown has passed.
+	TaggedKeys map[string][]string
+	Cooldown   time.Duration
+
+	// Headers are added to every request sent to the provider.
+	Headers map[string]string
+
+	Options Options
+}
+
+// DefaultCooldown is how long a rate limited API key is skipped for.
+const DefaultCooldown = time.Minute
+
+// ErrorCodeLimitExceeded is the JSON-RPC error code returned by hosted providers when a request limit is exceeded.
+const ErrorCodeLimitExceeded = -32005
+
+// UsageReporter is implemented by clients which count the requests sent with each API key.
+type UsageReporter interface {
+	Usage() Usage
+}
+
+// Usage is the number of requests sent for each tag and API key.
+type Usage struct {
+	Tags map[string]uint64 `json:"tags"`
+	Keys []KeyUsage        `json:"keys"`
+}
+
+// Merge returns the combined usage.
+func (usage Usage) Merge(other Usage) Usage {
+	merged := Usage{
+		Tags: map[string]uint64{},
+		Keys: append(append([]KeyUsage{}, usage.Keys...), other.Keys...),
+	}
+	for tag, count := range usage.Tags {
+		merged.Tags[tag] += count
+	}
+	for tag, count := range other.Tags {
+		merged.Tags[tag] += count
+	}
+	return merged
+}
+
+// KeyUsage is the number of requests sent with an API key. The key itself is masked.
+type KeyUsage struct {
+	Tag           string    `json:"tag"`
+	Key           string    `json:"key"`
+	Requests      uint64    `json:"requests"`
+	Throttled     uint64    `json:"throttled"`
+	CooldownUntil time.Time `json:"cooldownUntil"`
+}
+
+// providerKey is an API key and its usage.
+type providerKey struct {
+	usage KeyUsage
+	key   string
+}
+
+// providerClient implements the `Client` interface.
+type providerClient struct {
+	config ProviderConfig
+	client *http.Client
+
+	mu   *sync.Mutex
+	tags map[string]uint64
+	keys map[string][]*providerKey
+	next map[string]int
+}
+
+// NewProviderClient returns a new client for a hosted provider.
+func NewProviderClient(config ProviderConfig) Client {
+	if config.Options.Transport == nil && config.Options.Timeout == 0 {
+		config.Options = DefaultOptions()
+	}
+	if config.Cooldown == 0 {
+		config.Cooldown = DefaultCooldown
+	}
+
+	keys := map[string][]*providerKey{}
+	for tag, tagKeys := range config.TaggedKeys {
+		for _, key := range tagKeys {
+			keys[tag] = append(keys[tag], &providerKey{
+				usage: KeyUsage{Tag: tag, Key: maskKey(key)},
+				key:   key,
+			})
+		}
+	}
+	return &providerClient{
+		config: config,
+		client: NewHTTPClient(config.Options),
+		mu:     &sync.Mutex{},
+		tags:   map[string]uint64{},
+		keys:   keys,
+		next:   map[string]int{},
+	}
+}
+
+// HandleRequest implements the `Client` interface. Requests which are rate limited are retried with the other keys of
+// the tag, and the response of the last attempt is returned if every key is rate limited.
+func (provider *providerClient) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
+	tag := provider.tag(r.URL.Query().Get("tag"))
+	attempts := len(provider.keys[tag])
+	if attempts == 0 {
+		attempts = 1
+	}
+
+	for i := 0; ; i++ {
+		key := provider.apiKey(tag)
+		resp, err := provider.send(ctx, key, data)
+		if err != nil {
+			return nil, err
+		}
+		throttled, err := isThrottled(resp)
+		if err != nil {
+			return nil, err
+		}
+		if !throttled {
+			return resp, nil
+		}
+
+		provider.cooldown(tag, key)
+		if i+1 >= attempts {
+			return resp, nil
+		}
+		resp.Body.Close()
+	}
+}
+
+// Usage implements the `UsageReporter` interface.
+func (provider *providerClient) Usage() Usage {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	usage := Usage{
+		Tags: make(map[string]uint64, len(provider.tags)),
+		Keys: []KeyUsage{},
+	}
+	for tag, count := range provider.tags {
+		usage.Tags[tag] = count
+	}
+	for _, tag := range sortedTags(provider.keys) {
+		for _, key := range provider.keys[tag] {
+			usage.Keys = append(usage.Keys, key.usage)
+		}
+	}
+	return usage
+}
+
+func (provider *providerClient) send(ctx context.Context, key *providerKey, data []byte) (*http.Response, error) {
+	apiKey := ""
+	if key != nil {
+		apiKey = key.key
+	}
+	req, err := http.NewRequest("POST", provider.endpoint(apiKey), bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot construct post request for provider: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range provider.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	switch provider.config.Auth {
+	case AuthQuery:
+		query := req.URL.Query()
+		query.Set(provider.config.KeyName, apiKey)
+		req.URL.RawQuery = query.Encode()
+	case AuthHeader:
+		req.Header.Set(provider.config.KeyName, apiKey)
+	case AuthBearer:
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	}
+	return provider.client.Do(req)
+}
+
+// tag returns the tag whose keys are used for the given request tag and counts the request. Unknown tags are counted
+// as the empty tag.
+func (provider *providerClient) tag(tag string) string {
+	if _, ok := provider.keys[tag]; !ok {
+		tag = ""
+	}
+	provider.mu.Lock()
+	provider.tags[tag]++
+	provider.mu.Unlock()
+	return tag
+}
+
+// apiKey returns the current key of the tag, skipping keys which are cooling down. If every key is cooling down, the
+// key which was rate limited first is returned.
+func (provider *providerClient) apiKey(tag string) *providerKey {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	keys := provider.keys[tag]
+	if len(keys) == 0 {
+		return nil
+	}
+	now := time.Now()
+	selected := keys[provider.next[tag]%len(keys)]
+	for i := range keys {
+		key := keys[(provider.next[tag]+i)%len(keys)]
+		if now.After(key.usage.CooldownUntil) {
+			selected = key
+			break
+		}
+		if key.usage.CooldownUntil.Before(selected.usage.CooldownUntil) {
+			selected = key
+		}
+	}
+	selected.usage.Requests++
+	return selected
+}
+
+// cooldown skips the key until the cooldown has passed and rotates to the next key of the tag.
+func (provider *providerClient) cooldown(tag string, key *providerKey) {
+	if key == nil {
+		return
+	}
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	key.usage.Throttled++
+	key.usage.CooldownUntil = time.Now().Add(provider.config.Cooldown)
+	for i := range provider.keys[tag] {
+		if provider.keys[tag][i] == key {
+			provider.next[tag] = i + 1
+		}
+	}
+}
+
+// endpoint returns the provider URL for the given API key.
+func (provider *providerClient) endpoint(apiKey string) string {
+	key := ""
+	if provider.config.Auth == AuthURL {
+		key = url.PathEscape(apiKey)
+	}
+	return strings.NewReplacer("{network}", provider.config.Network, "{key}", key).Replace(provider.config.URL)
+}
+
+// isThrottled returns whether the response shows that a request limit has been exceeded. The body of the response is
+// buffered so that it can still be read.
+func isThrottled(resp *http.Response) (bool, error) {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return false, fmt.Errorf("cannot read provider response: %v", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	jsonResp := types.JSONResponse{}
+	if err := json.Unmarshal(body, &jsonResp); err != nil || jsonResp.Error == nil {
+		return false, nil
+	}
+	return jsonResp.Error.Code == ErrorCodeLimitExceeded || strings.Contains(strings.ToLower(jsonResp.Error.Message), "limit exceeded"), nil
+}
+
+// maskKey hides all but the last four characters of an API key.
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
+}
+
+func sortedTags(keys map[string][]*providerKey) []string {
+	tags := make([]string, 0, len(keys))
+	for tag := range keys {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
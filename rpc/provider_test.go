@@ -0,0 +1,209 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/rpc"
+)
+
+var _ = Describe("Provider RPC client", func() {
+	// serve starts a server which records the last request it received.
+	serve := func(last **http.Request) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*last = r
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+		}))
+	}
+
+	send := func(client Client, tag string) {
+		r, err := http.NewRequest("POST", "http://0.0.0.0:5000/eth/mainnet?tag="+tag, nil)
+		Expect(err).ToNot(HaveOccurred())
+		resp, err := client.HandleRequest(context.Background(), r, []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+	}
+
+	taggedKeys := map[string][]string{
+		"":         {"default-key"},
+		"darknode": {"darknode-key"},
+	}
+
+	Context("when sending the key in the url", func() {
+		It("should replace the placeholders in the url template", func() {
+			var last *http.Request
+			server := serve(&last)
+			defer server.Close()
+
+			client := NewProviderClient(ProviderConfig{
+				URL:        server.URL + "/{network}/v3/{key}",
+				Network:    "mainnet",
+				Auth:       AuthURL,
+				TaggedKeys: taggedKeys,
+			})
+			send(client, "")
+			Expect(last.URL.Path).To(Equal("/mainnet/v3/default-key"))
+			Expect(last.Header.Get("Content-Type")).To(Equal("application/json"))
+
+			send(client, "darknode")
+			Expect(last.URL.Path).To(Equal("/mainnet/v3/darknode-key"))
+
+			send(client, "unknown")
+			Expect(last.URL.Path).To(Equal("/mainnet/v3/default-key"))
+		})
+	})
+
+	Context("when sending the key as a query parameter", func() {
+		It("should add the key to the query", func() {
+			var last *http.Request
+			server := serve(&last)
+			defer server.Close()
+
+			client := NewProviderClient(ProviderConfig{
+				URL:        server.URL + "/rpc?chain={network}",
+				Network:    "bsc",
+				Auth:       AuthQuery,
+				KeyName:    "apikey",
+				TaggedKeys: taggedKeys,
+			})
+			send(client, "darknode")
+			Expect(last.URL.Path).To(Equal("/rpc"))
+			Expect(last.URL.Query().Get("chain")).To(Equal("bsc"))
+			Expect(last.URL.Query().Get("apikey")).To(Equal("darknode-key"))
+		})
+	})
+
+	Context("when sending the key in a header", func() {
+		It("should set the custom header", func() {
+			var last *http.Request
+			server := serve(&last)
+			defer server.Close()
+
+			client := NewProviderClient(ProviderConfig{
+				URL:        server.URL,
+				Auth:       AuthHeader,
+				KeyName:    "X-Api-Key",
+				TaggedKeys: taggedKeys,
+				Headers:    map[string]string{"X-Client": "mercury"},
+			})
+			send(client, "")
+			Expect(last.Header.Get("X-Api-Key")).To(Equal("default-key"))
+			Expect(last.Header.Get("X-Client")).To(Equal("mercury"))
+		})
+
+		It("should set the bearer token", func() {
+			var last *http.Request
+			server := serve(&last)
+			defer server.Close()
+
+			client := NewProviderClient(ProviderConfig{
+				URL:        server.URL,
+				Auth:       AuthBearer,
+				TaggedKeys: taggedKeys,
+			})
+			send(client, "darknode")
+			Expect(last.Header.Get("Authorization")).To(Equal("Bearer darknode-key"))
+		})
+	})
+
+	Context("when a key is rate limited", func() {
+		// serveLimited starts a server which rate limits the given keys, either with a status code or a JSON-RPC error.
+		serveLimited := func(limited map[string]bool, jsonError bool, keys *[]string) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				key := r.Header.Get("X-Api-Key")
+				*keys = append(*keys, key)
+				if !limited[key] {
+					w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+					return
+				}
+				if jsonError {
+					w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"daily request count exceeded, request rate limited"}}`))
+					return
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+		}
+
+		newClient := func(url string, cooldown time.Duration) Client {
+			return NewProviderClient(ProviderConfig{
+				URL:     url,
+				Auth:    AuthHeader,
+				KeyName: "X-Api-Key",
+				TaggedKeys: map[string][]string{
+					"":         {"default-key"},
+					"darknode": {"darknode-key-1", "darknode-key-2"},
+				},
+				Cooldown: cooldown,
+			})
+		}
+
+		It("should rotate to the next key of the tag", func() {
+			for _, jsonError := range []bool{false, true} {
+				keys := []string{}
+				server := serveLimited(map[string]bool{"darknode-key-1": true}, jsonError, &keys)
+
+				client := newClient(server.URL, time.Hour)
+				send(client, "darknode")
+				send(client, "darknode")
+				Expect(keys).To(Equal([]string{"darknode-key-1", "darknode-key-2", "darknode-key-2"}))
+				server.Close()
+			}
+		})
+
+		It("should reuse the key once the cooldown has passed", func() {
+			keys := []string{}
+			limited := map[string]bool{"darknode-key-1": true}
+			server := serveLimited(limited, false, &keys)
+			defer server.Close()
+
+			client := newClient(server.URL, 100*time.Millisecond)
+			send(client, "darknode")
+			limited["darknode-key-1"], limited["darknode-key-2"] = false, true
+			time.Sleep(200 * time.Millisecond)
+			send(client, "darknode")
+			Expect(keys).To(Equal([]string{"darknode-key-1", "darknode-key-2", "darknode-key-2", "darknode-key-1"}))
+		})
+
+		It("should return the rate limited response if every key is rate limited", func() {
+			keys := []string{}
+			server := serveLimited(map[string]bool{"darknode-key-1": true, "darknode-key-2": true}, false, &keys)
+			defer server.Close()
+
+			client := newClient(server.URL, time.Hour)
+			r, err := http.NewRequest("POST", "http://0.0.0.0:5000/eth/mainnet?tag=darknode", nil)
+			Expect(err).ToNot(HaveOccurred())
+			resp, err := client.HandleRequest(context.Background(), r, []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(keys).To(Equal([]string{"darknode-key-1", "darknode-key-2"}))
+		})
+
+		It("should count the requests of each tag and key", func() {
+			keys := []string{}
+			server := serveLimited(map[string]bool{"darknode-key-1": true}, false, &keys)
+			defer server.Close()
+
+			client := newClient(server.URL, time.Hour)
+			send(client, "darknode")
+			send(client, "darknode")
+			send(client, "unknown")
+
+			usage := client.(UsageReporter).Usage()
+			Expect(usage.Tags).To(Equal(map[string]uint64{"": 1, "darknode": 2}))
+			Expect(usage.Keys).To(HaveLen(3))
+			Expect(usage.Keys[0].Tag).To(Equal(""))
+			Expect(usage.Keys[0].Key).To(Equal("*******-key"))
+			Expect(usage.Keys[0].Requests).To(Equal(uint64(1)))
+			Expect(usage.Keys[1].Tag).To(Equal("darknode"))
+			Expect(usage.Keys[1].Requests).To(Equal(uint64(1)))
+			Expect(usage.Keys[1].Throttled).To(Equal(uint64(1)))
+			Expect(usage.Keys[1].CooldownUntil).To(BeTemporally(">", time.Now()))
+			Expect(usage.Keys[2].Key).To(Equal("**********ey-2"))
+			Expect(usage.Keys[2].Requests).To(Equal(uint64(2)))
+		})
+	})
+})
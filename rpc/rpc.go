@@ -2,15 +2,94 @@ package rpc
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"time"
 )
 
 // Client is a RPC client which can send and retrieve information from a blockchain through JSON-RPC. `data` is the
-// request data we want to send to the ZCash node, and `r` is the original request in case we need to access any query
-// parameters or other fields.
+// request data we want to send to the node, and `r` is the original request in case we need to access any query
+// parameters or other fields. The request to the node is cancelled when `ctx` is done.
 type Client interface {
-	HandleRequest(r *http.Request, data []byte) (*http.Response, error)
+	HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error)
+}
+
+// TransportOptions configures the pool of keep-alive connections used by a transport.
+type TransportOptions struct {
+	// MaxIdleConns is the maximum number of idle connections across all hosts.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the maximum number of idle connections kept for each host.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the total number of connections to each host. Zero means no limit.
+	MaxConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept in the pool before being closed.
+	IdleConnTimeout time.Duration
+	// DialTimeout is the maximum amount of time to wait for a connection to be established.
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout is the maximum amount of time to wait for a TLS handshake.
+	TLSHandshakeTimeout time.Duration
+}
+
+// DefaultTransportOptions returns the options used by the `DefaultTransport`.
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		MaxIdleConns:        256,
+		MaxIdleConnsPerHost: 32,
+		MaxConnsPerHost:     0,
+		IdleConnTimeout:     90 * time.Second,
+		DialTimeout:         10 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// NewTransport returns a new transport which keeps a bounded pool of idle connections to each host.
+func NewTransport(opts TransportOptions) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   opts.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        opts.MaxIdleConns,
+		MaxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     opts.MaxConnsPerHost,
+		IdleConnTimeout:     opts.IdleConnTimeout,
+		TLSHandshakeTimeout: opts.TLSHandshakeTimeout,
+	}
+}
+
+// DefaultTransport is shared by all clients which are not given their own transport, so that connections to the same
+// host can be reused across clients.
+var DefaultTransport = NewTransport(DefaultTransportOptions())
+
+// Options configures how a client sends requests to its node.
+type Options struct {
+	// Timeout is the maximum duration of a request to the node, including reading the response body.
+	Timeout time.Duration
+	// Transport is used to send requests. Clients which share a transport share its pool of connections.
+	Transport http.RoundTripper
+}
+
+// DefaultOptions returns the options used by clients which are not given any.
+func DefaultOptions() Options {
+	return Options{
+		Timeout:   30 * time.Second,
+		Transport: DefaultTransport,
+	}
+}
+
+// NewHTTPClient returns a new HTTP client with the given options.
+func NewHTTPClient(opts Options) *http.Client {
+	transport := opts.Transport
+	if transport == nil {
+		transport = DefaultTransport
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+	}
 }
 
 // client implements the `Client` interface.
@@ -18,25 +97,32 @@ type client struct {
 	host     string
 	username string
 	password string
+	client   *http.Client
 }
 
 // NewClient returns a new client.
 func NewClient(host, username, password string) Client {
+	return NewClientWithOptions(host, username, password, DefaultOptions())
+}
+
+// NewClientWithOptions returns a new client which sends requests using the given options.
+func NewClientWithOptions(host, username, password string, opts Options) Client {
 	return &client{
 		host:     host,
 		username: username,
 		password: password,
+		client:   NewHTTPClient(opts),
 	}
 }
 
 // HandleRequest implements the `Client` interface.
-func (node *client) HandleRequest(r *http.Request, data []byte) (*http.Response, error) {
-	client := http.Client{}
+func (node *client) HandleRequest(ctx context.Context, r *http.Request, data []byte) (*http.Response, error) {
 	req, err := http.NewRequest("POST", node.host, bytes.NewBuffer(data))
-	req.Header.Set("Content-Type", "application/json")
-	req.SetBasicAuth(node.username, node.password)
 	if err != nil {
-		return nil, fmt.Errorf("cannot construct post request for zcash node: %v", err)
+		return nil, fmt.Errorf("cannot construct post request for node: %v", err)
 	}
-	return client.Do(req)
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(node.username, node.password)
+	return node.client.Do(req)
 }
@@ -2,9 +2,12 @@ package rpc_test
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -24,7 +27,7 @@ var _ = Describe("RPC client", func() {
 			data := []byte(`{"jsonrpc":"2.0","id":1,"method":"getunconfirmedbalance","params":[]}`)
 
 			// Handle request using ZCash client.
-			resp, err := client.HandleRequest(r, data)
+			resp, err := client.HandleRequest(context.Background(), r, data)
 			Expect(err).ToNot(HaveOccurred())
 
 			respBytes, err := ioutil.ReadAll(resp.Body)
@@ -55,7 +58,7 @@ var _ = Describe("RPC client", func() {
 			data := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_gasPrice","params":[]}`)
 
 			// Handle request using Infura client.
-			resp, err := client.HandleRequest(r, data)
+			resp, err := client.HandleRequest(context.Background(), r, data)
 			Expect(err).ToNot(HaveOccurred())
 
 			respBytes, err := ioutil.ReadAll(resp.Body)
@@ -73,3 +76,76 @@ var _ = Describe("RPC client", func() {
 		})
 	})
 })
+
+var _ = Describe("RPC client options", func() {
+	Context("when sending requests to a node", func() {
+		It("should set the basic auth credentials and content type", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, password, ok := r.BasicAuth()
+				Expect(ok).To(BeTrue())
+				Expect(user).To(Equal("user"))
+				Expect(password).To(Equal("password"))
+				Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+				w.Write([]byte(`{"result":1}`))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, "user", "password")
+			r, err := http.NewRequest("POST", "http://0.0.0.0:5000/btc/testnet", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			resp, err := client.HandleRequest(context.Background(), r, []byte(`{"method":"getblockcount"}`))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("should return an error for an invalid host", func() {
+			client := NewClient(":invalid", "user", "password")
+			r, err := http.NewRequest("POST", "http://0.0.0.0:5000/btc/testnet", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = client.HandleRequest(context.Background(), r, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should stop the request when the context is cancelled", func() {
+			done := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-done
+			}))
+			defer server.Close()
+			defer close(done)
+
+			client := NewClient(server.URL, "", "")
+			r, err := http.NewRequest("POST", "http://0.0.0.0:5000/btc/testnet", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			_, err = client.HandleRequest(ctx, r, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
+		It("should stop the request after the timeout", func() {
+			done := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-done
+			}))
+			defer server.Close()
+			defer close(done)
+
+			transport := NewTransport(DefaultTransportOptions())
+			client := NewClientWithOptions(server.URL, "", "", Options{Timeout: 100 * time.Millisecond, Transport: transport})
+			r, err := http.NewRequest("POST", "http://0.0.0.0:5000/btc/testnet", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			start := time.Now()
+			_, err = client.HandleRequest(context.Background(), r, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+	})
+})
@@ -26,19 +26,146 @@ type ListUnspentObj struct {
 	Spendable     bool    `json:"spendable"`
 }
 
+// RawTransactionVerbose is a decoded transaction. The block fields are only set for transactions which have been
+// mined, and are never set by `decoderawtransaction`.
 type RawTransactionVerbose struct {
+	Hex           string `json:"hex"`
 	TxID          string `json:"txid"`
+	Hash          string `json:"hash"`
+	Size          int64  `json:"size"`
+	VSize         int64  `json:"vsize"`
+	Weight        int64  `json:"weight"`
+	Version       int32  `json:"version"`
+	LockTime      uint32 `json:"locktime"`
+	Vin           []Vin  `json:"vin"`
+	Vout          []Vout `json:"vout"`
+	BlockHash     string `json:"blockhash"`
 	Confirmations uint32 `json:"confirmations"`
+	Time          int64  `json:"time"`
+	BlockTime     int64  `json:"blocktime"`
+}
+
+// Vin is an input of a decoded transaction. Coinbase inputs only have the coinbase and sequence fields set.
+type Vin struct {
+	Coinbase    string    `json:"coinbase"`
+	TxID        string    `json:"txid"`
+	Vout        uint32    `json:"vout"`
+	ScriptSig   ScriptSig `json:"scriptSig"`
+	TxInWitness []string  `json:"txinwitness"`
+	Sequence    uint32    `json:"sequence"`
+}
+
+// Vout is an output of a decoded transaction.
+type Vout struct {
+	Value        float64      `json:"value"`
+	N            uint32       `json:"n"`
+	ScriptPubKey ScriptPubKey `json:"scriptPubKey"`
+}
+
+type ScriptSig struct {
+	Asm string `json:"asm"`
+	Hex string `json:"hex"`
 }
 
 type GetTxOutResponse struct {
+	BestBlock     string       `json:"bestblock"`
 	Confirmations int64        `json:"confirmations"`
 	Value         float64      `json:"value"`
 	ScriptPubKey  ScriptPubKey `json:"scriptPubKey"`
+	Coinbase      bool         `json:"coinbase"`
 }
 
+// ScriptPubKey is the script of an output. Older nodes return the addresses of the script in `Addresses` instead of
+// `Address`.
 type ScriptPubKey struct {
-	Hex string `json:"hex"`
+	Asm       string   `json:"asm"`
+	Hex       string   `json:"hex"`
+	Type      string   `json:"type"`
+	Address   string   `json:"address"`
+	Addresses []string `json:"addresses"`
+	ReqSigs   int64    `json:"reqSigs"`
+}
+
+// BlockHeaderVerbose is the result of `getblockheader` in verbose mode.
+type BlockHeaderVerbose struct {
+	Hash              string  `json:"hash"`
+	Confirmations     int64   `json:"confirmations"`
+	Height            int64   `json:"height"`
+	Version           int32   `json:"version"`
+	MerkleRoot        string  `json:"merkleroot"`
+	Time              int64   `json:"time"`
+	MedianTime        int64   `json:"mediantime"`
+	Nonce             uint64  `json:"nonce"`
+	Bits              string  `json:"bits"`
+	Difficulty        float64 `json:"difficulty"`
+	ChainWork         string  `json:"chainwork"`
+	NTx               int64   `json:"nTx"`
+	PreviousBlockHash string  `json:"previousblockhash"`
+	NextBlockHash     string  `json:"nextblockhash"`
+}
+
+// BlockVerbose is the result of `getblock` with a verbosity of 1, which includes the hashes of the transactions.
+type BlockVerbose struct {
+	BlockHeaderVerbose
+	Size         int64    `json:"size"`
+	StrippedSize int64    `json:"strippedsize"`
+	Weight       int64    `json:"weight"`
+	Tx           []string `json:"tx"`
+}
+
+// EstimateSmartFeeResponse is the result of `estimatesmartfee`. The fee rate is in BTC/kB, and is not set if the node
+// does not have enough data to estimate the fee (in which case `Errors` is set).
+type EstimateSmartFeeResponse struct {
+	FeeRate float64  `json:"feerate"`
+	Errors  []string `json:"errors"`
+	Blocks  int64    `json:"blocks"`
+}
+
+// MempoolEntry is the result of `getmempoolentry`. The fees are in BTC.
+type MempoolEntry struct {
+	VSize           int64       `json:"vsize"`
+	Size            int64       `json:"size"`
+	Weight          int64       `json:"weight"`
+	Fee             float64     `json:"fee"`
+	ModifiedFee     float64     `json:"modifiedfee"`
+	Time            int64       `json:"time"`
+	Height          int64       `json:"height"`
+	DescendantCount int64       `json:"descendantcount"`
+	DescendantSize  int64       `json:"descendantsize"`
+	AncestorCount   int64       `json:"ancestorcount"`
+	AncestorSize    int64       `json:"ancestorsize"`
+	WTxID           string      `json:"wtxid"`
+	Fees            MempoolFees `json:"fees"`
+	Depends         []string    `json:"depends"`
+	SpentBy         []string    `json:"spentby"`
+	Replaceable     bool        `json:"bip125-replaceable"`
+}
+
+type MempoolFees struct {
+	Base       float64 `json:"base"`
+	Modified   float64 `json:"modified"`
+	Ancestor   float64 `json:"ancestor"`
+	Descendant float64 `json:"descendant"`
+}
+
+// TestMempoolAcceptResult is the result of `testmempoolaccept` for a single transaction. The reject reason is set if
+// the transaction would not be accepted.
+type TestMempoolAcceptResult struct {
+	TxID         string      `json:"txid"`
+	WTxID        string      `json:"wtxid"`
+	Allowed      bool        `json:"allowed"`
+	VSize        int64       `json:"vsize"`
+	Fees         MempoolFees `json:"fees"`
+	RejectReason string      `json:"reject-reason"`
+}
+
+// TxOutResult is the transaction and the unspent output of an outpoint. The errors are set if the transaction or the
+// output could not be retrieved.
+type TxOutResult struct {
+	Tx       RawTransactionVerbose
+	TxErr    error
+	TxOut    GetTxOutResponse
+	TxOutErr error
 }
 
 type Client interface {
@@ -46,6 +173,17 @@ type Client interface {
 	SendRawTransaction(ctx context.Context, stx btctypes.BtcTx) (string, error)
 	GetTxOut(ctx context.Context, txid types.TxHash, i uint32) (GetTxOutResponse, error)
 	GetRawTransactionVerbose(ctx context.Context, txid types.TxHash) (RawTransactionVerbose, error)
+	GetBlockCount(ctx context.Context) (int64, error)
+	GetBestBlockHash(ctx context.Context) (string, error)
+	GetBlockHeader(ctx context.Context, blockHash string) (BlockHeaderVerbose, error)
+	GetBlock(ctx context.Context, blockHash string) (BlockVerbose, error)
+	EstimateSmartFee(ctx context.Context, confTarget int64) (EstimateSmartFeeResponse, error)
+	GetMempoolEntry(ctx context.Context, txid types.TxHash) (MempoolEntry, error)
+	TestMempoolAccept(ctx context.Context, stxs []btctypes.BtcTx) ([]TestMempoolAcceptResult, error)
+	DecodeRawTransaction(ctx context.Context, rawTx []byte) (RawTransactionVerbose, error)
+
+	// GetTxOuts returns the transaction and the unspent output of each outpoint using a single batch request.
+	GetTxOuts(ctx context.Context, outpoints []btctypes.OutPoint) ([]TxOutResult, error)
 }
 
 type rpcClient struct {
@@ -98,3 +236,94 @@ func (client *rpcClient) GetRawTransactionVerbose(ctx context.Context, txid type
 	}
 	return resp, nil
 }
+
+func (client *rpcClient) GetBlockCount(ctx context.Context) (int64, error) {
+	var resp int64
+	if err := client.client.SendRequest(ctx, "getblockcount", &resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (client *rpcClient) GetBestBlockHash(ctx context.Context) (string, error) {
+	resp := ""
+	if err := client.client.SendRequest(ctx, "getbestblockhash", &resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (client *rpcClient) GetBlockHeader(ctx context.Context, blockHash string) (BlockHeaderVerbose, error) {
+	resp := BlockHeaderVerbose{}
+	if err := client.client.SendRequest(ctx, "getblockheader", &resp, blockHash, true); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (client *rpcClient) GetBlock(ctx context.Context, blockHash string) (BlockVerbose, error) {
+	resp := BlockVerbose{}
+	if err := client.client.SendRequest(ctx, "getblock", &resp, blockHash, 1); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (client *rpcClient) EstimateSmartFee(ctx context.Context, confTarget int64) (EstimateSmartFeeResponse, error) {
+	resp := EstimateSmartFeeResponse{}
+	if err := client.client.SendRequest(ctx, "estimatesmartfee", &resp, confTarget); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (client *rpcClient) GetMempoolEntry(ctx context.Context, txid types.TxHash) (MempoolEntry, error) {
+	resp := MempoolEntry{}
+	if err := client.client.SendRequest(ctx, "getmempoolentry", &resp, txid); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (client *rpcClient) TestMempoolAccept(ctx context.Context, stxs []btctypes.BtcTx) ([]TestMempoolAcceptResult, error) {
+	rawTxs := make([]string, len(stxs))
+	for i, stx := range stxs {
+		stxBytes, err := stx.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		rawTxs[i] = hex.EncodeToString(stxBytes)
+	}
+	resp := []TestMempoolAcceptResult{}
+	if err := client.client.SendRequest(ctx, "testmempoolaccept", &resp, rawTxs); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (client *rpcClient) DecodeRawTransaction(ctx context.Context, rawTx []byte) (RawTransactionVerbose, error) {
+	resp := RawTransactionVerbose{}
+	if err := client.client.SendRequest(ctx, "decoderawtransaction", &resp, hex.EncodeToString(rawTx)); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (client *rpcClient) GetTxOuts(ctx context.Context, outpoints []btctypes.OutPoint) ([]TxOutResult, error) {
+	results := make([]TxOutResult, len(outpoints))
+	calls := make([]rpcclient.Call, 0, 2*len(outpoints))
+	for i, op := range outpoints {
+		calls = append(calls,
+			rpcclient.NewCall("getrawtransaction", &results[i].Tx, op.TxHash(), 1),
+			rpcclient.NewCall("gettxout", &results[i].TxOut, op.TxHash(), op.Vout()),
+		)
+	}
+	if err := client.client.SendBatch(ctx, calls); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].TxErr = calls[2*i].Error
+		results[i].TxOutErr = calls[2*i+1].Error
+	}
+	return results, nil
+}
@@ -0,0 +1,13 @@
+package btcrpcclient_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBtcrpcclient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Btcrpcclient Suite")
+}
@@ -0,0 +1,136 @@
+package btcrpcclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/rpcclient/btcrpcclient"
+)
+
+var _ = Describe("Bitcoin JSON-RPC client", func() {
+	// newNode returns a node which responds to each method with the given result, and records the parameters of the
+	// last request of each method.
+	newNode := func(results map[string]string, params map[string]json.RawMessage) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+
+			req := struct {
+				ID     int64           `json:"id"`
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params"`
+			}{}
+			Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+			params[req.Method] = req.Params
+			result, ok := results[req.Method]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprintf(w, `{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":%d}`, req.ID)
+				return
+			}
+			fmt.Fprintf(w, `{"result":%s,"error":null,"id":%d}`, result, req.ID)
+		}))
+	}
+
+	It("should decode verbose transactions", func() {
+		params := map[string]json.RawMessage{}
+		node := newNode(map[string]string{
+			"getrawtransaction": `{
+				"txid": "aa", "hash": "bb", "version": 2, "size": 225, "vsize": 144, "weight": 573, "locktime": 0,
+				"vin": [
+					{"txid": "cc", "vout": 1, "scriptSig": {"asm": "", "hex": ""}, "txinwitness": ["30", "02"], "sequence": 4294967293}
+				],
+				"vout": [
+					{"value": 0.0001, "n": 0, "scriptPubKey": {"asm": "0 dd", "hex": "0014dd", "type": "witness_v0_keyhash", "address": "tb1qdd"}}
+				],
+				"hex": "0200", "blockhash": "ee", "confirmations": 3, "time": 1600000000, "blocktime": 1600000000
+			}`,
+		}, params)
+		defer node.Close()
+		client := NewRPCClient(node.URL, "", "", time.Millisecond)
+
+		tx, err := client.GetRawTransactionVerbose(context.Background(), "aa")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(params["getrawtransaction"]).To(MatchJSON(`["aa", 1]`))
+		Expect(tx.TxID).To(Equal("aa"))
+		Expect(tx.VSize).To(Equal(int64(144)))
+		Expect(tx.BlockHash).To(Equal("ee"))
+		Expect(tx.Confirmations).To(Equal(uint32(3)))
+		Expect(tx.Time).To(Equal(int64(1600000000)))
+		Expect(tx.Vin).To(HaveLen(1))
+		Expect(tx.Vin[0].TxID).To(Equal("cc"))
+		Expect(tx.Vin[0].TxInWitness).To(Equal([]string{"30", "02"}))
+		Expect(tx.Vin[0].Sequence).To(Equal(uint32(4294967293)))
+		Expect(tx.Vout).To(HaveLen(1))
+		Expect(tx.Vout[0].ScriptPubKey.Type).To(Equal("witness_v0_keyhash"))
+		Expect(tx.Vout[0].ScriptPubKey.Address).To(Equal("tb1qdd"))
+	})
+
+	It("should return the blocks and the state of the chain", func() {
+		params := map[string]json.RawMessage{}
+		node := newNode(map[string]string{
+			"getblockcount":    `1000`,
+			"getbestblockhash": `"ff"`,
+			"getblockheader":   `{"hash": "ff", "confirmations": 1, "height": 1000, "previousblockhash": "fe", "nTx": 2}`,
+			"getblock":         `{"hash": "ff", "height": 1000, "weight": 4000, "tx": ["aa", "bb"]}`,
+			"estimatesmartfee": `{"feerate": 0.00012, "blocks": 2}`,
+		}, params)
+		defer node.Close()
+		client := NewRPCClient(node.URL, "", "", time.Millisecond)
+		ctx := context.Background()
+
+		height, err := client.GetBlockCount(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(height).To(Equal(int64(1000)))
+
+		hash, err := client.GetBestBlockHash(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hash).To(Equal("ff"))
+
+		header, err := client.GetBlockHeader(ctx, hash)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(params["getblockheader"]).To(MatchJSON(`["ff", true]`))
+		Expect(header.PreviousBlockHash).To(Equal("fe"))
+		Expect(header.NTx).To(Equal(int64(2)))
+
+		block, err := client.GetBlock(ctx, hash)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(params["getblock"]).To(MatchJSON(`["ff", 1]`))
+		Expect(block.Height).To(Equal(int64(1000)))
+		Expect(block.Tx).To(Equal([]string{"aa", "bb"}))
+
+		fee, err := client.EstimateSmartFee(ctx, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(params["estimatesmartfee"]).To(MatchJSON(`[2]`))
+		Expect(fee.FeeRate).To(Equal(0.00012))
+		Expect(fee.Blocks).To(Equal(int64(2)))
+	})
+
+	It("should return the mempool entries and decode raw transactions", func() {
+		params := map[string]json.RawMessage{}
+		node := newNode(map[string]string{
+			"getmempoolentry":      `{"vsize": 141, "weight": 561, "time": 1600000000, "height": 999, "fees": {"base": 0.00000282}, "depends": [], "bip125-replaceable": true}`,
+			"decoderawtransaction": `{"txid": "aa", "vin": [{"coinbase": "03", "sequence": 4294967295}], "vout": []}`,
+		}, params)
+		defer node.Close()
+		client := NewRPCClient(node.URL, "", "", time.Millisecond)
+		ctx := context.Background()
+
+		entry, err := client.GetMempoolEntry(ctx, "aa")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.VSize).To(Equal(int64(141)))
+		Expect(entry.Fees.Base).To(Equal(0.00000282))
+		Expect(entry.Replaceable).To(BeTrue())
+
+		tx, err := client.DecodeRawTransaction(ctx, []byte{0x02, 0x00})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(params["decoderawtransaction"]).To(MatchJSON(`["0200"]`))
+		Expect(tx.Vin[0].Coinbase).To(Equal("03"))
+		Expect(tx.BlockHash).To(BeEmpty())
+	})
+})
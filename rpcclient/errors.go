@@ -0,0 +1,43 @@
+package rpcclient
+
+import "github.com/renproject/mercury/types"
+
+// Error codes returned by bitcoind when a transaction is submitted.
+const (
+	// CodeVerifyError is returned for general errors during transaction submission (e.g. missing inputs).
+	CodeVerifyError = -25
+	// CodeVerifyRejected is returned if the transaction or block was rejected by the network rules (e.g. the fee is
+	// too low).
+	CodeVerifyRejected = -26
+	// CodeVerifyAlreadyInChain is returned if the transaction is already in the chain.
+	CodeVerifyAlreadyInChain = -27
+)
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	// CodeServerError is returned by geth for most errors, which are identified by their message.
+	CodeServerError = -32000
+)
+
+// Sentinel errors which can be matched against the errors returned by the client using `errors.Is`. Use `errors.As`
+// with a `*types.JSONError` to get the code, message and data of an error.
+var (
+	ErrVerify         = &types.JSONError{Code: CodeVerifyError}
+	ErrMissingInputs  = &types.JSONError{Code: CodeVerifyError, Message: "missing"}
+	ErrVerifyRejected = &types.JSONError{Code: CodeVerifyRejected}
+	// ErrInsufficientFee, ErrMinRelayFeeNotMet and ErrMempoolMinFeeNotMet are returned when the fee of a transaction is
+	// too low. Rejections for fees which are too high (e.g. "absurdly-high-fee") do not match them.
+	ErrInsufficientFee     = &types.JSONError{Code: CodeVerifyRejected, Message: "insufficient fee"}
+	ErrMinRelayFeeNotMet   = &types.JSONError{Code: CodeVerifyRejected, Message: "min relay fee not met"}
+	ErrMempoolMinFeeNotMet = &types.JSONError{Code: CodeVerifyRejected, Message: "mempool min fee not met"}
+	ErrAlreadyInChain      = &types.JSONError{Code: CodeVerifyAlreadyInChain}
+
+	ErrMethodNotFound = &types.JSONError{Code: CodeMethodNotFound}
+	ErrInvalidParams  = &types.JSONError{Code: CodeInvalidParams}
+
+	ErrNonceTooLow            = &types.JSONError{Code: CodeServerError, Message: "nonce too low"}
+	ErrReplacementUnderpriced = &types.JSONError{Code: CodeServerError, Message: "replacement transaction underpriced"}
+	ErrAlreadyKnown           = &types.JSONError{Code: CodeServerError, Message: "already known"}
+)
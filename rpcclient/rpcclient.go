@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"time"
+
+	"github.com/renproject/mercury/types"
 )
 
 // request represents a JSON-RPC request sent by a client.
@@ -22,34 +25,90 @@ type request struct {
 // response represents a JSON-RPC response returned to a client.
 type response struct {
 	Result *json.RawMessage `json:"result"`
-	Error  interface{}      `json:"error"`
+	Error  json.RawMessage  `json:"error"`
 	ID     int64            `json:"id"`
 }
 
-// errObj is a wrapper for a JSON interface value.
-type errObj struct {
-	Data interface{}
+// Call is a JSON-RPC call sent as part of a batch. The result of the call is decoded into Result, and Error is set if
+// the call failed.
+type Call struct {
+	Method string
+	Params []interface{}
+	Result interface{}
+	Error  error
 }
 
-func (e *errObj) Error() string {
-	return fmt.Sprintf("%v", e.Data)
+// NewCall returns a call of the method which decodes its result into the given value.
+func NewCall(method string, result interface{}, params ...interface{}) Call {
+	return Call{
+		Method: method,
+		Params: params,
+		Result: result,
+	}
 }
 
 type Client interface {
 	SendRequest(ctx context.Context, method string, response interface{}, params ...interface{}) error
+
+	// SendBatch sends the calls in a single request. An error is returned if the request fails, otherwise the result
+	// or the error of each call is set.
+	SendBatch(ctx context.Context, calls []Call) error
+}
+
+// Options configure how a client retries failed requests. Requests are only retried if they fail because of the
+// transport, or if the node responds with a 5xx or 429 status code. Errors returned by the JSON-RPC methods are never
+// retried.
+type Options struct {
+	// MaxAttempts is the maximum number of times a request is sent.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. The delay is doubled after every retry, up to MaxBackoff, and
+	// a random jitter of up to half the delay is subtracted from it.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// HTTPClient is used to send requests.
+	HTTPClient *http.Client
+}
+
+// DefaultOptions returns the options used by clients which are not given any.
+func DefaultOptions() Options {
+	return Options{
+		MaxAttempts: 5,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+		HTTPClient:  http.DefaultClient,
+	}
 }
 
 type client struct {
 	host     string
 	user     string
 	password string
-
-	retryDelay time.Duration
+	opts     Options
 }
 
+// NewClient returns a new client which waits for the given delay before retrying a request for the first time.
 func NewClient(host, user, password string, retryDelay time.Duration) Client {
+	opts := DefaultOptions()
+	opts.MinBackoff = retryDelay
+	if opts.MaxBackoff < retryDelay {
+		opts.MaxBackoff = retryDelay
+	}
+	return NewClientWithOptions(host, user, password, opts)
+}
+
+// NewClientWithOptions returns a new client with the given options.
+func NewClientWithOptions(host, user, password string, opts Options) Client {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
 	return &client{
-		host, user, password, retryDelay,
+		host:     host,
+		user:     user,
+		password: password,
+		opts:     opts,
 	}
 }
 
@@ -58,33 +117,96 @@ func (client *client) SendRequest(ctx context.Context, method string, response i
 	if err != nil {
 		return err
 	}
+	return client.send(ctx, data, func(body []byte) error {
+		return decodeResponse(body, response)
+	})
+}
 
-	return retry(ctx, client.retryDelay, func() error {
+// send sends the request to the node and decodes the response body, retrying the request if it fails because of the
+// transport, or if the node is unavailable or rate limits the client.
+func (client *client) send(ctx context.Context, data []byte, decode func(body []byte) error) error {
+	return retry(ctx, client.opts, func() error {
 		request, err := http.NewRequest("POST", client.host, bytes.NewBuffer(data))
 		if err != nil {
 			return err
 		}
 		request.SetBasicAuth(client.user, client.password)
-		// request = request.WithContext(ctx)
-		resp, err := http.DefaultClient.Do(request)
+		request = request.WithContext(ctx)
+		resp, err := client.opts.HTTPClient.Do(request)
 		if err != nil {
-			return err
+			return retryable{err}
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return retryable{fmt.Errorf("cannot read response body: %v", err)}
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return retryable{fmt.Errorf("rate limited: %s", body)}
 		}
-		return decodeResponse(resp.Body, response)
+		err = decode(body)
+		if resp.StatusCode >= 500 && err != nil && !isApplicationError(err) {
+			return retryable{fmt.Errorf("unexpected status %d: %w", resp.StatusCode, err)}
+		}
+		return err
 	})
 }
 
-// encodeRequest encodes parameters for a JSON-RPC client request.
-func encodeRequest(method string, params []interface{}) ([]byte, error) {
-	ps := make([]json.RawMessage, len(params))
+func (client *client) SendBatch(ctx context.Context, calls []Call) error {
+	if len(calls) == 0 {
+		return nil
+	}
 
-	var err error
-	for i := range ps {
-		ps[i], err = json.Marshal(params[i])
+	// The index of each call is used as its ID so that the responses can be matched with the calls.
+	reqs := make([]request, len(calls))
+	for i, call := range calls {
+		ps, err := encodeParams(call.Params)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		reqs[i] = request{
+			Version: "2.0",
+			ID:      int64(i),
+			Method:  call.Method,
+			Params:  ps,
+		}
+	}
+	data, err := json.Marshal(reqs)
+	if err != nil {
+		return err
+	}
+
+	var resps []response
+	if err := client.send(ctx, data, func(body []byte) (err error) {
+		resps, err = decodeBatchResponse(body)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	received := make([]bool, len(calls))
+	for _, resp := range resps {
+		if resp.ID < 0 || resp.ID >= int64(len(calls)) || received[resp.ID] {
+			continue
+		}
+		received[resp.ID] = true
+		calls[resp.ID].Error = resp.decode(calls[resp.ID].Result)
+	}
+	for i := range calls {
+		if !received[i] {
+			calls[i].Error = fmt.Errorf("missing response for call %d", i)
 		}
 	}
+	return nil
+}
+
+// encodeRequest encodes parameters for a JSON-RPC client request.
+func encodeRequest(method string, params []interface{}) ([]byte, error) {
+	ps, err := encodeParams(params)
+	if err != nil {
+		return nil, err
+	}
 
 	req := &request{
 		Version: "2.0",
@@ -95,16 +217,42 @@ func encodeRequest(method string, params []interface{}) ([]byte, error) {
 	return json.Marshal(req)
 }
 
+// encodeParams encodes the parameters of a JSON-RPC call.
+func encodeParams(params []interface{}) ([]json.RawMessage, error) {
+	ps := make([]json.RawMessage, len(params))
+
+	var err error
+	for i := range ps {
+		ps[i], err = json.Marshal(params[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ps, nil
+}
+
 // decodeResponse decodes the response body of a client request into the interface reply.
-func decodeResponse(r io.Reader, reply interface{}) error {
+func decodeResponse(body []byte, reply interface{}) error {
 	var c response
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(r)
-	if err := json.Unmarshal(buf.Bytes(), &c); err != nil {
-		return fmt.Errorf("cannot decode response body = %s, err = %v", buf.String(), err)
+	if err := json.Unmarshal(body, &c); err != nil {
+		return fmt.Errorf("cannot decode response body = %s, err = %v", body, err)
 	}
-	if c.Error != nil {
-		return &errObj{Data: c.Error}
+	return c.decode(reply)
+}
+
+// decodeBatchResponse decodes the response body of a batch request.
+func decodeBatchResponse(body []byte) ([]response, error) {
+	var resps []response
+	if err := json.Unmarshal(body, &resps); err != nil {
+		return nil, fmt.Errorf("cannot decode batch response body = %s, err = %v", body, err)
+	}
+	return resps, nil
+}
+
+// decode decodes the result of the response into the interface reply. Errors are returned as a `*types.JSONError`.
+func (c response) decode(reply interface{}) error {
+	if len(c.Error) > 0 && string(c.Error) != "null" {
+		return decodeError(c.Error)
 	}
 	if c.Result == nil {
 		return ErrNullResult
@@ -114,25 +262,66 @@ func decodeResponse(r io.Reader, reply interface{}) error {
 
 var ErrNullResult = fmt.Errorf("unexpected null result")
 
-func retry(ctx context.Context, delay time.Duration, fn func() error) error {
-	ticker := time.NewTicker(delay)
-	err := fn()
-	if err == nil {
-		return nil
+// decodeError decodes the error of a response. Errors which are not JSON-RPC error objects (e.g. the error messages
+// returned by Mercury) are returned with a zero code.
+func decodeError(data json.RawMessage) error {
+	jsonErr := types.JSONError{}
+	if err := json.Unmarshal(data, &jsonErr); err == nil && (jsonErr.Code != 0 || jsonErr.Message != "") {
+		return &jsonErr
 	}
+	msg := ""
+	if err := json.Unmarshal(data, &msg); err == nil {
+		return &types.JSONError{Message: msg}
+	}
+	return &types.JSONError{Message: string(data)}
+}
 
-	for {
+// retryable is an error which is caused by the transport or the availability of the node, so the request can be
+// retried.
+type retryable struct {
+	err error
+}
+
+func (err retryable) Error() string {
+	return err.err.Error()
+}
+
+// isApplicationError returns whether the error is a JSON-RPC error object returned by the method. These errors are
+// deterministic (e.g. invalid params or a transaction which is already in the chain) so they are not retried.
+func isApplicationError(err error) bool {
+	jsonErr := &types.JSONError{}
+	return errors.As(err, &jsonErr) && jsonErr.Code != 0
+}
+
+// retry calls the function until it succeeds, returns an error which cannot be retried, or the maximum number of
+// attempts is reached. It waits with an exponential backoff and jitter between attempts.
+func retry(ctx context.Context, opts Options, fn func() error) error {
+	backoff := opts.MinBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		retryErr, ok := err.(retryable)
+		if !ok {
+			return err
+		}
+		if attempt >= opts.MaxAttempts {
+			return retryErr.err
+		}
+
+		delay := backoff
+		if delay > 0 {
+			delay -= time.Duration(rand.Int63n(int64(delay)/2 + 1))
+		}
+		timer := time.NewTimer(delay)
 		select {
 		case <-ctx.Done():
-			if err != nil {
-				return err
-			}
-			return ctx.Err()
-		case <-ticker.C:
-			err = fn()
-			if err == nil {
-				return nil
-			}
+			timer.Stop()
+			return retryErr.err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
 		}
 	}
 }
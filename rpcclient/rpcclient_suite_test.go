@@ -0,0 +1,13 @@
+package rpcclient_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRpcclient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Rpcclient Suite")
+}
@@ -0,0 +1,268 @@
+package rpcclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/rpcclient"
+
+	"github.com/renproject/mercury/types"
+)
+
+var _ = Describe("JSON-RPC client", func() {
+	// newNode returns a node which doubles the first parameter of the `double` method, and counts the number of
+	// requests it receives.
+	newNode := func(requests *int64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+
+			atomic.AddInt64(requests, 1)
+			reqs := []struct {
+				ID     int64  `json:"id"`
+				Method string `json:"method"`
+				Params []int  `json:"params"`
+			}{}
+			Expect(json.NewDecoder(r.Body).Decode(&reqs)).To(Succeed())
+
+			// Respond in reverse order to make sure the responses are matched by ID.
+			resps := []string{}
+			for i := len(reqs) - 1; i >= 0; i-- {
+				req := reqs[i]
+				switch req.Method {
+				case "double":
+					resps = append(resps, fmt.Sprintf(`{"result":%d,"error":null,"id":%d}`, 2*req.Params[0], req.ID))
+				case "null":
+					resps = append(resps, fmt.Sprintf(`{"result":null,"error":null,"id":%d}`, req.ID))
+				default:
+					resps = append(resps, fmt.Sprintf(`{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":%d}`, req.ID))
+				}
+			}
+			fmt.Fprintf(w, "[%s]", strings.Join(resps, ","))
+		}))
+	}
+
+	Context("when sending a batch", func() {
+		It("should set the result or the error of each call using a single request", func() {
+			requests := int64(0)
+			node := newNode(&requests)
+			defer node.Close()
+			client := NewClient(node.URL, "", "", 10*time.Millisecond)
+
+			results := make([]int, 3)
+			calls := []Call{
+				NewCall("double", &results[0], 1),
+				NewCall("double", &results[1], 2),
+				NewCall("unknown", &results[2]),
+				NewCall("null", new(int)),
+			}
+			Expect(client.SendBatch(context.Background(), calls)).To(Succeed())
+			Expect(requests).To(Equal(int64(1)))
+
+			Expect(calls[0].Error).NotTo(HaveOccurred())
+			Expect(calls[1].Error).NotTo(HaveOccurred())
+			Expect(results[:2]).To(Equal([]int{2, 4}))
+			Expect(calls[2].Error).To(MatchError(ContainSubstring("Method not found")))
+			Expect(calls[3].Error).To(Equal(ErrNullResult))
+		})
+
+		It("should not send a request for an empty batch", func() {
+			requests := int64(0)
+			node := newNode(&requests)
+			defer node.Close()
+			client := NewClient(node.URL, "", "", 10*time.Millisecond)
+
+			Expect(client.SendBatch(context.Background(), nil)).To(Succeed())
+			Expect(requests).To(Equal(int64(0)))
+		})
+
+		It("should return an error if the response is not a batch", func() {
+			node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"error":"cannot get the method","id":-32600}`)
+			}))
+			defer node.Close()
+			client := NewClient(node.URL, "", "", 10*time.Millisecond)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			Expect(client.SendBatch(ctx, []Call{NewCall("double", new(int), 1)})).NotTo(Succeed())
+		})
+	})
+
+	Context("when a request fails", func() {
+		opts := Options{
+			MaxAttempts: 3,
+			MinBackoff:  time.Millisecond,
+			MaxBackoff:  10 * time.Millisecond,
+		}
+
+		// newFailingNode returns a node which responds with the given status and body until it has received the given
+		// number of requests, after which it responds successfully.
+		newFailingNode := func(failures int64, status int, body string, requests *int64) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt64(requests, 1) <= failures {
+					w.WriteHeader(status)
+					fmt.Fprint(w, body)
+					return
+				}
+				fmt.Fprint(w, `{"result":1,"error":null,"id":1}`)
+			}))
+		}
+
+		It("should retry if the node is unavailable or rate limits the client", func() {
+			for _, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+				requests := int64(0)
+				node := newFailingNode(2, status, "unavailable", &requests)
+				client := NewClientWithOptions(node.URL, "", "", opts)
+
+				result := 0
+				Expect(client.SendRequest(context.Background(), "getblockcount", &result)).To(Succeed())
+				Expect(result).To(Equal(1))
+				Expect(requests).To(Equal(int64(3)))
+				node.Close()
+			}
+		})
+
+		It("should stop retrying after the maximum number of attempts", func() {
+			requests := int64(0)
+			node := newFailingNode(10, http.StatusServiceUnavailable, "unavailable", &requests)
+			defer node.Close()
+			client := NewClientWithOptions(node.URL, "", "", opts)
+
+			Expect(client.SendRequest(context.Background(), "getblockcount", new(int))).NotTo(Succeed())
+			Expect(requests).To(Equal(int64(3)))
+		})
+
+		It("should not retry errors returned by the method", func() {
+			requests := int64(0)
+			body := `{"result":null,"error":{"code":-27,"message":"transaction already in block chain"},"id":1}`
+			node := newFailingNode(10, http.StatusInternalServerError, body, &requests)
+			defer node.Close()
+			client := NewClientWithOptions(node.URL, "", "", opts)
+
+			err := client.SendRequest(context.Background(), "sendrawtransaction", new(string), "abcd")
+			Expect(err).To(MatchError(ContainSubstring("transaction already in block chain")))
+			Expect(requests).To(Equal(int64(1)))
+		})
+
+		It("should not retry client errors", func() {
+			requests := int64(0)
+			node := newFailingNode(10, http.StatusBadRequest, "bad request", &requests)
+			defer node.Close()
+			client := NewClientWithOptions(node.URL, "", "", opts)
+
+			Expect(client.SendRequest(context.Background(), "getblockcount", new(int))).NotTo(Succeed())
+			Expect(requests).To(Equal(int64(1)))
+		})
+
+		It("should cancel the request when the context is done", func() {
+			// The node does not respond until the test is done.
+			release := make(chan struct{})
+			node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			}))
+			defer node.Close()
+			defer close(release)
+			client := NewClientWithOptions(node.URL, "", "", opts)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			Expect(client.SendRequest(ctx, "getblockcount", new(int))).NotTo(Succeed())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+	})
+
+	Context("when a method returns an error", func() {
+		respond := func(body string) error {
+			node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, body)
+			}))
+			defer node.Close()
+			client := NewClientWithOptions(node.URL, "", "", Options{MaxAttempts: 1})
+			return client.SendRequest(context.Background(), "sendrawtransaction", new(string), "abcd")
+		}
+
+		It("should decode the code, message and data of the error", func() {
+			err := respond(`{"result":null,"error":{"code":-26,"message":"min relay fee not met","data":{"fee":1}},"id":1}`)
+			jsonErr := &types.JSONError{}
+			Expect(errors.As(err, &jsonErr)).To(BeTrue())
+			Expect(jsonErr.Code).To(Equal(CodeVerifyRejected))
+			Expect(jsonErr.Message).To(Equal("min relay fee not met"))
+			Expect(jsonErr.Data).To(MatchJSON(`{"fee":1}`))
+		})
+
+		It("should match the sentinel errors", func() {
+			cases := []struct {
+				body     string
+				matches  []error
+				mismatch []error
+			}{
+				{
+					`{"code":-27,"message":"transaction already in block chain"}`,
+					[]error{ErrAlreadyInChain},
+					[]error{ErrVerifyRejected, ErrMissingInputs},
+				},
+				{
+					`{"code":-25,"message":"bad-txns-inputs-missingorspent"}`,
+					[]error{ErrVerify, ErrMissingInputs},
+					[]error{ErrAlreadyInChain},
+				},
+				{
+					`{"code":-26,"message":"insufficient fee"}`,
+					[]error{ErrVerifyRejected, ErrInsufficientFee},
+					[]error{ErrVerify, ErrMinRelayFeeNotMet, ErrMempoolMinFeeNotMet},
+				},
+				{
+					`{"code":-26,"message":"min relay fee not met, 100 < 141"}`,
+					[]error{ErrVerifyRejected, ErrMinRelayFeeNotMet},
+					[]error{ErrInsufficientFee, ErrMempoolMinFeeNotMet},
+				},
+				{
+					`{"code":-26,"message":"absurdly-high-fee"}`,
+					[]error{ErrVerifyRejected},
+					[]error{ErrInsufficientFee, ErrMinRelayFeeNotMet, ErrMempoolMinFeeNotMet},
+				},
+				{
+					`{"code":-32000,"message":"nonce too low"}`,
+					[]error{ErrNonceTooLow},
+					[]error{ErrReplacementUnderpriced},
+				},
+				{
+					`{"code":-32000,"message":"replacement transaction underpriced"}`,
+					[]error{ErrReplacementUnderpriced},
+					[]error{ErrNonceTooLow},
+				},
+			}
+			for _, c := range cases {
+				err := respond(fmt.Sprintf(`{"result":null,"error":%s,"id":1}`, c.body))
+
+				// Errors wrapped by the caller should still match.
+				wrapped := fmt.Errorf("cannot send raw transaction: %w", err)
+				for _, target := range c.matches {
+					Expect(errors.Is(wrapped, target)).To(BeTrue(), fmt.Sprintf("%v should match %v", err, target))
+				}
+				for _, target := range c.mismatch {
+					Expect(errors.Is(wrapped, target)).To(BeFalse(), fmt.Sprintf("%v should not match %v", err, target))
+				}
+			}
+		})
+
+		It("should return errors which are not error objects as messages", func() {
+			err := respond(`{"error":"method unavailable: stop","id":1}`)
+			jsonErr := &types.JSONError{}
+			Expect(errors.As(err, &jsonErr)).To(BeTrue())
+			Expect(jsonErr.Code).To(BeZero())
+			Expect(jsonErr.Message).To(Equal("method unavailable: stop"))
+		})
+	})
+})
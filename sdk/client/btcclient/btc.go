@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/btcsuite/btcd/chaincfg"
@@ -23,8 +24,24 @@ import (
 
 const (
 	Dust = btctypes.Amount(600)
+
+	// DogeDust is the dust limit of Dogecoin (0.01 DOGE), which is higher than the dust limit of the other chains.
+	DogeDust = btctypes.Amount(1000000)
+
+	// MinFeeRate is the lowest fee rate (in the smallest unit per virtual byte) which is relayed by nodes.
+	MinFeeRate = btctypes.FeeRate(1)
 )
 
+// defaultFeeRates are the fee rates (in the smallest unit per byte) of the chains which are not supported by the gas
+// station.
+var defaultFeeRates = map[types.Chain]btctypes.FeeRate{
+	types.Litecoin: 10,
+	types.Dogecoin: 1000,
+}
+
+// fallbackFeeRate is the fee rate (in the smallest unit per byte) used if the gas station is unavailable.
+const fallbackFeeRate btctypes.FeeRate = 10
+
 // Client is a client which is used to talking with certain Bitcoin network. It can interacting with the blockchain
 // through Mercury server.
 type client struct {
@@ -48,22 +65,49 @@ type BchClient struct {
 	Client
 }
 
+type LtcClient struct {
+	Client
+}
+
+type DogeClient struct {
+	Client
+}
+
+// LocalMercuryURL is the base URL of a Mercury server running on the local machine.
+const LocalMercuryURL = "http://0.0.0.0:5000"
+
+// MercuryURL returns the URL of the network on the default Mercury server. Local networks (localnet and regtest) use
+// the local Mercury server.
 func MercuryURL(network btctypes.Network) string {
-	switch network.String() {
-	case btctypes.BtcMainnet.String(), btctypes.ZecMainnet.String(), btctypes.BchMainnet.String():
-		return fmt.Sprintf("%s/%s/mainnet", mclient.MercuryURL, network.Chain().String())
-	case btctypes.BtcTestnet.String(), btctypes.ZecTestnet.String(), btctypes.BchTestnet.String():
-		return fmt.Sprintf("%s/%s/testnet", mclient.MercuryURL, network.Chain().String())
-	case btctypes.BtcLocalnet.String(), btctypes.ZecLocalnet.String(), btctypes.BchLocalnet.String():
-		return fmt.Sprintf("http://0.0.0.0:5000/%s/testnet", network.Chain().String())
-	default:
-		panic(types.ErrUnknownNetwork)
+	if network.IsLocal() {
+		return NetworkURL(LocalMercuryURL, network)
 	}
+	return NetworkURL(mclient.MercuryURL, network)
 }
 
-// NewClient returns a new Client of given network.
+// NetworkURL returns the URL of the network on the Mercury server with the given base URL. Localnet networks use the
+// testnet routes, and other networks use their own routes (e.g. regtest networks use the regtest routes).
+func NetworkURL(baseURL string, network btctypes.Network) string {
+	name := network.String()
+	switch network {
+	case btctypes.BtcLocalnet, btctypes.BchLocalnet, btctypes.LtcLocalnet, btctypes.DogeLocalnet:
+		name = btctypes.BtcTestnet.String()
+	}
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(baseURL, "/"), network.Chain().String(), name)
+}
+
+// NewClient returns a new Client of given network which uses the default Mercury server.
 func NewClient(logger logrus.FieldLogger, network btctypes.Network) Client {
-	host := MercuryURL(network)
+	return newClient(logger, network, MercuryURL(network))
+}
+
+// NewCustomClient returns a new Client of given network which uses the Mercury server with the given base URL (e.g.
+// "http://localhost:5000"). This is useful for local development chains.
+func NewCustomClient(logger logrus.FieldLogger, network btctypes.Network, baseURL string) Client {
+	return newClient(logger, network, NetworkURL(baseURL, network))
+}
+
+func newClient(logger logrus.FieldLogger, network btctypes.Network, host string) Client {
 	gasStation := NewBtcGasStation(logger, 30*time.Minute)
 	baseClient := &client{
 		client:     btcrpcclient.NewRPCClient(host, "", "", 5*time.Second),
@@ -80,6 +124,10 @@ func NewClient(logger logrus.FieldLogger, network btctypes.Network) Client {
 		return &ZecClient{baseClient}
 	case types.BitcoinCash:
 		return &BchClient{baseClient}
+	case types.Litecoin:
+		return &LtcClient{baseClient}
+	case types.Dogecoin:
+		return &DogeClient{baseClient}
 	default:
 		panic(types.ErrUnknownChain)
 	}
@@ -107,7 +155,49 @@ func (c *client) UTXO(ctx context.Context, op btctypes.OutPoint) (btctypes.UTXO,
 		}
 		return nil, fmt.Errorf("cannot get tx output from btc client: %v", err)
 	}
+	return newUTXO(op, tx, txOut)
+}
+
+// UTXOs returns the UTXOs for the given outpoints using a single request. The UTXO or the error of each outpoint is
+// returned at the same index, and an error is returned if the request fails.
+func (c *client) UTXOs(ctx context.Context, ops []btctypes.OutPoint) ([]btctypes.UTXO, []error, error) {
+	utxos := make([]btctypes.UTXO, len(ops))
+	errs := make([]error, len(ops))
+
+	// Only look up the outpoints with valid transaction hashes.
+	valid := make([]btctypes.OutPoint, 0, len(ops))
+	indices := make([]int, 0, len(ops))
+	for i, op := range ops {
+		if len(op.TxHash()) != 64 {
+			errs[i] = NewErrInvalidTxHash(fmt.Errorf(string(op.TxHash())))
+			continue
+		}
+		valid = append(valid, op)
+		indices = append(indices, i)
+	}
+
+	results, err := c.client.GetTxOuts(ctx, valid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot get tx outputs from btc client: %v", err)
+	}
+	for j, result := range results {
+		i := indices[j]
+		switch {
+		case result.TxErr != nil:
+			errs[i] = NewErrTxHashNotFound(result.TxErr)
+		case result.TxOutErr == rpcclient.ErrNullResult:
+			errs[i] = NewErrUTXOSpent(result.TxOutErr)
+		case result.TxOutErr != nil:
+			errs[i] = fmt.Errorf("cannot get tx output from btc client: %v", result.TxOutErr)
+		default:
+			utxos[i], errs[i] = newUTXO(ops[i], result.Tx, result.TxOut)
+		}
+	}
+	return utxos, errs, nil
+}
 
+// newUTXO returns the UTXO of the outpoint from the transaction and the output returned by the btc client.
+func newUTXO(op btctypes.OutPoint, tx btcrpcclient.RawTransactionVerbose, txOut btcrpcclient.GetTxOutResponse) (btctypes.UTXO, error) {
 	amount, err := btcutil.NewAmount(txOut.Value)
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse amount received from btc client: %v", err)
@@ -168,6 +258,16 @@ func (c *client) Confirmations(ctx context.Context, txHash types.TxHash) (uint64
 	return uint64(tx.Confirmations), nil
 }
 
+// dust returns the dust limit of the chain.
+func (c *client) dust() btctypes.Amount {
+	return DustLimit(c.network)
+}
+
+// BuildUnsignedTx builds a transaction spending the UTXOs to the recipients which pays the given absolute fee. The
+// difference between the UTXOs and the amount paid to the recipients and the fee is refunded, unless it is below the
+// dust limit, in which case it is added to the fee.
+//
+// Deprecated: use BuildUnsignedTxAtFeeRate instead, since the size of the transaction is not known in advance.
 func (c *client) BuildUnsignedTx(utxos btctypes.UTXOs, recipients btctypes.Recipients, refundTo btctypes.Address, gas btctypes.Amount) (btctypes.BtcTx, error) {
 	// Pre-condition checks.
 	if gas < Dust {
@@ -175,33 +275,86 @@ func (c *client) BuildUnsignedTx(utxos btctypes.UTXOs, recipients btctypes.Recip
 	}
 
 	amountFromUTXOs := utxos.Sum()
-	if amountFromUTXOs < Dust {
-		return nil, fmt.Errorf("pre-condition violation: amount=%v from utxos is less than dust=%v", amountFromUTXOs, Dust)
-	}
-
-	// Add an output for each recipient and sum the total amount that is being transferred to recipients.
-	amountToRecipients := btctypes.Amount(0)
-	for _, recipient := range recipients {
-		amountToRecipients += recipient.Amount
+	if amountFromUTXOs < c.dust() {
+		return nil, fmt.Errorf("pre-condition violation: amount=%v from utxos is less than dust=%v", amountFromUTXOs, c.dust())
 	}
 
 	// Check that we are not transferring more to recipients than available in the UTXOs (accounting for gas).
+	amountToRecipients := recipients.Sum()
 	amountToRefund := amountFromUTXOs - amountToRecipients - gas
 	if amountToRefund < 0 {
 		return nil, fmt.Errorf("insufficient balance: expected %v, got %v", amountToRecipients+gas, amountFromUTXOs)
 	}
 
 	// Add an output to refund the difference between the amount being transferred to recipients and the total amount
-	// from the UTXOs, if it is greater than the Dust amount.
-	if amountToRefund > Dust {
-		recipients = append(recipients, btctypes.NewRecipient(refundTo, amountToRefund))
+	// from the UTXOs, if it is greater than the dust amount.
+	if amountToRefund > c.dust() {
+		recipients = append(recipients[:len(recipients):len(recipients)], btctypes.NewRecipient(refundTo, amountToRefund))
 	}
 
 	// Get the signature hashes we need to sign.
 	return btctypes.NewUnsignedTx(c.network, utxos, recipients)
 }
 
-// SubmitSignedTx submits the signed transaction and returns the transaction hash in hex.
+// BuildUnsignedTxAtFeeRate builds a transaction spending the UTXOs to the recipients. The fee is the estimated virtual
+// size of the transaction multiplied by the fee rate. The difference between the UTXOs and the amount paid to the
+// recipients and the fee is refunded, unless it is below the dust limit, in which case it is added to the fee.
+func (c *client) BuildUnsignedTxAtFeeRate(utxos btctypes.UTXOs, recipients btctypes.Recipients, refundTo btctypes.Address, feeRate btctypes.FeeRate) (btctypes.BtcTx, error) {
+	return c.BuildUnsignedTxWithOptions(utxos, recipients, refundTo, feeRate, btctypes.DefaultTxOptions())
+}
+
+// BuildUnsignedTxWithOptions builds a transaction in the same way as `BuildUnsignedTxAtFeeRate`, using the options
+// (e.g. to signal that the transaction can be replaced).
+func (c *client) BuildUnsignedTxWithOptions(utxos btctypes.UTXOs, recipients btctypes.Recipients, refundTo btctypes.Address, feeRate btctypes.FeeRate, opts btctypes.TxOptions) (btctypes.BtcTx, error) {
+	// Pre-condition checks.
+	if feeRate < MinFeeRate {
+		return nil, fmt.Errorf("pre-condition violation: fee rate = %v is too low", feeRate)
+	}
+
+	amountFromUTXOs := utxos.Sum()
+	if amountFromUTXOs < c.dust() {
+		return nil, fmt.Errorf("pre-condition violation: amount=%v from utxos is less than dust=%v", amountFromUTXOs, c.dust())
+	}
+
+	// Sum the total amount that is being transferred to recipients.
+	amountToRecipients := recipients.Sum()
+
+	// Add an output to refund the difference between the amount being transferred to recipients (accounting for the
+	// fee of the refund output) and the total amount from the UTXOs, if it is greater than the dust amount.
+	withRefund := append(recipients[:len(recipients):len(recipients)], btctypes.NewRecipient(refundTo, 0))
+	fee, err := c.EstimateFee(utxos, withRefund, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	if amountToRefund := amountFromUTXOs - amountToRecipients - fee; amountToRefund > c.dust() {
+		withRefund[len(recipients)].Amount = amountToRefund
+		return btctypes.NewUnsignedTxWithOptions(c.network, utxos, withRefund, opts)
+	}
+
+	// Check that we are not transferring more to recipients than available in the UTXOs (accounting for the fee).
+	fee, err = c.EstimateFee(utxos, recipients, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	if amountFromUTXOs < amountToRecipients+fee {
+		return nil, fmt.Errorf("insufficient balance: expected %v, got %v", amountToRecipients+fee, amountFromUTXOs)
+	}
+
+	// Get the signature hashes we need to sign.
+	return btctypes.NewUnsignedTxWithOptions(c.network, utxos, recipients, opts)
+}
+
+// EstimateFee returns the fee of a transaction spending the UTXOs to the recipients at the fee rate.
+func (c *client) EstimateFee(utxos btctypes.UTXOs, recipients btctypes.Recipients, feeRate btctypes.FeeRate) (btctypes.Amount, error) {
+	vsize, err := btctypes.EstimateVSize(c.network, utxos, recipients)
+	if err != nil {
+		return 0, fmt.Errorf("cannot estimate size of tx: %v", err)
+	}
+	return feeRate.Fee(vsize), nil
+}
+
+// SubmitSignedTx submits the signed transaction and returns the transaction hash in hex. Errors returned by the node
+// can be matched using `errors.Is` with the sentinel errors of the rpcclient package (e.g. `rpcclient.ErrAlreadyInChain`).
 func (c *client) SubmitSignedTx(ctx context.Context, stx btctypes.BtcTx) (types.TxHash, error) {
 	// Pre-condition checks
 	if !stx.IsSigned() {
@@ -213,19 +366,23 @@ func (c *client) SubmitSignedTx(ctx context.Context, stx btctypes.BtcTx) (types.
 
 	txHash, err := c.client.SendRawTransaction(ctx, stx)
 	if err != nil {
-		return "", fmt.Errorf("cannot send raw transaction using btc client: %v", err)
+		return "", fmt.Errorf("cannot send raw transaction using btc client: %w", err)
 	}
 	return types.TxHash(txHash), nil
 }
 
-// EstimateTxSize estimates the tx size depending on number of utxos used and recipients. DEPRICATED use
-// btctypes.EstimateTxSize() instead.
+// EstimateTxSize estimates the tx size depending on number of utxos used and recipients.
+//
+// Deprecated: use EstimateFee, or btctypes.EstimateVSize, instead.
 func (c *client) EstimateTxSize(numUTXOs, numRecipients int) int {
 	return 146*numUTXOs + 33*numRecipients + 10
 }
 
 func (c *client) VerifyTx(tx btctypes.BtcTx) error {
-	if c.network.Chain() != types.Bitcoin {
+	// Litecoin and Dogecoin use the same scripts and signature hashes as Bitcoin.
+	switch c.network.Chain() {
+	case types.Bitcoin, types.Litecoin, types.Dogecoin:
+	default:
 		return nil
 	}
 
@@ -251,6 +408,11 @@ func (c *client) VerifyTx(tx btctypes.BtcTx) error {
 }
 
 func (c *client) SuggestGasPrice(ctx context.Context, speed types.TxSpeed, txSizeInBytes int) btctypes.Amount {
+	// The gas station only returns Bitcoin fees.
+	if feeRate, ok := defaultFeeRates[c.network.Chain()]; ok {
+		return feeRate.Fee(txSizeInBytes)
+	}
+
 	gasStationPrice, err := c.gasStation.GasRequired(ctx, speed, txSizeInBytes)
 	if err == nil {
 		return gasStationPrice
@@ -260,6 +422,27 @@ func (c *client) SuggestGasPrice(ctx context.Context, speed types.TxSpeed, txSiz
 	return 10000 * btctypes.SAT
 }
 
+// SuggestFeeRate returns the fee rate (in the smallest unit per virtual byte) for transactions to be confirmed at the
+// given speed. It is never below `MinFeeRate`.
+func (c *client) SuggestFeeRate(ctx context.Context, speed types.TxSpeed) btctypes.FeeRate {
+	// The gas station only returns Bitcoin fees.
+	if feeRate, ok := defaultFeeRates[c.network.Chain()]; ok {
+		return feeRate
+	}
+
+	fee, err := c.gasStation.GasRequired(ctx, speed, 1)
+	if err != nil {
+		c.logger.Errorf("error getting btc gas information: %v", err)
+		c.logger.Infof("using %v sats per byte as fee rate", fallbackFeeRate)
+		return fallbackFeeRate
+	}
+	feeRate := btctypes.FeeRate(fee)
+	if feeRate < MinFeeRate {
+		return MinFeeRate
+	}
+	return feeRate
+}
+
 func (c *client) SerializePublicKey(pubkey ecdsa.PublicKey) []byte {
 	return btctypes.SerializePublicKey(pubkey)
 }
@@ -325,3 +508,10 @@ func (c *BtcClient) SegWitAddressFromPubKey(pubkey ecdsa.PublicKey) (btctypes.Ad
 func (c *BtcClient) SegWitAddressFromScript(script []byte) (btctypes.Address, error) {
 	return btctypes.SegWitAddressFromScript(script, c.Network())
 }
+
+func (c *LtcClient) SegWitAddressFromPubKey(pubkey ecdsa.PublicKey) (btctypes.Address, error) {
+	return btctypes.SegWitAddressFromPubKey(pubkey, c.Network())
+}
+func (c *LtcClient) SegWitAddressFromScript(script []byte) (btctypes.Address, error) {
+	return btctypes.SegWitAddressFromScript(script, c.Network())
+}
@@ -1,6 +1,7 @@
 package btcclient_test
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
@@ -8,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/btcsuite/btcd/wire"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/renproject/mercury/sdk/client/btcclient"
@@ -31,6 +33,86 @@ var _ = Describe("btc client", func() {
 		return wallet
 	}
 
+	Context("when getting the url of a network", func() {
+		It("should use the route of the network", func() {
+			Expect(MercuryURL(btctypes.BtcRegtest)).To(Equal(LocalMercuryURL + "/btc/regtest"))
+			Expect(MercuryURL(btctypes.BtcLocalnet)).To(Equal(LocalMercuryURL + "/btc/testnet"))
+			Expect(MercuryURL(btctypes.BtcSignet)).To(HaveSuffix("/btc/signet"))
+			Expect(NetworkURL("http://localhost:18000/", btctypes.BchRegtest)).To(Equal("http://localhost:18000/bch/regtest"))
+			Expect(NetworkURL("http://localhost:18000", btctypes.ZecRegnet)).To(Equal("http://localhost:18000/zec/regtest"))
+			Expect(MercuryURL(btctypes.ZecRegnet)).To(Equal(LocalMercuryURL + "/zec/regtest"))
+			Expect(MercuryURL(btctypes.LtcLocalnet)).To(Equal(LocalMercuryURL + "/ltc/testnet"))
+			Expect(MercuryURL(btctypes.DogeLocalnet)).To(Equal(LocalMercuryURL + "/doge/testnet"))
+			Expect(MercuryURL(btctypes.LtcTestnet)).NotTo(HavePrefix(LocalMercuryURL))
+			Expect(MercuryURL(btctypes.ZecTestnet)).NotTo(HavePrefix(LocalMercuryURL))
+		})
+	})
+
+	Context("when building a transaction at a fee rate", func() {
+		It("should pay the estimated fee and refund the change above the dust limit", func() {
+			client := NewCustomClient(logger, btctypes.BtcRegtest, LocalMercuryURL)
+			from, err := testutil.RandomAddress(client.Network())
+			Expect(err).NotTo(HaveOccurred())
+			to, err := testutil.RandomSegWitAddress(client.Network())
+			Expect(err).NotTo(HaveOccurred())
+			scriptPubKey, err := client.PayToAddrScript(from)
+			Expect(err).NotTo(HaveOccurred())
+			utxos := btctypes.UTXOs{
+				btctypes.NewUTXO(btctypes.NewOutPoint(types.TxHash(fmt.Sprintf("%064x", 1)), 0), 40000, scriptPubKey, 1, nil),
+				btctypes.NewUTXO(btctypes.NewOutPoint(types.TxHash(fmt.Sprintf("%064x", 2)), 1), 60000, scriptPubKey, 1, nil),
+			}
+
+			// outputs returns the amounts of the outputs of the transaction.
+			outputs := func(tx btctypes.BtcTx) []int64 {
+				data, err := tx.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				msgTx := new(wire.MsgTx)
+				Expect(msgTx.Deserialize(bytes.NewReader(data))).To(Succeed())
+				amounts := make([]int64, len(msgTx.TxOut))
+				for i, txOut := range msgTx.TxOut {
+					amounts[i] = txOut.Value
+				}
+				return amounts
+			}
+
+			recipients := btctypes.Recipients{btctypes.NewRecipient(to, 50000)}
+			fee, err := client.EstimateFee(utxos, append(recipients, btctypes.NewRecipient(from, 0)), 5)
+			Expect(err).NotTo(HaveOccurred())
+			tx, err := client.BuildUnsignedTxAtFeeRate(utxos, recipients, from, 5)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outputs(tx)).To(Equal([]int64{50000, int64(50000 - fee)}))
+
+			// Change below the dust limit is paid to the miners.
+			fee, err = client.EstimateFee(utxos, recipients, 5)
+			Expect(err).NotTo(HaveOccurred())
+			recipients = btctypes.Recipients{btctypes.NewRecipient(to, 100000-fee-Dust)}
+			tx, err = client.BuildUnsignedTxAtFeeRate(utxos, recipients, from, 5)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outputs(tx)).To(Equal([]int64{int64(100000 - fee - Dust)}))
+
+			recipients = btctypes.Recipients{btctypes.NewRecipient(to, 100000-fee+1)}
+			_, err = client.BuildUnsignedTxAtFeeRate(utxos, recipients, from, 5)
+			Expect(err).To(HaveOccurred())
+			_, err = client.BuildUnsignedTxAtFeeRate(utxos, recipients, from, 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when building a transaction with an absolute fee", func() {
+		It("should pay the fee and refund the change", func() {
+			client := NewCustomClient(logger, btctypes.BtcRegtest, LocalMercuryURL)
+			from, err := testutil.RandomAddress(client.Network())
+			Expect(err).NotTo(HaveOccurred())
+			scriptPubKey, err := client.PayToAddrScript(from)
+			Expect(err).NotTo(HaveOccurred())
+			utxos := btctypes.UTXOs{btctypes.NewUTXO(btctypes.NewOutPoint(types.TxHash(fmt.Sprintf("%064x", 1)), 0), 40000, scriptPubKey, 1, nil)}
+
+			tx, err := client.BuildUnsignedTx(utxos, btctypes.Recipients{btctypes.NewRecipient(from, 20000)}, from, 10000)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tx.Recipients().Sum()).To(Equal(btctypes.Amount(30000)))
+		})
+	})
+
 	testCases := []struct {
 		Network btctypes.Network
 
@@ -187,6 +269,27 @@ var _ = Describe("btc client", func() {
 				_, ok := err.(ErrTxHashNotFound)
 				Expect(ok).To(BeTrue())
 			})
+
+			It("should return the UTXO or the error of each outpoint in a single request", func() {
+				client := NewClient(logger, testCase.Network)
+
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+
+				utxos, errs, err := client.UTXOs(ctx, []btctypes.OutPoint{
+					testCase.UnspentOutPoint,
+					testCase.SpentOutPoint,
+					testCase.InvalidTxHashOutPoint,
+					testCase.NonExistentTxHashOutPoint,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(errs[0]).NotTo(HaveOccurred())
+				Expect(utxos[0].TxHash()).To(Equal(testCase.UnspentOutPoint.TxHash()))
+				Expect(utxos[0].Amount()).To(Equal(testCase.Amount))
+				Expect(errs[1]).To(BeAssignableToTypeOf(ErrUTXOSpent{}))
+				Expect(errs[2]).To(BeAssignableToTypeOf(ErrInvalidTxHash{}))
+				Expect(errs[3]).To(BeAssignableToTypeOf(ErrTxHashNotFound{}))
+			})
 		})
 
 		Context(fmt.Sprintf("when building a utx on %s %s", testCase.Network.Chain(), testCase.Network), func() {
@@ -11,12 +11,20 @@ import (
 type Client interface {
 	Network() btctypes.Network
 	UTXO(ctx context.Context, op btctypes.OutPoint) (btctypes.UTXO, error)
+	UTXOs(ctx context.Context, ops []btctypes.OutPoint) ([]btctypes.UTXO, []error, error)
 	UTXOsFromAddress(ctx context.Context, address btctypes.Address) (btctypes.UTXOs, error)
 	Confirmations(ctx context.Context, txHash types.TxHash) (uint64, error)
-	BuildUnsignedTx(utxos btctypes.UTXOs, recipients btctypes.Recipients, refundTo btctypes.Address, gas btctypes.Amount) (btctypes.BtcTx, error)
+	BuildUnsignedTx(utxos btctypes.UTXOs, recipients btctypes.Recipients, refundTo btctypes.Address, gas btctypes.Amount) (btctypes.BtcTx, error) // Deprecated
+	BuildUnsignedTxAtFeeRate(utxos btctypes.UTXOs, recipients btctypes.Recipients, refundTo btctypes.Address, feeRate btctypes.FeeRate) (btctypes.BtcTx, error)
+	BuildUnsignedTxWithOptions(utxos btctypes.UTXOs, recipients btctypes.Recipients, refundTo btctypes.Address, feeRate btctypes.FeeRate, opts btctypes.TxOptions) (btctypes.BtcTx, error)
+	BumpFee(tx btctypes.BtcTx, refundTo btctypes.Address, feeRate btctypes.FeeRate, key *ecdsa.PrivateKey) (btctypes.BtcTx, error)
+	PackageOf(ctx context.Context, txHash types.TxHash) (Package, error)
+	CPFP(ctx context.Context, utxo btctypes.UTXO, to btctypes.Address, feeRate btctypes.FeeRate, key *ecdsa.PrivateKey) (btctypes.BtcTx, error)
+	EstimateFee(utxos btctypes.UTXOs, recipients btctypes.Recipients, feeRate btctypes.FeeRate) (btctypes.Amount, error)
 	SubmitSignedTx(ctx context.Context, stx btctypes.BtcTx) (types.TxHash, error)
-	EstimateTxSize(numUTXOs, numRecipients int) int // Depricated
+	EstimateTxSize(numUTXOs, numRecipients int) int // Deprecated
 	SuggestGasPrice(ctx context.Context, speed types.TxSpeed, txSizeInBytes int) btctypes.Amount
+	SuggestFeeRate(ctx context.Context, speed types.TxSpeed) btctypes.FeeRate
 	SerializePublicKey(pubkey ecdsa.PublicKey) []byte
 	AddressFromBase58(addr string) (btctypes.Address, error)
 	AddressFromPubKey(pubkey ecdsa.PublicKey) (btctypes.Address, error)
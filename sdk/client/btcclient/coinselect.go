@@ -0,0 +1,287 @@
+package btcclient
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+var (
+	// ErrInsufficientFunds is returned when the UTXOs cannot pay for the recipients and the fee.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrNoChangelessSolution is returned by the branch-and-bound strategy when no subset of the UTXOs pays for the
+	// recipients and the fee without a change output.
+	ErrNoChangelessSolution = errors.New("no changeless solution")
+)
+
+// CoinSelectionStrategy is a strategy for choosing the UTXOs spent by a transaction.
+type CoinSelectionStrategy int
+
+const (
+	// BranchAndBound searches for a subset of the UTXOs which pays for the recipients and the fee without a change
+	// output, wasting as little as possible. It returns `ErrNoChangelessSolution` if there is no such subset.
+	BranchAndBound CoinSelectionStrategy = iota + 1
+	// LargestFirst spends the largest UTXOs first, which minimises the number of inputs.
+	LargestFirst
+	// SmallestFirst spends the smallest UTXOs first, which consolidates UTXOs.
+	SmallestFirst
+	// Random spends the UTXOs in a random order. UTXOs with the same script pubkey are spent together, so that the
+	// transaction does not link an address to another one while leaving some of its outputs unspent.
+	Random
+)
+
+// String implements the `fmt.Stringer` interface.
+func (strategy CoinSelectionStrategy) String() string {
+	switch strategy {
+	case BranchAndBound:
+		return "branch and bound"
+	case LargestFirst:
+		return "largest first"
+	case SmallestFirst:
+		return "smallest first"
+	case Random:
+		return "random"
+	default:
+		return fmt.Sprintf("strategy(%d)", int(strategy))
+	}
+}
+
+// maxBranchAndBoundTries is the maximum number of branches explored by the branch-and-bound strategy (the same limit
+// is used by Bitcoin Core).
+const maxBranchAndBoundTries = 100000
+
+// CoinSelectionOptions configure the selection of UTXOs.
+type CoinSelectionOptions struct {
+	// Strategy is the strategy used to choose the UTXOs.
+	Strategy CoinSelectionStrategy
+	// FeeRate is the fee rate of the transaction.
+	FeeRate btctypes.FeeRate
+	// MinConfirmations is the minimum number of confirmations of the UTXOs which can be spent.
+	MinConfirmations uint64
+	// Rand is the source of randomness of the random strategy. A source seeded with the current time is used if it is
+	// nil.
+	Rand *rand.Rand
+}
+
+// DefaultCoinSelectionOptions returns options which use the branch-and-bound strategy at 10 satoshis per virtual byte, and
+// spend confirmed UTXOs.
+func DefaultCoinSelectionOptions() CoinSelectionOptions {
+	return CoinSelectionOptions{
+		Strategy:         BranchAndBound,
+		FeeRate:          10,
+		MinConfirmations: 1,
+		Rand:             nil,
+	}
+}
+
+// CoinSelection is the result of selecting UTXOs for a transaction.
+type CoinSelection struct {
+	// UTXOs are the selected UTXOs.
+	UTXOs btctypes.UTXOs
+	// Fee is the fee of the transaction, including any change which is too small to be spent.
+	Fee btctypes.Amount
+	// Change is the amount which must be refunded to the sender, or zero if the transaction has no change output.
+	Change btctypes.Amount
+}
+
+// DustLimit returns the dust limit of the network.
+func DustLimit(network btctypes.Network) btctypes.Amount {
+	if network.Chain() == types.Dogecoin {
+		return DogeDust
+	}
+	return Dust
+}
+
+// SelectCoins selects the UTXOs which pay for the recipients and the fee at the fee rate of the options. Only UTXOs
+// with at least `MinConfirmations` confirmations are spent, and UTXOs which cost more to spend than their amount are
+// ignored. Change is refunded to the refund address, unless it is below the dust limit of the network, in which case
+// it is added to the fee.
+func SelectCoins(network btctypes.Network, utxos btctypes.UTXOs, recipients btctypes.Recipients, refundTo btctypes.Address, opts CoinSelectionOptions) (CoinSelection, error) {
+	if opts.FeeRate < MinFeeRate {
+		return CoinSelection{}, fmt.Errorf("pre-condition violation: fee rate = %v is too low", opts.FeeRate)
+	}
+	fee := func(weight int) btctypes.Amount {
+		return opts.FeeRate.Fee(btctypes.VSize(weight))
+	}
+
+	candidates := make([]candidate, 0, len(utxos))
+	available := btctypes.Amount(0)
+	segWit := false
+	for _, utxo := range utxos.Filter(opts.MinConfirmations) {
+		value := utxo.Amount() - fee(btctypes.InputWeight(network, utxo))
+		if value <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{utxo: utxo, value: value})
+		available += value
+		segWit = segWit || btctypes.IsWitnessInput(network, utxo)
+	}
+
+	// The target is the amount paid to the recipients and the fee of the transaction without any inputs.
+	amount := btctypes.Amount(0)
+	weight := btctypes.OverheadWeight(network, len(candidates), len(recipients)+1, segWit)
+	for _, recipient := range recipients {
+		scriptPubKey, err := btctypes.PayToAddrScript(recipient.Address, network)
+		if err != nil {
+			return CoinSelection{}, fmt.Errorf("cannot get script pubkey of recipient: %v", err)
+		}
+		amount += recipient.Amount
+		weight += btctypes.OutputWeight(scriptPubKey)
+	}
+	target := amount + fee(weight)
+	if available < target {
+		return CoinSelection{}, fmt.Errorf("%w: expected %v, got %v", ErrInsufficientFunds, target, available)
+	}
+
+	// The change output costs the fee of the output, and the fee of spending it later on.
+	changeScriptPubKey, err := btctypes.PayToAddrScript(refundTo, network)
+	if err != nil {
+		return CoinSelection{}, fmt.Errorf("cannot get script pubkey of refund address: %v", err)
+	}
+	changeOutputFee := fee(btctypes.OutputWeight(changeScriptPubKey))
+	change := btctypes.NewUTXO(btctypes.NewOutPoint("", 0), 0, changeScriptPubKey, 0, nil)
+	changeCost := changeOutputFee + fee(btctypes.InputWeight(network, change))
+
+	var selected btctypes.UTXOs
+	var value btctypes.Amount
+	switch opts.Strategy {
+	case BranchAndBound:
+		var ok bool
+		if selected, ok = selectBranchAndBound(candidates, target, changeCost); !ok {
+			return CoinSelection{}, ErrNoChangelessSolution
+		}
+		return CoinSelection{UTXOs: selected, Fee: selected.Sum() - amount}, nil
+	case LargestFirst:
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].value > candidates[j].value })
+		selected, value = selectInOrder(ungrouped(candidates), target)
+	case SmallestFirst:
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].value < candidates[j].value })
+		selected, value = selectInOrder(ungrouped(candidates), target)
+	case Random:
+		r := opts.Rand
+		if r == nil {
+			r = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+		selected, value = selectInOrder(shuffledGroups(candidates, r), target)
+	default:
+		return CoinSelection{}, fmt.Errorf("unknown coin selection strategy: %v", opts.Strategy)
+	}
+
+	// Change which does not pay for its own output, or is not above the dust limit, is added to the fee.
+	selection := CoinSelection{UTXOs: selected}
+	if change := value - target - changeOutputFee; change > DustLimit(network) {
+		selection.Change = change
+	}
+	selection.Fee = selected.Sum() - amount - selection.Change
+	return selection, nil
+}
+
+// candidate is a UTXO which can be spent, and its effective value (its amount less the fee of spending it).
+type candidate struct {
+	utxo  btctypes.UTXO
+	value btctypes.Amount
+}
+
+// selectBranchAndBound explores the subsets of the candidates (from largest to smallest) whose effective value is at
+// least the target, but no more than the cost of a change output above it. It returns the subset which wastes the
+// least, and false if there is no such subset.
+func selectBranchAndBound(candidates []candidate, target, changeCost btctypes.Amount) (btctypes.UTXOs, bool) {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].value > candidates[j].value })
+
+	// remaining[i] is the effective value of the candidates from i onwards.
+	remaining := make([]btctypes.Amount, len(candidates)+1)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + candidates[i].value
+	}
+
+	var best []bool
+	bestWaste := btctypes.Amount(-1)
+	selected := make([]bool, len(candidates))
+	tries := 0
+
+	var search func(i int, value btctypes.Amount)
+	search = func(i int, value btctypes.Amount) {
+		tries++
+		if tries > maxBranchAndBoundTries || value > target+changeCost || value+remaining[i] < target {
+			return
+		}
+		if value >= target {
+			if waste := value - target; bestWaste < 0 || waste < bestWaste {
+				best = append(best[:0], selected...)
+				bestWaste = waste
+			}
+			return
+		}
+
+		selected[i] = true
+		search(i+1, value+candidates[i].value)
+		selected[i] = false
+		// Omitting a candidate after omitting an equal one explores the same subsets again.
+		if i > 0 && !selected[i-1] && candidates[i-1].value == candidates[i].value {
+			return
+		}
+		search(i+1, value)
+	}
+	search(0, 0)
+
+	if best == nil {
+		return nil, false
+	}
+	utxos := btctypes.UTXOs{}
+	for i, ok := range best {
+		if ok {
+			utxos = append(utxos, candidates[i].utxo)
+		}
+	}
+	return utxos, true
+}
+
+// selectInOrder selects groups of candidates in order until their effective value reaches the target, and returns the
+// selected UTXOs and their effective value.
+func selectInOrder(groups [][]candidate, target btctypes.Amount) (btctypes.UTXOs, btctypes.Amount) {
+	utxos := btctypes.UTXOs{}
+	value := btctypes.Amount(0)
+	for _, group := range groups {
+		if value >= target {
+			break
+		}
+		for _, c := range group {
+			utxos = append(utxos, c.utxo)
+			value += c.value
+		}
+	}
+	return utxos, value
+}
+
+// ungrouped returns a group for each candidate.
+func ungrouped(candidates []candidate) [][]candidate {
+	groups := make([][]candidate, len(candidates))
+	for i, c := range candidates {
+		groups[i] = []candidate{c}
+	}
+	return groups
+}
+
+// shuffledGroups groups the candidates by script pubkey, and returns the groups in a random order.
+func shuffledGroups(candidates []candidate, r *rand.Rand) [][]candidate {
+	groups := [][]candidate{}
+	indices := map[string]int{}
+	for _, c := range candidates {
+		key := string(c.utxo.ScriptPubKey())
+		i, ok := indices[key]
+		if !ok {
+			i = len(groups)
+			indices[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], c)
+	}
+	r.Shuffle(len(groups), func(i, j int) { groups[i], groups[j] = groups[j], groups[i] })
+	return groups
+}
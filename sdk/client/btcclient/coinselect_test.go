@@ -0,0 +1,136 @@
+package btcclient_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/sdk/client/btcclient"
+
+	"github.com/renproject/mercury/testutil"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+var _ = Describe("coin selection", func() {
+	// At 1 satoshi per byte, a P2PKH input costs 149 satoshis, a P2PKH output costs 34 satoshis, and a transaction
+	// paying 10000 satoshis to a single recipient costs 44 satoshis before any inputs are added.
+	network := btctypes.BtcRegtest
+	var address btctypes.Address
+	var recipients btctypes.Recipients
+
+	BeforeEach(func() {
+		var err error
+		address, err = testutil.RandomAddress(network)
+		Expect(err).NotTo(HaveOccurred())
+		recipients = btctypes.Recipients{btctypes.NewRecipient(address, 10000)}
+	})
+
+	// newUTXOs returns confirmed UTXOs of the amounts, which are spent by the same script pubkey.
+	newUTXOs := func(amounts ...btctypes.Amount) btctypes.UTXOs {
+		utxos := make(btctypes.UTXOs, len(amounts))
+		for i, amount := range amounts {
+			utxos[i] = btctypes.NewUTXO(btctypes.NewOutPoint(types.TxHash(fmt.Sprintf("%064x", i)), 0), amount, []byte{0x51}, 1, nil)
+		}
+		return utxos
+	}
+
+	options := func(strategy CoinSelectionStrategy) CoinSelectionOptions {
+		opts := DefaultCoinSelectionOptions()
+		opts.Strategy = strategy
+		opts.FeeRate = 1
+		return opts
+	}
+
+	Context("when using the branch-and-bound strategy", func() {
+		It("should select the UTXOs which pay for the recipients without change", func() {
+			utxos := newUTXOs(5149, 5193, 20149, 3149)
+			selection, err := SelectCoins(network, utxos, recipients, address, options(BranchAndBound))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selection.UTXOs).To(ConsistOf(utxos[0], utxos[1]))
+			Expect(selection.Change).To(Equal(btctypes.Amount(0)))
+			Expect(selection.Fee).To(Equal(btctypes.Amount(342)))
+		})
+
+		It("should return an error if every solution needs change", func() {
+			_, err := SelectCoins(network, newUTXOs(20149, 3149), recipients, address, options(BranchAndBound))
+			Expect(err).To(Equal(ErrNoChangelessSolution))
+		})
+	})
+
+	Context("when using the largest-first strategy", func() {
+		It("should select the largest UTXO and refund the change", func() {
+			utxos := newUTXOs(5149, 5193, 20149, 3149)
+			selection, err := SelectCoins(network, utxos, recipients, address, options(LargestFirst))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selection.UTXOs).To(Equal(btctypes.UTXOs{utxos[2]}))
+			Expect(selection.Change).To(Equal(btctypes.Amount(9922)))
+			Expect(selection.Fee).To(Equal(btctypes.Amount(227)))
+		})
+
+		It("should add change below the dust limit to the fee", func() {
+			selection, err := SelectCoins(network, newUTXOs(10327), recipients, address, options(LargestFirst))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selection.Change).To(Equal(btctypes.Amount(0)))
+			Expect(selection.Fee).To(Equal(btctypes.Amount(327)))
+		})
+	})
+
+	Context("when using the smallest-first strategy", func() {
+		It("should select the smallest UTXOs which are worth spending", func() {
+			utxos := newUTXOs(5149, 5193, 20149, 3149, 100)
+			selection, err := SelectCoins(network, utxos, recipients, address, options(SmallestFirst))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selection.UTXOs).To(Equal(btctypes.UTXOs{utxos[3], utxos[0], utxos[1]}))
+			Expect(selection.Change).To(Equal(btctypes.Amount(2966)))
+			Expect(selection.Fee).To(Equal(btctypes.Amount(525)))
+		})
+	})
+
+	Context("when using the random strategy", func() {
+		It("should spend the UTXOs of a script pubkey together", func() {
+			utxos := btctypes.UTXOs{}
+			for i := 0; i < 6; i++ {
+				// The first two UTXOs have the same script pubkey.
+				scriptPubKey := []byte{byte(i)}
+				if i == 1 {
+					scriptPubKey = []byte{0}
+				}
+				utxos = append(utxos, btctypes.NewUTXO(btctypes.NewOutPoint(types.TxHash(fmt.Sprintf("%064x", i)), 0), 6149, scriptPubKey, 1, nil))
+			}
+
+			for seed := int64(0); seed < 20; seed++ {
+				opts := options(Random)
+				opts.Rand = rand.New(rand.NewSource(seed))
+				selection, err := SelectCoins(network, utxos, recipients, address, opts)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(selection.UTXOs.Sum() - selection.Fee - selection.Change).To(Equal(btctypes.Amount(10000)))
+
+				spent := 0
+				for _, utxo := range selection.UTXOs {
+					if utxo == utxos[0] || utxo == utxos[1] {
+						spent++
+					}
+				}
+				Expect(spent).To(Or(Equal(0), Equal(2)))
+
+				opts.Rand = rand.New(rand.NewSource(seed))
+				Expect(SelectCoins(network, utxos, recipients, address, opts)).To(Equal(selection))
+			}
+		})
+	})
+
+	Context("when the UTXOs are not enough", func() {
+		It("should only spend UTXOs with enough confirmations", func() {
+			utxos := append(newUTXOs(5149, 5193), btctypes.NewUTXO(btctypes.NewOutPoint(types.TxHash(fmt.Sprintf("%064x", 9)), 0), 100000, []byte{0x51}, 0, nil))
+			selection, err := SelectCoins(network, utxos, recipients, address, options(LargestFirst))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selection.UTXOs).To(ConsistOf(utxos[0], utxos[1]))
+
+			_, err = SelectCoins(network, newUTXOs(5149, 3149), recipients, address, options(LargestFirst))
+			Expect(errors.Is(err, ErrInsufficientFunds)).To(BeTrue())
+		})
+	})
+})
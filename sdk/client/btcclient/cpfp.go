@@ -0,0 +1,90 @@
+package btcclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+// ErrParentConfirmed is returned when building a child for a parent transaction which is not in the mempool.
+var ErrParentConfirmed = errors.New("parent transaction is not in the mempool")
+
+// Package is the fee and the virtual size of an unconfirmed transaction and its unconfirmed ancestors. Miners include
+// the transaction in a block based on the fee rate of the package.
+type Package struct {
+	Fee   btctypes.Amount
+	VSize int
+}
+
+// FeeRate returns the fee rate of the package, rounded down.
+func (p Package) FeeRate() btctypes.FeeRate {
+	if p.VSize == 0 {
+		return 0
+	}
+	return btctypes.FeeRate(p.Fee / btctypes.Amount(p.VSize))
+}
+
+// PackageOf returns the package of the unconfirmed transaction. `ErrParentConfirmed` is returned if the transaction is
+// not in the mempool.
+func (c *client) PackageOf(ctx context.Context, txHash types.TxHash) (Package, error) {
+	entry, err := c.client.GetMempoolEntry(ctx, txHash)
+	if err != nil {
+		if confirmations, confErr := c.Confirmations(ctx, txHash); confErr == nil && confirmations > 0 {
+			return Package{}, ErrParentConfirmed
+		}
+		return Package{}, fmt.Errorf("cannot get mempool entry from btc client: %v", err)
+	}
+	return Package{
+		Fee:   btctypes.AmountFromFloat64(entry.Fees.Ancestor),
+		VSize: int(entry.AncestorSize),
+	}, nil
+}
+
+// CPFP returns a transaction which spends the UTXO of an unconfirmed parent transaction to `to`, and is signed using
+// the key. The child pays enough fee for the package of the parent and the child to reach the fee rate, so that miners
+// are incentivised to include the parent (e.g. a deposit with a low fee from a sender we do not control). The UTXO can
+// be the output of `BtcTx.OutputUTXO` or `btcgateway.Gateway.UTXO`.
+//
+// The fee rate must be higher than the fee rate of the package of the parent, and the child always pays for its own
+// relay.
+func (c *client) CPFP(ctx context.Context, utxo btctypes.UTXO, to btctypes.Address, feeRate btctypes.FeeRate, key *ecdsa.PrivateKey) (btctypes.BtcTx, error) {
+	// Pre-condition checks.
+	if utxo.Confirmations() > 0 {
+		return nil, ErrParentConfirmed
+	}
+	parent, err := c.PackageOf(ctx, utxo.TxHash())
+	if err != nil {
+		return nil, err
+	}
+	if parent.FeeRate() >= feeRate {
+		return nil, fmt.Errorf("pre-condition violation: fee rate = %v is not higher than the fee rate of the package = %v", feeRate, parent.FeeRate())
+	}
+
+	utxos := btctypes.UTXOs{utxo}
+	recipients := btctypes.Recipients{btctypes.NewRecipient(to, 0)}
+	vsize, err := btctypes.EstimateVSize(c.network, utxos, recipients)
+	if err != nil {
+		return nil, fmt.Errorf("cannot estimate size of tx: %v", err)
+	}
+	fee := feeRate.Fee(parent.VSize+vsize) - parent.Fee
+	if minFee := MinFeeRate.Fee(vsize); fee < minFee {
+		fee = minFee
+	}
+	if utxo.Amount()-fee <= c.dust() {
+		return nil, fmt.Errorf("insufficient balance: expected %v, got %v", fee+c.dust(), utxo.Amount())
+	}
+	recipients[0].Amount = utxo.Amount() - fee
+
+	child, err := btctypes.NewUnsignedTx(c.network, utxos, recipients)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build child tx: %v", err)
+	}
+	if err := child.Sign(key); err != nil {
+		return nil, fmt.Errorf("cannot sign child tx: %v", err)
+	}
+	return child, nil
+}
@@ -0,0 +1,120 @@
+package btcclient_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/sdk/client/btcclient"
+
+	"github.com/renproject/mercury/sdk/gateway/btcgateway"
+	"github.com/renproject/mercury/testutil/btcsim"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("child-pays-for-parent", func() {
+	logger := logrus.StandardLogger()
+	ctx := context.Background()
+	network := btctypes.BtcRegtest
+
+	// setup returns a client of a simulated chain, and a key whose address has a confirmed UTXO of 100000 satoshis.
+	setup := func() (*btcsim.Chain, Client, func(), *ecdsa.PrivateKey, btctypes.Address) {
+		chain, err := btcsim.New(network)
+		Expect(err).NotTo(HaveOccurred())
+		server := btcsim.NewServer(chain)
+		client := NewCustomClient(logger, network, server.URL)
+
+		key, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		from, err := btctypes.AddressFromPubKey(key.PublicKey, network)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = chain.Fund(from, 100000)
+		Expect(err).NotTo(HaveOccurred())
+		chain.Mine(1)
+		return chain, client, server.Close, key, from
+	}
+
+	// deposit submits a transaction paying 50000 satoshis to the address at the minimum fee rate.
+	deposit := func(client Client, key *ecdsa.PrivateKey, from, to btctypes.Address) btctypes.BtcTx {
+		utxos, err := client.UTXOsFromAddress(ctx, from)
+		Expect(err).NotTo(HaveOccurred())
+		tx, err := client.BuildUnsignedTxAtFeeRate(utxos, btctypes.Recipients{btctypes.NewRecipient(to, 50000)}, from, MinFeeRate)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tx.Sign(key)).To(Succeed())
+		_, err = client.SubmitSignedTx(ctx, tx)
+		Expect(err).NotTo(HaveOccurred())
+		return tx
+	}
+
+	// expectPackageFeeRate expects the package of the child to pay at least the fee rate.
+	expectPackageFeeRate := func(chain *btcsim.Chain, child btctypes.BtcTx, feeRate btctypes.FeeRate) {
+		entry, err := chain.GetMempoolEntry(ctx, child.Hash())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.AncestorCount).To(Equal(int64(2)))
+		fee := btctypes.AmountFromFloat64(entry.Fees.Ancestor)
+		Expect(fee).To(BeNumerically(">=", feeRate.Fee(int(entry.AncestorSize))))
+		Expect(fee).To(BeNumerically("<=", feeRate.Fee(int(entry.AncestorSize+2))))
+	}
+
+	Context("when the parent pays to an address", func() {
+		It("should build a child which raises the fee rate of the package", func() {
+			chain, client, closeFn, key, from := setup()
+			defer closeFn()
+			toKey, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			to, err := btctypes.AddressFromPubKey(toKey.PublicKey, network)
+			Expect(err).NotTo(HaveOccurred())
+			parent := deposit(client, key, from, to)
+
+			pkg, err := client.PackageOf(ctx, parent.Hash())
+			Expect(err).NotTo(HaveOccurred())
+			entry, err := chain.GetMempoolEntry(ctx, parent.Hash())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pkg.VSize).To(Equal(int(entry.VSize)))
+			Expect(pkg.Fee).To(Equal(btctypes.AmountFromFloat64(entry.Fees.Base)))
+			Expect(pkg.FeeRate()).To(Equal(MinFeeRate))
+
+			// The fee rate must be higher than the fee rate of the package.
+			_, err = client.CPFP(ctx, parent.OutputUTXO(to), to, MinFeeRate, toKey)
+			Expect(err).To(HaveOccurred())
+
+			child, err := client.CPFP(ctx, parent.OutputUTXO(to), to, 20, toKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(child.IsSigned()).To(BeTrue())
+			Expect(child.UTXOs()).To(Equal(btctypes.UTXOs{parent.OutputUTXO(to)}))
+			_, err = client.SubmitSignedTx(ctx, child)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chain.Mempool()).To(Equal([]types.TxHash{parent.Hash(), child.Hash()}))
+			expectPackageFeeRate(chain, child, 20)
+
+			// Children cannot be built once the parent is confirmed.
+			chain.Mine(1)
+			_, err = client.CPFP(ctx, parent.OutputUTXO(to), to, 20, toKey)
+			Expect(err).To(Equal(ErrParentConfirmed))
+			_, err = client.PackageOf(ctx, parent.Hash())
+			Expect(err).To(Equal(ErrParentConfirmed))
+		})
+	})
+
+	Context("when the parent pays to a gateway", func() {
+		It("should build a child which spends the gateway UTXO", func() {
+			chain, client, closeFn, key, from := setup()
+			defer closeFn()
+			gateway := btcgateway.New(client, key.PublicKey, []byte("ghash"))
+			parent := deposit(client, key, from, gateway.Address())
+
+			utxo, err := gateway.UTXO(ctx, parent.OutputUTXO(gateway.Address()).OutPoint())
+			Expect(err).NotTo(HaveOccurred())
+			child, err := client.CPFP(ctx, utxo, gateway.Spender(), 20, key)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = client.SubmitSignedTx(ctx, child)
+			Expect(err).NotTo(HaveOccurred())
+			expectPackageFeeRate(chain, child, 20)
+		})
+	})
+})
@@ -9,6 +9,7 @@ import (
 	. "github.com/renproject/mercury/sdk/client/btcclient"
 
 	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
 
 	"github.com/sirupsen/logrus"
 )
@@ -39,4 +40,13 @@ var _ = Describe("bitcoin tx gas", func() {
 			Expect(standardGas >= slowGas).Should(BeTrue())
 		})
 	})
+
+	Context("when suggesting fee rates of chains which are not supported by the gas station", func() {
+		It("should return the default fee rate per byte", func() {
+			logger := logrus.StandardLogger()
+			ctx := context.Background()
+			Expect(NewCustomClient(logger, btctypes.LtcLocalnet, "").SuggestFeeRate(ctx, types.Fast)).To(Equal(btctypes.FeeRate(10)))
+			Expect(NewCustomClient(logger, btctypes.DogeLocalnet, "").SuggestFeeRate(ctx, types.Fast)).To(Equal(btctypes.FeeRate(1000)))
+		})
+	})
 })
@@ -0,0 +1,99 @@
+package btcclient
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+var (
+	// ErrNotReplaceable is returned when bumping the fee of a transaction which does not signal that it can be
+	// replaced.
+	ErrNotReplaceable = errors.New("transaction is not replaceable")
+
+	// ErrNoChange is returned when bumping the fee of a transaction whose last output does not refund any change.
+	ErrNoChange = errors.New("transaction has no change output")
+
+	// ErrAmbiguousChange is returned when bumping the fee of a transaction which only pays the refund address, as its
+	// output cannot be told apart from a payment.
+	ErrAmbiguousChange = errors.New("transaction has an ambiguous change output")
+)
+
+// BumpFee returns a transaction which replaces the given transaction (BIP125), and is signed using the key. The
+// replacement spends the same UTXOs to the same recipients at the new fee rate, and the additional fee is taken from
+// the change refunded to `refundTo`. The change output is the last output (see `BuildUnsignedTxAtFeeRate`), and it is removed
+// if the remaining change is below the dust limit.
+//
+// The fee rate must be higher than the fee rate of the given transaction, and the replacement always pays for its own
+// relay on top of the fee of the given transaction.
+func (c *client) BumpFee(tx btctypes.BtcTx, refundTo btctypes.Address, feeRate btctypes.FeeRate, key *ecdsa.PrivateKey) (btctypes.BtcTx, error) {
+	// Pre-condition checks.
+	if !tx.Replaceable() {
+		return nil, ErrNotReplaceable
+	}
+	utxos := tx.UTXOs()
+	recipients := tx.Recipients()
+	change := len(recipients) - 1
+	if change < 0 || recipients[change].Address.EncodeAddress() != refundTo.EncodeAddress() {
+		return nil, ErrNoChange
+	}
+	if change == 0 {
+		return nil, ErrAmbiguousChange
+	}
+
+	fee := utxos.Sum() - recipients.Sum()
+	vsize, err := btctypes.EstimateVSize(c.network, utxos, recipients)
+	if err != nil {
+		return nil, fmt.Errorf("cannot estimate size of tx: %v", err)
+	}
+	if feeRate.Fee(vsize) <= fee {
+		return nil, fmt.Errorf("pre-condition violation: fee rate = %v is not higher than the fee rate of the tx", feeRate)
+	}
+
+	replacement := append(btctypes.Recipients{}, recipients...)
+	available := fee + recipients[change].Amount
+	newFee, err := c.replacementFee(utxos, replacement, fee, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	if amountToRefund := available - newFee; amountToRefund > c.dust() {
+		replacement[change].Amount = amountToRefund
+	} else {
+		replacement = replacement[:change]
+		newFee, err = c.replacementFee(utxos, replacement, fee, feeRate)
+		if err != nil {
+			return nil, err
+		}
+		if available < newFee {
+			return nil, fmt.Errorf("insufficient change: expected %v, got %v", newFee-fee, available-fee)
+		}
+	}
+
+	bumped, err := btctypes.NewUnsignedTxWithOptions(c.network, utxos, replacement, btctypes.TxOptions{RBF: true})
+	if err != nil {
+		return nil, fmt.Errorf("cannot build replacement tx: %v", err)
+	}
+	if err := bumped.Sign(key); err != nil {
+		return nil, fmt.Errorf("cannot sign replacement tx: %v", err)
+	}
+	return bumped, nil
+}
+
+// replacementFee returns the fee of a replacement at the fee rate. BIP125 requires the replacement to pay at least the
+// fee of the original transaction, and the relay fee of the replacement.
+func (c *client) replacementFee(utxos btctypes.UTXOs, recipients btctypes.Recipients, originalFee btctypes.Amount, feeRate btctypes.FeeRate) (btctypes.Amount, error) {
+	fee, err := c.EstimateFee(utxos, recipients, feeRate)
+	if err != nil {
+		return 0, err
+	}
+	minFee, err := c.EstimateFee(utxos, recipients, MinFeeRate)
+	if err != nil {
+		return 0, err
+	}
+	if minFee += originalFee; fee < minFee {
+		return minFee, nil
+	}
+	return fee, nil
+}
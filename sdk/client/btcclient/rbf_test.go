@@ -0,0 +1,167 @@
+package btcclient_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/sdk/client/btcclient"
+
+	"github.com/renproject/mercury/rpcclient"
+	"github.com/renproject/mercury/testutil"
+	"github.com/renproject/mercury/testutil/btcsim"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("replace-by-fee", func() {
+	logger := logrus.StandardLogger()
+	ctx := context.Background()
+	network := btctypes.BtcRegtest
+
+	// setup returns a client of a simulated chain, and a key whose address has a confirmed UTXO of 100000 satoshis.
+	setup := func() (*btcsim.Chain, Client, func(), *ecdsa.PrivateKey, btctypes.Address) {
+		chain, err := btcsim.New(network)
+		Expect(err).NotTo(HaveOccurred())
+		server := btcsim.NewServer(chain)
+		client := NewCustomClient(logger, network, server.URL)
+
+		key, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		from, err := btctypes.AddressFromPubKey(key.PublicKey, network)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = chain.Fund(from, 100000)
+		Expect(err).NotTo(HaveOccurred())
+		chain.Mine(1)
+		return chain, client, server.Close, key, from
+	}
+
+	// transferTo submits a transaction paying the amount to the address at the fee rate.
+	transferTo := func(client Client, key *ecdsa.PrivateKey, from, to btctypes.Address, amount btctypes.Amount, feeRate btctypes.FeeRate, opts btctypes.TxOptions) btctypes.BtcTx {
+		utxos, err := client.UTXOsFromAddress(ctx, from)
+		Expect(err).NotTo(HaveOccurred())
+		tx, err := client.BuildUnsignedTxWithOptions(utxos, btctypes.Recipients{btctypes.NewRecipient(to, amount)}, from, feeRate, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tx.Sign(key)).To(Succeed())
+		_, err = client.SubmitSignedTx(ctx, tx)
+		Expect(err).NotTo(HaveOccurred())
+		return tx
+	}
+
+	// transfer submits a transaction paying the amount to a random address at the fee rate.
+	transfer := func(client Client, key *ecdsa.PrivateKey, from btctypes.Address, amount btctypes.Amount, feeRate btctypes.FeeRate, opts btctypes.TxOptions) btctypes.BtcTx {
+		to, err := testutil.RandomAddress(network)
+		Expect(err).NotTo(HaveOccurred())
+		return transferTo(client, key, from, to, amount, feeRate, opts)
+	}
+
+	// fee returns the fee paid by the transaction in the mempool.
+	fee := func(chain *btcsim.Chain, tx btctypes.BtcTx) btctypes.Amount {
+		entry, err := chain.GetMempoolEntry(ctx, tx.Hash())
+		Expect(err).NotTo(HaveOccurred())
+		return btctypes.AmountFromFloat64(entry.Fees.Base)
+	}
+
+	Context("when bumping the fee of a replaceable transaction", func() {
+		It("should take the fee from the change and replace the transaction", func() {
+			chain, client, closeFn, key, from := setup()
+			defer closeFn()
+			tx := transfer(client, key, from, 50000, 2, btctypes.TxOptions{RBF: true})
+			entry, err := chain.GetMempoolEntry(ctx, tx.Hash())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Replaceable).To(BeTrue())
+
+			bumped, err := client.BumpFee(tx, from, 10, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bumped.IsSigned()).To(BeTrue())
+			Expect(bumped.Replaceable()).To(BeTrue())
+			Expect(bumped.UTXOs()).To(Equal(tx.UTXOs()))
+			Expect(bumped.Recipients()[0]).To(Equal(tx.Recipients()[0]))
+			Expect(bumped.Recipients()[1].Amount).To(BeNumerically("<", tx.Recipients()[1].Amount))
+
+			_, err = client.SubmitSignedTx(ctx, bumped)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chain.Mempool()).To(Equal([]types.TxHash{bumped.Hash()}))
+			entry, err = chain.GetMempoolEntry(ctx, bumped.Hash())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fee(chain, bumped)).To(BeNumerically(">=", 10*entry.VSize))
+
+			// The fee rate of a replacement must be higher than the fee rate of the transaction.
+			_, err = client.BumpFee(bumped, from, 10, key)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should remove the change output if the remaining change is dust", func() {
+			chain, client, closeFn, key, from := setup()
+			defer closeFn()
+			tx := transfer(client, key, from, 98000, 2, btctypes.TxOptions{RBF: true})
+			Expect(tx.Recipients()).To(HaveLen(2))
+
+			bumped, err := client.BumpFee(tx, from, 8, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bumped.Recipients()).To(Equal(tx.Recipients()[:1]))
+			_, err = client.SubmitSignedTx(ctx, bumped)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fee(chain, bumped)).To(Equal(btctypes.Amount(2000)))
+
+			// There is not enough change left to bump the fee again.
+			_, err = client.BumpFee(bumped, from, 20, key)
+			Expect(err).To(Equal(ErrNoChange))
+		})
+	})
+
+	Context("when bumping the fee of a transaction which pays the refund address", func() {
+		It("should only take the fee from the last output", func() {
+			_, client, closeFn, key, from := setup()
+			defer closeFn()
+			tx := transferTo(client, key, from, from, 50000, 2, btctypes.TxOptions{RBF: true})
+			Expect(tx.Recipients()).To(HaveLen(2))
+
+			bumped, err := client.BumpFee(tx, from, 10, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bumped.Recipients()[0]).To(Equal(tx.Recipients()[0]))
+			Expect(bumped.Recipients()[1].Amount).To(BeNumerically("<", tx.Recipients()[1].Amount))
+			_, err = client.SubmitSignedTx(ctx, bumped)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return an error if the only output pays the refund address", func() {
+			_, client, closeFn, key, from := setup()
+			defer closeFn()
+			utxos, err := client.UTXOsFromAddress(ctx, from)
+			Expect(err).NotTo(HaveOccurred())
+			recipients := btctypes.Recipients{btctypes.NewRecipient(from, 0)}
+			fee, err := client.EstimateFee(utxos, recipients, 2)
+			Expect(err).NotTo(HaveOccurred())
+			recipients[0].Amount = utxos.Sum() - fee
+			tx := transferTo(client, key, from, from, recipients[0].Amount, 2, btctypes.TxOptions{RBF: true})
+			Expect(tx.Recipients()).To(HaveLen(1))
+
+			_, err = client.BumpFee(tx, from, 10, key)
+			Expect(err).To(Equal(ErrAmbiguousChange))
+		})
+	})
+
+	Context("when bumping the fee of a transaction which cannot be replaced", func() {
+		It("should return an error", func() {
+			chain, client, closeFn, key, from := setup()
+			defer closeFn()
+			tx := transfer(client, key, from, 50000, 2, btctypes.DefaultTxOptions())
+			_, err := client.BumpFee(tx, from, 10, key)
+			Expect(err).To(Equal(ErrNotReplaceable))
+
+			// The chain rejects replacements of transactions which do not signal replaceability.
+			replacement, err := client.BuildUnsignedTxWithOptions(tx.UTXOs(), tx.Recipients()[:1], from, 10, btctypes.TxOptions{RBF: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(replacement.Sign(key)).To(Succeed())
+			_, err = client.SubmitSignedTx(ctx, replacement)
+			Expect(errors.Is(err, rpcclient.ErrVerifyRejected)).To(BeTrue())
+			Expect(chain.Mempool()).To(Equal([]types.TxHash{tx.Hash()}))
+		})
+	})
+})
@@ -171,14 +171,12 @@ var _ = Describe("btc gateway", func() {
 				// fmt.Printf("utxo: %v", gatewayUTXO)
 				gatewayUTXOs := btctypes.UTXOs{gatewayUTXO}
 				Expect(len(gatewayUTXOs)).To(BeNumerically(">", 0))
-				txSize := gateway.EstimateTxSize(0, len(gatewayUTXOs), 1)
-				gasAmount := client.SuggestGasPrice(ctx, types.Standard, txSize)
-				fmt.Printf("gas amount=%v", gasAmount)
-				recipients := btctypes.Recipients{{
-					Address: account.Address(),
-					Amount:  gatewayUTXOs.Sum() - gasAmount,
-				}}
-				tx, err := client.BuildUnsignedTx(gatewayUTXOs, recipients, account.Address(), gasAmount)
+				feeRate := client.SuggestFeeRate(ctx, types.Standard)
+				recipients := btctypes.Recipients{btctypes.NewRecipient(account.Address(), 0)}
+				fee, err := client.EstimateFee(gatewayUTXOs, recipients, feeRate)
+				Expect(err).NotTo(HaveOccurred())
+				recipients[0].Amount = gatewayUTXOs.Sum() - fee
+				tx, err := client.BuildUnsignedTxAtFeeRate(gatewayUTXOs, recipients, account.Address(), feeRate)
 				Expect(err).NotTo(HaveOccurred())
 
 				// Sign the transaction
@@ -225,14 +223,12 @@ var _ = Describe("btc gateway", func() {
 			// fmt.Printf("utxo: %v", gatewayUTXO)
 			gatewayUTXOs := btctypes.UTXOs{gatewayUTXO}
 			Expect(len(gatewayUTXOs)).To(BeNumerically(">", 0))
-			txSize := gateway.EstimateTxSize(0, len(gatewayUTXOs), 1)
-			gasAmount := client.SuggestGasPrice(ctx, types.Standard, txSize)
-			fmt.Printf("gas amount=%v", gasAmount)
-			recipients := btctypes.Recipients{{
-				Address: account.Address(),
-				Amount:  gatewayUTXOs.Sum() - gasAmount,
-			}}
-			tx, err := client.BuildUnsignedTx(gatewayUTXOs, recipients, account.Address(), gasAmount)
+			feeRate := client.SuggestFeeRate(ctx, types.Standard)
+			recipients := btctypes.Recipients{btctypes.NewRecipient(account.Address(), 0)}
+			fee, err := client.EstimateFee(gatewayUTXOs, recipients, feeRate)
+			Expect(err).NotTo(HaveOccurred())
+			recipients[0].Amount = gatewayUTXOs.Sum() - fee
+			tx, err := client.BuildUnsignedTxAtFeeRate(gatewayUTXOs, recipients, account.Address(), feeRate)
 			Expect(err).NotTo(HaveOccurred())
 
 			// Sign the transaction
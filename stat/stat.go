@@ -1,59 +1,328 @@
+// Package stat keeps a rolling time series of the requests handled by the server. Requests are counted in minute
+// buckets, which are removed once they are older than the retention period.
 package stat
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
 
+// DefaultRetention is how long requests are kept for by default.
+const DefaultRetention = 24 * time.Hour
+
+// UnknownMethod is the method recorded for requests without a valid, whitelisted method.
+const UnknownMethod = "unknown"
+
+// latencyBounds are the upper bounds of the buckets of the latency histogram. Latencies above the last bound are
+// counted in an additional bucket.
+var latencyBounds = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// Event is a request handled by the server.
+type Event struct {
+	// Time is when the request was received. The current time is used if it is zero.
+	Time time.Time
+
+	// Caller identifies who sent the request. It is empty for anonymous requests.
+	Caller string
+
+	Network string
+	Method  string
+	Status  int
+	Cache   string
+	Latency time.Duration
+}
+
+// Recorder records the requests handled by the server.
+type Recorder interface {
+	Record(event Event)
+}
+
+// recorders records each event using every recorder.
+type recorders []Recorder
+
+// Recorders returns a Recorder which records each event using every given recorder.
+func Recorders(rs ...Recorder) Recorder {
+	return recorders(rs)
+}
+
+func (rs recorders) Record(event Event) {
+	for _, r := range rs {
+		r.Record(event)
+	}
+}
+
+// Filter selects the requests included in a summary. Empty fields match every request.
+type Filter struct {
+	// Window is how far back to look. The retention period is used if it is zero.
+	Window  time.Duration
+	Network string
+	Method  string
+}
+
+// Summary is the statistics of the requests which match a filter.
+type Summary struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Total    Series            `json:"total"`
+	Networks map[string]Series `json:"networks"`
+	Methods  map[string]Series `json:"methods"`
+	Timeline []Point           `json:"timeline"`
+}
+
+// Series is the statistics of a group of requests.
+type Series struct {
+	Requests uint64            `json:"requests"`
+	Errors   uint64            `json:"errors"`
+	Statuses map[int]uint64    `json:"statuses"`
+	Cache    map[string]uint64 `json:"cache"`
+	Latency  Latency           `json:"latency"`
+}
+
+// Latency is the estimated quantiles of the latency of a group of requests in milliseconds.
+type Latency struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+}
+
+// Point is the number of requests received in a minute.
+type Point struct {
+	Time     time.Time `json:"time"`
+	Requests uint64    `json:"requests"`
+	Errors   uint64    `json:"errors"`
+}
+
 type Stat struct {
-	// requestTimes is a map of hour -> method -> count
-	requestTimes map[int]map[string]int
-	// initTimes is a map of hour -> time
-	initTimes  map[int]time.Time
-	requestsMu *sync.Mutex
+	// buckets is a map of minute -> network and method -> counters
+	buckets   map[int64]map[key]*counters
+	retention time.Duration
+	mu        *sync.Mutex
+
+	// pruned is the oldest minute kept when the buckets were last pruned. Buckets are pruned at most once per minute.
+	pruned int64
+}
+
+type key struct {
+	network string
+	method  string
+}
+
+// counters are the aggregated events of a bucket.
+type counters struct {
+	requests  uint64
+	errors    uint64
+	statuses  map[int]uint64
+	cache     map[string]uint64
+	latencies []uint64
 }
 
-// day is the number of nanoseconds in a day
-const day = 24 * time.Hour
+func newCounters() *counters {
+	return &counters{
+		statuses:  map[int]uint64{},
+		cache:     map[string]uint64{},
+		latencies: make([]uint64, len(latencyBounds)+1),
+	}
+}
 
+// New returns a new Stat which keeps requests for the default retention period.
 func New() Stat {
-	requestTimes := make(map[int]map[string]int)
-	initTimes := make(map[int]time.Time)
+	return NewWithRetention(DefaultRetention)
+}
+
+// NewWithRetention returns a new Stat which keeps requests for the given retention period.
+func NewWithRetention(retention time.Duration) Stat {
 	return Stat{
-		requestTimes: requestTimes,
-		initTimes:    initTimes,
-		requestsMu:   &sync.Mutex{},
+		buckets:   map[int64]map[key]*counters{},
+		retention: retention,
+		mu:        &sync.Mutex{},
 	}
 }
 
-func (stat *Stat) Get() map[string]int {
-	t := time.Now().Truncate(time.Hour)
-	numRequests := make(map[string]int)
-	stat.requestsMu.Lock()
-	for hour, methodTimestamps := range stat.requestTimes {
-		// only count the hour if it was within the past day
-		if t.Sub(stat.initTimes[hour]) > day {
-			continue
+// Record adds a request to the bucket of the minute it was received in. Requests older than the retention period are
+// ignored.
+func (stat *Stat) Record(event Event) {
+	now := time.Now()
+	if event.Time.IsZero() {
+		event.Time = now
+	}
+	oldest := now.Add(-stat.retention).Truncate(time.Minute)
+	if event.Time.Before(oldest) {
+		return
+	}
+
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+
+	// Remove the buckets which are older than the retention period once the oldest minute changes.
+	if oldest.Unix() > stat.pruned {
+		for minute := range stat.buckets {
+			if minute < oldest.Unix() {
+				delete(stat.buckets, minute)
+			}
 		}
+		stat.pruned = oldest.Unix()
+	}
+
+	minute := event.Time.Truncate(time.Minute).Unix()
+	if stat.buckets[minute] == nil {
+		stat.buckets[minute] = map[key]*counters{}
+	}
+	k := key{event.Network, event.Method}
+	if stat.buckets[minute][k] == nil {
+		stat.buckets[minute][k] = newCounters()
+	}
+	stat.buckets[minute][k].add(event)
+}
+
+// Get returns the statistics of the requests which match the filter.
+func (stat *Stat) Get(filter Filter) Summary {
+	window := filter.Window
+	if window <= 0 || window > stat.retention {
+		window = stat.retention
+	}
+	to := time.Now().Truncate(time.Minute)
+	from := to.Add(-window).Add(time.Minute)
 
-		for method, count := range methodTimestamps {
-			numRequests[method] += count
+	total := newCounters()
+	networks := map[string]*counters{}
+	methods := map[string]*counters{}
+	// The retention period is not validated, so the capacity is clamped in case it is negative.
+	capacity := int(window / time.Minute)
+	if capacity < 0 {
+		capacity = 0
+	}
+	timeline := make([]Point, 0, capacity)
+	for t := from; !t.After(to); t = t.Add(time.Minute) {
+		timeline = append(timeline, Point{Time: t})
+	}
+
+	stat.mu.Lock()
+	for minute, bucket := range stat.buckets {
+		t := time.Unix(minute, 0)
+		if t.Before(from) || t.After(to) {
+			continue
 		}
+		point := &timeline[int(t.Sub(from)/time.Minute)]
+		for k, c := range bucket {
+			if (filter.Network != "" && filter.Network != k.network) || (filter.Method != "" && filter.Method != k.method) {
+				continue
+			}
+			if networks[k.network] == nil {
+				networks[k.network] = newCounters()
+			}
+			if methods[k.method] == nil {
+				methods[k.method] = newCounters()
+			}
+			total.merge(c)
+			networks[k.network].merge(c)
+			methods[k.method].merge(c)
+			point.Requests += c.requests
+			point.Errors += c.errors
+		}
+	}
+	stat.mu.Unlock()
+
+	summary := Summary{
+		From:     from,
+		To:       to.Add(time.Minute),
+		Total:    total.series(),
+		Networks: make(map[string]Series, len(networks)),
+		Methods:  make(map[string]Series, len(methods)),
+		Timeline: timeline,
+	}
+	for network, c := range networks {
+		summary.Networks[network] = c.series()
+	}
+	for method, c := range methods {
+		summary.Methods[method] = c.series()
+	}
+	return summary
+}
+
+func (c *counters) add(event Event) {
+	c.requests++
+	if event.Status >= 400 {
+		c.errors++
+	}
+	c.statuses[event.Status]++
+	if event.Cache != "" {
+		c.cache[event.Cache]++
+	}
+	c.latencies[sort.Search(len(latencyBounds), func(i int) bool { return event.Latency <= latencyBounds[i] })]++
+}
+
+func (c *counters) merge(other *counters) {
+	c.requests += other.requests
+	c.errors += other.errors
+	for status, count := range other.statuses {
+		c.statuses[status] += count
+	}
+	for outcome, count := range other.cache {
+		c.cache[outcome] += count
+	}
+	for i, count := range other.latencies {
+		c.latencies[i] += count
 	}
-	stat.requestsMu.Unlock()
-	return numRequests
 }
 
-func (stat *Stat) Insert(method string) {
-	t := time.Now().Truncate(time.Hour)
-	stat.requestsMu.Lock()
+func (c *counters) series() Series {
+	return Series{
+		Requests: c.requests,
+		Errors:   c.errors,
+		Statuses: c.statuses,
+		Cache:    c.cache,
+		Latency: Latency{
+			P50: c.quantile(0.5),
+			P90: c.quantile(0.9),
+			P99: c.quantile(0.99),
+		},
+	}
+}
 
-	// initialise the secondary map if nil
-	if stat.requestTimes[t.Hour()] == nil || t.Sub(stat.initTimes[t.Hour()]) > day {
-		stat.requestTimes[t.Hour()] = make(map[string]int)
-		stat.initTimes[t.Hour()] = t
+// quantile estimates the given quantile of the latency in milliseconds by interpolating within the bucket of the
+// histogram which contains it.
+func (c *counters) quantile(q float64) float64 {
+	if c.requests == 0 {
+		return 0
 	}
+	rank := q * float64(c.requests)
+	cumulative := 0.0
+	for i, count := range c.latencies {
+		if count == 0 || cumulative+float64(count) < rank {
+			cumulative += float64(count)
+			continue
+		}
+		// Latencies above the last bound are reported as the last bound.
+		if i == len(latencyBounds) {
+			return milliseconds(latencyBounds[i-1])
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = milliseconds(latencyBounds[i-1])
+		}
+		upper := milliseconds(latencyBounds[i])
+		return lower + (upper-lower)*(rank-cumulative)/float64(count)
+	}
+	return milliseconds(latencyBounds[len(latencyBounds)-1])
+}
 
-	stat.requestTimes[t.Hour()][method]++
-	stat.requestsMu.Unlock()
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
 }
@@ -0,0 +1,99 @@
+package stat_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/stat"
+)
+
+var _ = Describe("Stat", func() {
+	record := func(s *Stat, ago time.Duration, network, method string, status int, latency time.Duration) {
+		s.Record(Event{
+			Time:    time.Now().Add(-ago),
+			Network: network,
+			Method:  method,
+			Status:  status,
+			Cache:   "miss",
+			Latency: latency,
+		})
+	}
+
+	Context("when querying a window", func() {
+		It("should only include requests within the window", func() {
+			s := New()
+			record(&s, 0, "btc/mainnet", "getblockcount", 200, time.Millisecond)
+			record(&s, 30*time.Minute, "btc/mainnet", "getblockcount", 200, time.Millisecond)
+			record(&s, 3*time.Hour, "btc/mainnet", "getblockcount", 200, time.Millisecond)
+
+			Expect(s.Get(Filter{Window: 10 * time.Minute}).Total.Requests).To(Equal(uint64(1)))
+			Expect(s.Get(Filter{Window: time.Hour}).Total.Requests).To(Equal(uint64(2)))
+			Expect(s.Get(Filter{}).Total.Requests).To(Equal(uint64(3)))
+
+			summary := s.Get(Filter{Window: time.Hour})
+			Expect(summary.Timeline).To(HaveLen(60))
+			Expect(summary.Timeline[59].Requests).To(Equal(uint64(1)))
+			Expect(summary.To.Sub(summary.From)).To(Equal(time.Hour))
+		})
+
+		It("should drop requests older than the retention period", func() {
+			s := NewWithRetention(time.Hour)
+			record(&s, 2*time.Hour, "btc/mainnet", "getblockcount", 200, time.Millisecond)
+			record(&s, 0, "btc/mainnet", "getblockcount", 200, time.Millisecond)
+
+			summary := s.Get(Filter{Window: 24 * time.Hour})
+			Expect(summary.Total.Requests).To(Equal(uint64(1)))
+			Expect(summary.Timeline).To(HaveLen(60))
+		})
+
+		It("should not panic if the retention period is negative", func() {
+			s := NewWithRetention(-time.Hour)
+			record(&s, 0, "btc/mainnet", "getblockcount", 200, time.Millisecond)
+
+			summary := s.Get(Filter{})
+			Expect(summary.Total.Requests).To(Equal(uint64(0)))
+			Expect(summary.Timeline).To(BeEmpty())
+		})
+	})
+
+	Context("when filtering by network and method", func() {
+		It("should break down the matching requests", func() {
+			s := New()
+			record(&s, 0, "btc/mainnet", "getblockcount", 200, time.Millisecond)
+			record(&s, 0, "btc/mainnet", "listunspent", 500, time.Millisecond)
+			record(&s, 0, "eth/mainnet", "eth_blockNumber", 200, time.Millisecond)
+
+			summary := s.Get(Filter{Network: "btc/mainnet"})
+			Expect(summary.Total.Requests).To(Equal(uint64(2)))
+			Expect(summary.Total.Errors).To(Equal(uint64(1)))
+			Expect(summary.Total.Statuses).To(Equal(map[int]uint64{200: 1, 500: 1}))
+			Expect(summary.Total.Cache).To(Equal(map[string]uint64{"miss": 2}))
+			Expect(summary.Networks).To(HaveLen(1))
+			Expect(summary.Methods).To(HaveKey("listunspent"))
+
+			summary = s.Get(Filter{Method: "eth_blockNumber"})
+			Expect(summary.Total.Requests).To(Equal(uint64(1)))
+			Expect(summary.Networks).To(HaveKey("eth/mainnet"))
+		})
+	})
+
+	Context("when estimating latency quantiles", func() {
+		It("should interpolate within the histogram buckets", func() {
+			s := New()
+			for i := 0; i < 90; i++ {
+				record(&s, 0, "btc/mainnet", "getblockcount", 200, 15*time.Millisecond)
+			}
+			for i := 0; i < 10; i++ {
+				record(&s, 0, "btc/mainnet", "getblockcount", 200, 3*time.Second)
+			}
+
+			latency := s.Get(Filter{}).Total.Latency
+			Expect(latency.P50).To(BeNumerically(">", 10))
+			Expect(latency.P50).To(BeNumerically("<=", 20))
+			Expect(latency.P90).To(BeNumerically("<=", 20))
+			Expect(latency.P99).To(BeNumerically(">", 2000))
+			Expect(latency.P99).To(BeNumerically("<=", 5000))
+		})
+	})
+})
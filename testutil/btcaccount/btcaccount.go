@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"crypto/rand"
+	"errors"
 	"fmt"
 
 	"github.com/btcsuite/btcutil"
@@ -100,20 +101,39 @@ func (acc *account) Transfer(ctx context.Context, to btctypes.Address, value btc
 		return "", fmt.Errorf("error fetching utxos: %v", err)
 	}
 
-	fee := acc.Client.SuggestGasPrice(context.Background(), speed, acc.Client.EstimateTxSize(len(utxos), 2))
-	if all {
-		value = utxos.Sum() - fee
-	}
+	feeRate := acc.Client.SuggestFeeRate(ctx, speed)
 
-	// Check if we have enough funds
-	balance := utxos.Sum()
-	if balance < value+fee {
-		return "", ErrInsufficientBalance(fmt.Sprintf("%v", value), fmt.Sprintf("%v", balance))
+	recipients := btctypes.Recipients{{Address: to, Amount: value}}
+	if all {
+		// Sweep every UTXO to the recipient.
+		fee, err := acc.Client.EstimateFee(utxos, recipients, feeRate)
+		if err != nil {
+			return "", fmt.Errorf("error estimating fee: %v", err)
+		}
+		recipients[0].Amount = utxos.Sum() - fee
+		if recipients[0].Amount <= 0 {
+			return "", ErrInsufficientBalance(fmt.Sprintf("%v", fee), fmt.Sprintf("%v", utxos.Sum()))
+		}
+	} else {
+		// Avoid a change output if possible, and spend the UTXOs in a random order otherwise.
+		opts := btcclient.DefaultCoinSelectionOptions()
+		opts.FeeRate = feeRate
+		opts.MinConfirmations = 0
+		selection, err := btcclient.SelectCoins(acc.Client.Network(), utxos, recipients, acc.Address(), opts)
+		if errors.Is(err, btcclient.ErrNoChangelessSolution) {
+			opts.Strategy = btcclient.Random
+			selection, err = btcclient.SelectCoins(acc.Client.Network(), utxos, recipients, acc.Address(), opts)
+		}
+		if errors.Is(err, btcclient.ErrInsufficientFunds) {
+			return "", ErrInsufficientBalance(fmt.Sprintf("%v", value), fmt.Sprintf("%v", utxos.Sum()))
+		}
+		if err != nil {
+			return "", fmt.Errorf("error selecting utxos: %v", err)
+		}
+		utxos = selection.UTXOs
 	}
 
-	// todo : select some utxos from all the utxos we have.
-	tx, err := acc.Client.BuildUnsignedTx(utxos, btctypes.Recipients{{Address: to, Amount: value}}, acc.Address(), fee)
-
+	tx, err := acc.Client.BuildUnsignedTxAtFeeRate(utxos, recipients, acc.Address(), feeRate)
 	if err != nil {
 		return "", fmt.Errorf("error building unsigned tx: %v", err)
 	}
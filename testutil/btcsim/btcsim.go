@@ -0,0 +1,912 @@
+// Package btcsim implements an in-process simulated chain of Bitcoin and its forks. The chain keeps a UTXO set and a
+// mempool, and produces blocks when requested. It implements `btcrpcclient.Client` so that it can be used directly by
+// the SDK, and it serves bitcoind-style JSON-RPC so that it can be used as the upstream node of Mercury.
+package btcsim
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/renproject/mercury/rpcclient"
+	"github.com/renproject/mercury/rpcclient/btcrpcclient"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+// Error codes returned by bitcoind which are not defined by the rpcclient package.
+const (
+	codeInvalidAddressOrKey  = -5
+	codeInvalidParameter     = -8
+	codeDeserializationError = -22
+)
+
+const (
+	// DefaultFeeRate is the fee rate (in satoshis per virtual byte) returned by `estimatesmartfee` unless another rate
+	// is set.
+	DefaultFeeRate = btctypes.Amount(10)
+
+	// MinRelayFeeRate is the minimum fee rate (in satoshis per virtual byte) of transactions accepted by the mempool.
+	MinRelayFeeRate = btctypes.Amount(1)
+
+	// blockInterval is the difference between the timestamps of consecutive blocks.
+	blockInterval = 10 * time.Minute
+)
+
+// Chain is a simulated chain. Transactions are validated against the UTXO set, and their scripts are executed using
+// `txscript` (except on Bitcoin Cash, which uses a different signature hash). It is safe for concurrent use.
+type Chain struct {
+	mu      sync.Mutex
+	network btctypes.Network
+	start   time.Time
+	feeRate btctypes.Amount
+	nonce   uint64
+
+	blocks  []block
+	heights map[chainhash.Hash]int
+	txs     map[chainhash.Hash]*entry
+	utxos   map[wire.OutPoint]*wire.TxOut
+	spentBy map[wire.OutPoint]chainhash.Hash
+	mempool []chainhash.Hash
+}
+
+type block struct {
+	hash   chainhash.Hash
+	header wire.BlockHeader
+	txs    []*wire.MsgTx
+}
+
+// entry is a transaction which is either in the mempool (with a height of -1) or in a block.
+type entry struct {
+	tx     *wire.MsgTx
+	height int
+	fee    btctypes.Amount
+
+	// time and entryHeight are the time and the height of the chain when the transaction was accepted.
+	time        time.Time
+	entryHeight int
+}
+
+// New returns a new simulated chain of the network which only has a genesis block. Chains which do not use the
+// transaction format of Bitcoin (i.e. ZCash) are not supported.
+func New(network btctypes.Network) (*Chain, error) {
+	switch network.Chain() {
+	case types.Bitcoin, types.BitcoinCash, types.Litecoin, types.Dogecoin:
+	default:
+		return nil, fmt.Errorf("unsupported chain: %s", network.Chain())
+	}
+
+	chain := &Chain{
+		network: network,
+		start:   time.Now().Truncate(time.Second),
+		feeRate: DefaultFeeRate,
+		heights: map[chainhash.Hash]int{},
+		txs:     map[chainhash.Hash]*entry{},
+		utxos:   map[wire.OutPoint]*wire.TxOut{},
+		spentBy: map[wire.OutPoint]chainhash.Hash{},
+	}
+	chain.mine()
+	return chain, nil
+}
+
+// Network returns the network of the chain.
+func (chain *Chain) Network() btctypes.Network {
+	return chain.network
+}
+
+// SetFeeRate sets the fee rate (in satoshis per virtual byte) returned by `estimatesmartfee`.
+func (chain *Chain) SetFeeRate(feeRate btctypes.Amount) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.feeRate = feeRate
+}
+
+// Fund adds a transaction which sends the amount to the address into the mempool, and returns the outpoint of the
+// output. The transaction is not validated as its input does not exist.
+func (chain *Chain) Fund(address btctypes.Address, amount btctypes.Amount) (btctypes.OutPoint, error) {
+	script, err := btctypes.PayToAddrScript(address, chain.network)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the script of %s: %v", address.EncodeAddress(), err)
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	tx := wire.NewMsgTx(btctypes.BtcVersion)
+	chain.nonce++
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(chain.nonceHash(), 0), chain.nonceScript(), nil))
+	tx.AddTxOut(wire.NewTxOut(int64(amount), script))
+	chain.addToMempool(tx, 0)
+	return btctypes.NewOutPoint(types.TxHash(tx.TxHash().String()), 0), nil
+}
+
+// Mine produces the given number of blocks, and returns their hashes. The first block includes every transaction in
+// the mempool.
+func (chain *Chain) Mine(n int) []string {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	hashes := make([]string, n)
+	for i := range hashes {
+		hashes[i] = chain.mine().String()
+	}
+	return hashes
+}
+
+// Height returns the height of the tip of the chain.
+func (chain *Chain) Height() int {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return chain.height()
+}
+
+// Mempool returns the hashes of the transactions in the mempool in the order they were accepted.
+func (chain *Chain) Mempool() []types.TxHash {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	hashes := make([]types.TxHash, len(chain.mempool))
+	for i, hash := range chain.mempool {
+		hashes[i] = types.TxHash(hash.String())
+	}
+	return hashes
+}
+
+// ListUnspent implements the `btcrpcclient.Client` interface.
+func (chain *Chain) ListUnspent(ctx context.Context, minConf, maxConf int64, addresses []btctypes.Address) (btcrpcclient.ListUnspentResponse, error) {
+	scripts := make([][]byte, len(addresses))
+	for i, address := range addresses {
+		script, err := btctypes.PayToAddrScript(address, chain.network)
+		if err != nil {
+			return nil, invalidAddress(address.EncodeAddress())
+		}
+		scripts[i] = script
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return chain.listUnspent(minConf, maxConf, scripts), nil
+}
+
+// SendRawTransaction implements the `btcrpcclient.Client` interface.
+func (chain *Chain) SendRawTransaction(ctx context.Context, stx btctypes.BtcTx) (string, error) {
+	data, err := stx.Serialize()
+	if err != nil {
+		return "", err
+	}
+	tx, err := deserialize(data)
+	if err != nil {
+		return "", err
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return chain.accept(tx)
+}
+
+// GetTxOut implements the `btcrpcclient.Client` interface. It returns `rpcclient.ErrNullResult` if the output does
+// not exist or has been spent.
+func (chain *Chain) GetTxOut(ctx context.Context, txid types.TxHash, i uint32) (btcrpcclient.GetTxOutResponse, error) {
+	hash, err := chainhash.NewHashFromStr(string(txid))
+	if err != nil {
+		return btcrpcclient.GetTxOutResponse{}, &types.JSONError{Code: codeInvalidParameter, Message: fmt.Sprintf("invalid txid: %s", txid)}
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	txOut, ok := chain.utxos[wire.OutPoint{Hash: *hash, Index: i}]
+	if !ok {
+		return btcrpcclient.GetTxOutResponse{}, rpcclient.ErrNullResult
+	}
+	return chain.txOut(*hash, txOut), nil
+}
+
+// GetRawTransactionVerbose implements the `btcrpcclient.Client` interface.
+func (chain *Chain) GetRawTransactionVerbose(ctx context.Context, txid types.TxHash) (btcrpcclient.RawTransactionVerbose, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	entry, err := chain.entry(txid)
+	if err != nil {
+		return btcrpcclient.RawTransactionVerbose{}, err
+	}
+	return chain.verbose(entry.tx, entry.height), nil
+}
+
+// GetBlockCount implements the `btcrpcclient.Client` interface.
+func (chain *Chain) GetBlockCount(ctx context.Context) (int64, error) {
+	return int64(chain.Height()), nil
+}
+
+// GetBestBlockHash implements the `btcrpcclient.Client` interface.
+func (chain *Chain) GetBestBlockHash(ctx context.Context) (string, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return chain.tip().hash.String(), nil
+}
+
+// GetBlockHeader implements the `btcrpcclient.Client` interface.
+func (chain *Chain) GetBlockHeader(ctx context.Context, blockHash string) (btcrpcclient.BlockHeaderVerbose, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	height, err := chain.blockHeight(blockHash)
+	if err != nil {
+		return btcrpcclient.BlockHeaderVerbose{}, err
+	}
+	return chain.header(height), nil
+}
+
+// GetBlock implements the `btcrpcclient.Client` interface.
+func (chain *Chain) GetBlock(ctx context.Context, blockHash string) (btcrpcclient.BlockVerbose, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	height, err := chain.blockHeight(blockHash)
+	if err != nil {
+		return btcrpcclient.BlockVerbose{}, err
+	}
+	return chain.block(height), nil
+}
+
+// EstimateSmartFee implements the `btcrpcclient.Client` interface. It always returns the fee rate of the chain.
+func (chain *Chain) EstimateSmartFee(ctx context.Context, confTarget int64) (btcrpcclient.EstimateSmartFeeResponse, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return chain.estimateSmartFee(), nil
+}
+
+// GetMempoolEntry implements the `btcrpcclient.Client` interface.
+func (chain *Chain) GetMempoolEntry(ctx context.Context, txid types.TxHash) (btcrpcclient.MempoolEntry, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	entry, err := chain.entry(txid)
+	if err != nil || entry.height >= 0 {
+		return btcrpcclient.MempoolEntry{}, errNotInMempool
+	}
+	return chain.mempoolEntry(entry), nil
+}
+
+// TestMempoolAccept implements the `btcrpcclient.Client` interface.
+func (chain *Chain) TestMempoolAccept(ctx context.Context, stxs []btctypes.BtcTx) ([]btcrpcclient.TestMempoolAcceptResult, error) {
+	txs := make([]*wire.MsgTx, len(stxs))
+	for i, stx := range stxs {
+		data, err := stx.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		if txs[i], err = deserialize(data); err != nil {
+			return nil, err
+		}
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return chain.testMempoolAccept(txs), nil
+}
+
+// DecodeRawTransaction implements the `btcrpcclient.Client` interface.
+func (chain *Chain) DecodeRawTransaction(ctx context.Context, rawTx []byte) (btcrpcclient.RawTransactionVerbose, error) {
+	tx, err := deserialize(rawTx)
+	if err != nil {
+		return btcrpcclient.RawTransactionVerbose{}, err
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	verbose := chain.verbose(tx, -1)
+	verbose.Hex = ""
+	return verbose, nil
+}
+
+// GetTxOuts implements the `btcrpcclient.Client` interface.
+func (chain *Chain) GetTxOuts(ctx context.Context, outpoints []btctypes.OutPoint) ([]btcrpcclient.TxOutResult, error) {
+	results := make([]btcrpcclient.TxOutResult, len(outpoints))
+	for i, op := range outpoints {
+		results[i].Tx, results[i].TxErr = chain.GetRawTransactionVerbose(ctx, op.TxHash())
+		results[i].TxOut, results[i].TxOutErr = chain.GetTxOut(ctx, op.TxHash(), op.Vout())
+	}
+	return results, nil
+}
+
+// accept adds the transaction to the mempool if it is valid, and returns its hash. The transactions it replaces are
+// removed from the mempool.
+func (chain *Chain) accept(tx *wire.MsgTx) (string, error) {
+	fee, replaced, err := chain.validate(tx)
+	if err != nil {
+		return "", err
+	}
+	for _, hash := range replaced {
+		chain.evict(hash)
+	}
+	chain.addToMempool(tx, fee)
+	return tx.TxHash().String(), nil
+}
+
+func (chain *Chain) testMempoolAccept(txs []*wire.MsgTx) []btcrpcclient.TestMempoolAcceptResult {
+	results := make([]btcrpcclient.TestMempoolAcceptResult, len(txs))
+	for i, tx := range txs {
+		results[i] = btcrpcclient.TestMempoolAcceptResult{
+			TxID:  tx.TxHash().String(),
+			WTxID: tx.WitnessHash().String(),
+			VSize: vsize(tx),
+		}
+		fee, _, err := chain.validate(tx)
+		if err != nil {
+			results[i].RejectReason = err.(*types.JSONError).Message
+			continue
+		}
+		results[i].Allowed = true
+		results[i].Fees.Base = btcutil.Amount(fee).ToBTC()
+	}
+	return results
+}
+
+func (chain *Chain) listUnspent(minConf, maxConf int64, scripts [][]byte) btcrpcclient.ListUnspentResponse {
+	resp := btcrpcclient.ListUnspentResponse{}
+	for op, txOut := range chain.utxos {
+		matches := false
+		for _, script := range scripts {
+			if bytes.Equal(script, txOut.PkScript) {
+				matches = true
+				break
+			}
+		}
+		confirmations := chain.confirmations(chain.txs[op.Hash].height)
+		if !matches || confirmations < minConf || confirmations > maxConf {
+			continue
+		}
+		scriptPubKey := chain.scriptPubKey(txOut.PkScript)
+		resp = append(resp, btcrpcclient.ListUnspentObj{
+			Address:       scriptPubKey.Address,
+			Amount:        btcutil.Amount(txOut.Value).ToBTC(),
+			TxID:          op.Hash.String(),
+			Vout:          op.Index,
+			ScriptPubKey:  scriptPubKey.Hex,
+			Confirmations: confirmations,
+			Spendable:     true,
+		})
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		if resp[i].TxID != resp[j].TxID {
+			return resp[i].TxID < resp[j].TxID
+		}
+		return resp[i].Vout < resp[j].Vout
+	})
+	return resp
+}
+
+// validate checks whether the transaction can be added to the mempool, and returns its fee and the hashes of the
+// mempool transactions it replaces (BIP125). The errors match the errors returned by bitcoind.
+func (chain *Chain) validate(tx *wire.MsgTx) (btctypes.Amount, []chainhash.Hash, error) {
+	hash := tx.TxHash()
+	if entry, ok := chain.txs[hash]; ok {
+		if entry.height >= 0 {
+			return 0, nil, &types.JSONError{Code: rpcclient.CodeVerifyAlreadyInChain, Message: "transaction already in block chain"}
+		}
+		return 0, nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: "txn-already-in-mempool"}
+	}
+	if len(tx.TxIn) == 0 {
+		return 0, nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: "bad-txns-vin-empty"}
+	}
+	if len(tx.TxOut) == 0 {
+		return 0, nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: "bad-txns-vout-empty"}
+	}
+
+	prevOuts := make([]*wire.TxOut, len(tx.TxIn))
+	conflicts := []chainhash.Hash{}
+	amountIn := int64(0)
+	for i, txIn := range tx.TxIn {
+		prevOut, ok := chain.utxos[txIn.PreviousOutPoint]
+		if !ok {
+			conflict, ok := chain.spentBy[txIn.PreviousOutPoint]
+			if !ok {
+				return 0, nil, &types.JSONError{Code: rpcclient.CodeVerifyError, Message: "bad-txns-inputs-missingorspent"}
+			}
+			// The output has been spent by a transaction in the mempool, which can be replaced if it signals so.
+			if !signalsRBF(chain.txs[conflict].tx) {
+				return 0, nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: "txn-mempool-conflict"}
+			}
+			conflicts = append(conflicts, conflict)
+			prevOut = chain.txs[txIn.PreviousOutPoint.Hash].tx.TxOut[txIn.PreviousOutPoint.Index]
+		}
+		prevOuts[i] = prevOut
+		amountIn += prevOut.Value
+	}
+	amountOut := int64(0)
+	for _, txOut := range tx.TxOut {
+		if txOut.Value < 0 {
+			return 0, nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: "bad-txns-vout-negative"}
+		}
+		amountOut += txOut.Value
+	}
+	if amountOut > amountIn {
+		return 0, nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: "bad-txns-in-belowout"}
+	}
+
+	// Bitcoin Cash signs the inputs using the fork ID, which is not supported by txscript.
+	if chain.network.Chain() != types.BitcoinCash {
+		sigHashes := txscript.NewTxSigHashes(tx)
+		for i, prevOut := range prevOuts {
+			engine, err := txscript.NewEngine(prevOut.PkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes, prevOut.Value)
+			if err == nil {
+				err = engine.Execute()
+			}
+			if err != nil {
+				return 0, nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: fmt.Sprintf("mandatory-script-verify-flag-failed (%v)", err)}
+			}
+		}
+	}
+
+	fee := btctypes.Amount(amountIn - amountOut)
+	if fee < MinRelayFeeRate*btctypes.Amount(vsize(tx)) {
+		return 0, nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: "min relay fee not met"}
+	}
+	replaced, err := chain.checkReplacement(tx, fee, conflicts)
+	if err != nil {
+		return 0, nil, err
+	}
+	return fee, replaced, nil
+}
+
+// checkReplacement checks that the transaction pays enough to replace the conflicting transactions, and returns the
+// hashes of the conflicting transactions and their descendants.
+func (chain *Chain) checkReplacement(tx *wire.MsgTx, fee btctypes.Amount, conflicts []chainhash.Hash) ([]chainhash.Hash, error) {
+	replaced := []chainhash.Hash{}
+	seen := map[chainhash.Hash]bool{}
+	replacedFee := btctypes.Amount(0)
+	for _, conflict := range conflicts {
+		entry := chain.txs[conflict]
+		// The replacement must have a higher fee rate than the transactions it directly replaces.
+		if !seen[conflict] && fee*btctypes.Amount(vsize(entry.tx)) <= entry.fee*btctypes.Amount(vsize(tx)) {
+			return nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: fmt.Sprintf("insufficient fee, rejecting replacement %v; new feerate is not higher than old feerate", tx.TxHash())}
+		}
+		for _, hash := range chain.descendants(conflict) {
+			if !seen[hash] {
+				seen[hash] = true
+				replaced = append(replaced, hash)
+				replacedFee += chain.txs[hash].fee
+			}
+		}
+	}
+	for _, txIn := range tx.TxIn {
+		if seen[txIn.PreviousOutPoint.Hash] {
+			return nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: "bad-txns-spends-conflicting-tx"}
+		}
+	}
+	// The replacement must pay for the transactions it replaces, and for its own relay.
+	if fee < replacedFee+MinRelayFeeRate*btctypes.Amount(vsize(tx)) {
+		return nil, &types.JSONError{Code: rpcclient.CodeVerifyRejected, Message: fmt.Sprintf("insufficient fee, rejecting replacement %v, not enough additional fees to relay", tx.TxHash())}
+	}
+	return replaced, nil
+}
+
+// descendants returns the hash of the mempool transaction, and the hashes of the mempool transactions which spend its
+// outputs (directly or indirectly). Each hash is only returned once.
+func (chain *Chain) descendants(hash chainhash.Hash) []chainhash.Hash {
+	return chain.walk(hash, func(tx *wire.MsgTx) []chainhash.Hash {
+		children := []chainhash.Hash{}
+		for i := range tx.TxOut {
+			if child, ok := chain.spentBy[wire.OutPoint{Hash: tx.TxHash(), Index: uint32(i)}]; ok {
+				children = append(children, child)
+			}
+		}
+		return children
+	})
+}
+
+// ancestors returns the hash of the mempool transaction, and the hashes of the mempool transactions whose outputs it
+// spends (directly or indirectly). Each hash is only returned once.
+func (chain *Chain) ancestors(hash chainhash.Hash) []chainhash.Hash {
+	return chain.walk(hash, func(tx *wire.MsgTx) []chainhash.Hash {
+		parents := []chainhash.Hash{}
+		for _, txIn := range tx.TxIn {
+			if parent, ok := chain.txs[txIn.PreviousOutPoint.Hash]; ok && parent.height < 0 {
+				parents = append(parents, txIn.PreviousOutPoint.Hash)
+			}
+		}
+		return parents
+	})
+}
+
+// walk returns the hash, and the hashes of the transactions reachable from it using the next function.
+func (chain *Chain) walk(hash chainhash.Hash, next func(tx *wire.MsgTx) []chainhash.Hash) []chainhash.Hash {
+	hashes := []chainhash.Hash{hash}
+	seen := map[chainhash.Hash]bool{hash: true}
+	for i := 0; i < len(hashes); i++ {
+		for _, h := range next(chain.txs[hashes[i]].tx) {
+			if !seen[h] {
+				seen[h] = true
+				hashes = append(hashes, h)
+			}
+		}
+	}
+	return hashes
+}
+
+// packageOf returns the number of transactions, the total virtual size and the total fee of the transactions.
+func (chain *Chain) packageOf(hashes []chainhash.Hash) (int64, int64, btctypes.Amount) {
+	size, fee := int64(0), btctypes.Amount(0)
+	for _, hash := range hashes {
+		size += vsize(chain.txs[hash].tx)
+		fee += chain.txs[hash].fee
+	}
+	return int64(len(hashes)), size, fee
+}
+
+// evict removes the mempool transaction and its descendants from the mempool, and restores the outputs they spent.
+func (chain *Chain) evict(hash chainhash.Hash) {
+	entry, ok := chain.txs[hash]
+	if !ok || entry.height >= 0 {
+		return
+	}
+	for i := range entry.tx.TxOut {
+		op := wire.OutPoint{Hash: hash, Index: uint32(i)}
+		if child, ok := chain.spentBy[op]; ok {
+			chain.evict(child)
+		}
+		delete(chain.utxos, op)
+	}
+	for _, txIn := range entry.tx.TxIn {
+		delete(chain.spentBy, txIn.PreviousOutPoint)
+		if parent, ok := chain.txs[txIn.PreviousOutPoint.Hash]; ok {
+			chain.utxos[txIn.PreviousOutPoint] = parent.tx.TxOut[txIn.PreviousOutPoint.Index]
+		}
+	}
+	delete(chain.txs, hash)
+	for i, mempoolHash := range chain.mempool {
+		if mempoolHash == hash {
+			chain.mempool = append(chain.mempool[:i], chain.mempool[i+1:]...)
+			break
+		}
+	}
+}
+
+func (chain *Chain) addToMempool(tx *wire.MsgTx, fee btctypes.Amount) {
+	hash := tx.TxHash()
+	for _, txIn := range tx.TxIn {
+		if _, ok := chain.utxos[txIn.PreviousOutPoint]; ok {
+			delete(chain.utxos, txIn.PreviousOutPoint)
+			chain.spentBy[txIn.PreviousOutPoint] = hash
+		}
+	}
+	for i, txOut := range tx.TxOut {
+		chain.utxos[wire.OutPoint{Hash: hash, Index: uint32(i)}] = txOut
+	}
+	chain.txs[hash] = &entry{
+		tx:          tx,
+		height:      -1,
+		fee:         fee,
+		time:        time.Now(),
+		entryHeight: chain.height(),
+	}
+	chain.mempool = append(chain.mempool, hash)
+}
+
+// mine produces a block with a coinbase transaction and the transactions in the mempool.
+func (chain *Chain) mine() chainhash.Hash {
+	height := len(chain.blocks)
+	chain.nonce++
+
+	// The coinbase does not have any spendable outputs.
+	coinbase := wire.NewMsgTx(btctypes.BtcVersion)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), chain.nonceScript(), nil))
+	nullData, _ := txscript.NullDataScript(nil)
+	coinbase.AddTxOut(wire.NewTxOut(0, nullData))
+	chain.txs[coinbase.TxHash()] = &entry{tx: coinbase, time: time.Now(), entryHeight: height}
+
+	txs := []*wire.MsgTx{coinbase}
+	for _, hash := range chain.mempool {
+		txs = append(txs, chain.txs[hash].tx)
+	}
+	for _, tx := range txs {
+		chain.txs[tx.TxHash()].height = height
+	}
+	chain.mempool = nil
+	chain.spentBy = map[wire.OutPoint]chainhash.Hash{}
+
+	prevBlock := chainhash.Hash{}
+	if height > 0 {
+		prevBlock = chain.tip().hash
+	}
+	header := wire.BlockHeader{
+		Version:    4,
+		PrevBlock:  prevBlock,
+		MerkleRoot: merkleRoot(txs),
+		Timestamp:  chain.start.Add(time.Duration(height) * blockInterval),
+		Bits:       chain.network.Params().PowLimitBits,
+		Nonce:      uint32(chain.nonce),
+	}
+	hash := header.BlockHash()
+	chain.blocks = append(chain.blocks, block{hash: hash, header: header, txs: txs})
+	chain.heights[hash] = height
+	return hash
+}
+
+func (chain *Chain) height() int {
+	return len(chain.blocks) - 1
+}
+
+func (chain *Chain) tip() block {
+	return chain.blocks[len(chain.blocks)-1]
+}
+
+// confirmations returns the number of confirmations of a transaction at the given height. Transactions in the
+// mempool have no confirmations.
+func (chain *Chain) confirmations(height int) int64 {
+	if height < 0 {
+		return 0
+	}
+	return int64(chain.height() - height + 1)
+}
+
+func (chain *Chain) entry(txid types.TxHash) (*entry, error) {
+	hash, err := chainhash.NewHashFromStr(string(txid))
+	if err != nil {
+		return nil, &types.JSONError{Code: codeInvalidParameter, Message: fmt.Sprintf("invalid txid: %s", txid)}
+	}
+	entry, ok := chain.txs[*hash]
+	if !ok {
+		return nil, &types.JSONError{Code: codeInvalidAddressOrKey, Message: "No such mempool or blockchain transaction"}
+	}
+	return entry, nil
+}
+
+func (chain *Chain) blockHeight(blockHash string) (int, error) {
+	hash, err := chainhash.NewHashFromStr(blockHash)
+	if err != nil {
+		return 0, &types.JSONError{Code: codeInvalidParameter, Message: fmt.Sprintf("invalid block hash: %s", blockHash)}
+	}
+	height, ok := chain.heights[*hash]
+	if !ok {
+		return 0, &types.JSONError{Code: codeInvalidAddressOrKey, Message: "Block not found"}
+	}
+	return height, nil
+}
+
+func (chain *Chain) header(height int) btcrpcclient.BlockHeaderVerbose {
+	block := chain.blocks[height]
+	header := btcrpcclient.BlockHeaderVerbose{
+		Hash:          block.hash.String(),
+		Confirmations: chain.confirmations(height),
+		Height:        int64(height),
+		Version:       block.header.Version,
+		MerkleRoot:    block.header.MerkleRoot.String(),
+		Time:          block.header.Timestamp.Unix(),
+		MedianTime:    block.header.Timestamp.Unix(),
+		Nonce:         uint64(block.header.Nonce),
+		Bits:          fmt.Sprintf("%08x", block.header.Bits),
+		Difficulty:    1,
+		NTx:           int64(len(block.txs)),
+	}
+	if height > 0 {
+		header.PreviousBlockHash = block.header.PrevBlock.String()
+	}
+	if height < chain.height() {
+		header.NextBlockHash = chain.blocks[height+1].hash.String()
+	}
+	return header
+}
+
+func (chain *Chain) block(height int) btcrpcclient.BlockVerbose {
+	block := chain.blocks[height]
+	verbose := btcrpcclient.BlockVerbose{
+		BlockHeaderVerbose: chain.header(height),
+		Tx:                 make([]string, len(block.txs)),
+	}
+	for i, tx := range block.txs {
+		verbose.Tx[i] = tx.TxHash().String()
+		verbose.Size += int64(tx.SerializeSize())
+		verbose.StrippedSize += int64(tx.SerializeSizeStripped())
+		verbose.Weight += weight(tx)
+	}
+	return verbose
+}
+
+func (chain *Chain) txOut(hash chainhash.Hash, txOut *wire.TxOut) btcrpcclient.GetTxOutResponse {
+	return btcrpcclient.GetTxOutResponse{
+		BestBlock:     chain.tip().hash.String(),
+		Confirmations: chain.confirmations(chain.txs[hash].height),
+		Value:         btcutil.Amount(txOut.Value).ToBTC(),
+		ScriptPubKey:  chain.scriptPubKey(txOut.PkScript),
+	}
+}
+
+// estimateSmartFee returns the fee rate of the chain in BTC/kB.
+func (chain *Chain) estimateSmartFee() btcrpcclient.EstimateSmartFeeResponse {
+	return btcrpcclient.EstimateSmartFeeResponse{
+		FeeRate: btcutil.Amount(chain.feeRate * 1000).ToBTC(),
+		Blocks:  1,
+	}
+}
+
+func (chain *Chain) mempoolEntry(entry *entry) btcrpcclient.MempoolEntry {
+	hash := entry.tx.TxHash()
+	fee := btcutil.Amount(entry.fee).ToBTC()
+	mempoolEntry := btcrpcclient.MempoolEntry{
+		VSize:       vsize(entry.tx),
+		Size:        int64(entry.tx.SerializeSize()),
+		Weight:      weight(entry.tx),
+		Fee:         fee,
+		ModifiedFee: fee,
+		Time:        entry.time.Unix(),
+		Height:      int64(entry.entryHeight),
+		WTxID:       entry.tx.WitnessHash().String(),
+		Depends:     []string{},
+		SpentBy:     []string{},
+	}
+	ancestorCount, ancestorSize, ancestorFee := chain.packageOf(chain.ancestors(hash))
+	descendantCount, descendantSize, descendantFee := chain.packageOf(chain.descendants(hash))
+	mempoolEntry.AncestorCount, mempoolEntry.AncestorSize = ancestorCount, ancestorSize
+	mempoolEntry.DescendantCount, mempoolEntry.DescendantSize = descendantCount, descendantSize
+	mempoolEntry.Fees = btcrpcclient.MempoolFees{
+		Base:       fee,
+		Modified:   fee,
+		Ancestor:   btcutil.Amount(ancestorFee).ToBTC(),
+		Descendant: btcutil.Amount(descendantFee).ToBTC(),
+	}
+	for _, txIn := range entry.tx.TxIn {
+		if parent, ok := chain.txs[txIn.PreviousOutPoint.Hash]; ok && parent.height < 0 {
+			mempoolEntry.Depends = append(mempoolEntry.Depends, txIn.PreviousOutPoint.Hash.String())
+		}
+	}
+	mempoolEntry.Replaceable = signalsRBF(entry.tx)
+	for op, child := range chain.spentBy {
+		if op.Hash == hash {
+			mempoolEntry.SpentBy = append(mempoolEntry.SpentBy, child.String())
+		}
+	}
+	return mempoolEntry
+}
+
+// verbose returns the decoded transaction. The block fields are set if the height is not negative.
+func (chain *Chain) verbose(tx *wire.MsgTx, height int) btcrpcclient.RawTransactionVerbose {
+	buf := new(bytes.Buffer)
+	tx.Serialize(buf)
+	verbose := btcrpcclient.RawTransactionVerbose{
+		Hex:      hex.EncodeToString(buf.Bytes()),
+		TxID:     tx.TxHash().String(),
+		Hash:     tx.WitnessHash().String(),
+		Size:     int64(tx.SerializeSize()),
+		VSize:    vsize(tx),
+		Weight:   weight(tx),
+		Version:  tx.Version,
+		LockTime: tx.LockTime,
+		Vin:      make([]btcrpcclient.Vin, len(tx.TxIn)),
+		Vout:     make([]btcrpcclient.Vout, len(tx.TxOut)),
+	}
+	for i, txIn := range tx.TxIn {
+		if txIn.PreviousOutPoint.Index == wire.MaxPrevOutIndex && txIn.PreviousOutPoint.Hash == (chainhash.Hash{}) {
+			verbose.Vin[i] = btcrpcclient.Vin{
+				Coinbase: hex.EncodeToString(txIn.SignatureScript),
+				Sequence: txIn.Sequence,
+			}
+			continue
+		}
+		asm, _ := txscript.DisasmString(txIn.SignatureScript)
+		verbose.Vin[i] = btcrpcclient.Vin{
+			TxID:      txIn.PreviousOutPoint.Hash.String(),
+			Vout:      txIn.PreviousOutPoint.Index,
+			ScriptSig: btcrpcclient.ScriptSig{Asm: asm, Hex: hex.EncodeToString(txIn.SignatureScript)},
+			Sequence:  txIn.Sequence,
+		}
+		for _, witness := range txIn.Witness {
+			verbose.Vin[i].TxInWitness = append(verbose.Vin[i].TxInWitness, hex.EncodeToString(witness))
+		}
+	}
+	for i, txOut := range tx.TxOut {
+		verbose.Vout[i] = btcrpcclient.Vout{
+			Value:        btcutil.Amount(txOut.Value).ToBTC(),
+			N:            uint32(i),
+			ScriptPubKey: chain.scriptPubKey(txOut.PkScript),
+		}
+	}
+	if height >= 0 {
+		block := chain.blocks[height]
+		verbose.BlockHash = block.hash.String()
+		verbose.Confirmations = uint32(chain.confirmations(height))
+		verbose.Time = block.header.Timestamp.Unix()
+		verbose.BlockTime = block.header.Timestamp.Unix()
+	}
+	return verbose
+}
+
+func (chain *Chain) scriptPubKey(script []byte) btcrpcclient.ScriptPubKey {
+	asm, _ := txscript.DisasmString(script)
+	class, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(script, chain.network.Params())
+	scriptPubKey := btcrpcclient.ScriptPubKey{
+		Asm:     asm,
+		Hex:     hex.EncodeToString(script),
+		Type:    class.String(),
+		ReqSigs: int64(reqSigs),
+	}
+	for _, addr := range addrs {
+		scriptPubKey.Addresses = append(scriptPubKey.Addresses, addr.EncodeAddress())
+	}
+	if len(addrs) == 1 {
+		scriptPubKey.Address = addrs[0].EncodeAddress()
+	}
+	return scriptPubKey
+}
+
+// nonceHash and nonceScript make the funding and coinbase transactions unique.
+func (chain *Chain) nonceHash() *chainhash.Hash {
+	hash := chainhash.Hash(sha256.Sum256(chain.nonceScript()))
+	return &hash
+}
+
+func (chain *Chain) nonceScript() []byte {
+	script := make([]byte, 8)
+	binary.LittleEndian.PutUint64(script, chain.nonce)
+	return script
+}
+
+func deserialize(data []byte) (*wire.MsgTx, error) {
+	tx := new(wire.MsgTx)
+	if err := tx.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, &types.JSONError{Code: codeDeserializationError, Message: "TX decode failed"}
+	}
+	return tx, nil
+}
+
+var errNotInMempool = &types.JSONError{Code: codeInvalidAddressOrKey, Message: "Transaction not in mempool"}
+
+// signalsRBF returns whether the transaction signals that it can be replaced using the sequence of its inputs (BIP125).
+func signalsRBF(tx *wire.MsgTx) bool {
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence < wire.MaxTxInSequenceNum-1 {
+			return true
+		}
+	}
+	return false
+}
+
+func invalidAddress(address string) error {
+	return &types.JSONError{Code: codeInvalidAddressOrKey, Message: fmt.Sprintf("Invalid address: %s", address)}
+}
+
+func weight(tx *wire.MsgTx) int64 {
+	return int64(tx.SerializeSizeStripped()*3 + tx.SerializeSize())
+}
+
+func vsize(tx *wire.MsgTx) int64 {
+	return (weight(tx) + 3) / 4
+}
+
+func merkleRoot(txs []*wire.MsgTx) chainhash.Hash {
+	hashes := make([]chainhash.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.TxHash()
+	}
+	for len(hashes) > 1 {
+		if len(hashes)%2 == 1 {
+			hashes = append(hashes, hashes[len(hashes)-1])
+		}
+		next := make([]chainhash.Hash, len(hashes)/2)
+		for i := range next {
+			next[i] = chainhash.DoubleHashH(append(hashes[2*i][:], hashes[2*i+1][:]...))
+		}
+		hashes = next
+	}
+	return hashes[0]
+}
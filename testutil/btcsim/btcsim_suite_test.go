@@ -0,0 +1,13 @@
+package btcsim_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBtcsim(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Btcsim Suite")
+}
@@ -0,0 +1,293 @@
+package btcsim_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/testutil/btcsim"
+
+	"github.com/renproject/kv"
+	"github.com/renproject/mercury/api"
+	"github.com/renproject/mercury/cache"
+	"github.com/renproject/mercury/proxy"
+	"github.com/renproject/mercury/rpc"
+	"github.com/renproject/mercury/rpcclient"
+	"github.com/renproject/mercury/sdk/client/btcclient"
+	"github.com/renproject/mercury/stat"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Simulated chain", func() {
+	logger := logrus.StandardLogger()
+	ctx := context.Background()
+
+	// newAccount returns a new key and its address on the network.
+	newAccount := func(network btctypes.Network, segWit bool) (*ecdsa.PrivateKey, btctypes.Address) {
+		key, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		var address btctypes.Address
+		if segWit {
+			address, err = btctypes.SegWitAddressFromPubKey(key.PublicKey, network)
+		} else {
+			address, err = btctypes.AddressFromPubKey(key.PublicKey, network)
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return key, address
+	}
+
+	// transfer builds, signs and submits a transaction which spends every UTXO of the key.
+	transfer := func(client btcclient.Client, key *ecdsa.PrivateKey, from, to btctypes.Address, amount btctypes.Amount) types.TxHash {
+		utxos, err := client.UTXOsFromAddress(ctx, from)
+		Expect(err).NotTo(HaveOccurred())
+		tx, err := client.BuildUnsignedTxAtFeeRate(utxos, btctypes.Recipients{btctypes.NewRecipient(to, amount)}, from, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tx.Sign(key)).To(Succeed())
+		txHash, err := client.SubmitSignedTx(ctx, tx)
+		Expect(err).NotTo(HaveOccurred())
+		return txHash
+	}
+
+	Context("when funding addresses", func() {
+		It("should only list the outputs once they have been mined", func() {
+			chain, err := New(btctypes.BtcRegtest)
+			Expect(err).NotTo(HaveOccurred())
+			_, address := newAccount(btctypes.BtcRegtest, false)
+
+			op, err := chain.Fund(address, 100000)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chain.Mempool()).To(Equal([]types.TxHash{op.TxHash()}))
+			utxos, err := chain.ListUnspent(ctx, 1, 999999, []btctypes.Address{address})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(utxos).To(BeEmpty())
+
+			hashes := chain.Mine(3)
+			Expect(hashes).To(HaveLen(3))
+			Expect(chain.Height()).To(Equal(3))
+			Expect(chain.Mempool()).To(BeEmpty())
+
+			utxos, err = chain.ListUnspent(ctx, 1, 999999, []btctypes.Address{address})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(utxos).To(HaveLen(1))
+			Expect(utxos[0].TxID).To(Equal(string(op.TxHash())))
+			Expect(utxos[0].Amount).To(Equal(0.001))
+			Expect(utxos[0].Confirmations).To(Equal(int64(3)))
+
+			tx, err := chain.GetRawTransactionVerbose(ctx, op.TxHash())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tx.BlockHash).To(Equal(hashes[0]))
+			Expect(tx.Vout[0].ScriptPubKey.Address).To(Equal(address.EncodeAddress()))
+			block, err := chain.GetBlock(ctx, hashes[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(block.Tx).To(ContainElement(string(op.TxHash())))
+			Expect(block.NextBlockHash).To(Equal(hashes[1]))
+		})
+
+		It("should not support chains with a different transaction format", func() {
+			_, err := New(btctypes.ZecTestnet)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	for _, segWit := range []bool{false, true} {
+		segWit := segWit
+
+		Context("when transferring funds using the SDK", func() {
+			It("should validate the signatures and confirm the transaction", func() {
+				chain, err := New(btctypes.BtcRegtest)
+				Expect(err).NotTo(HaveOccurred())
+				server := NewServer(chain)
+				defer server.Close()
+				client := btcclient.NewCustomClient(logger, btctypes.BtcRegtest, server.URL)
+
+				key, from := newAccount(btctypes.BtcRegtest, segWit)
+				_, to := newAccount(btctypes.BtcRegtest, false)
+				_, err = chain.Fund(from, 100000)
+				Expect(err).NotTo(HaveOccurred())
+				chain.Mine(1)
+
+				txHash := transfer(client, key, from, to, 50000)
+				Expect(chain.Mempool()).To(Equal([]types.TxHash{txHash}))
+				entry, err := chain.GetMempoolEntry(ctx, txHash)
+				Expect(err).NotTo(HaveOccurred())
+				// The estimated size is never smaller than the actual size, and at most two bytes larger for each
+				// signature.
+				fee := btctypes.AmountFromFloat64(entry.Fees.Base)
+				Expect(fee).To(BeNumerically(">=", 10*entry.VSize))
+				Expect(fee).To(BeNumerically("<=", 10*(entry.VSize+2)))
+
+				confs, err := client.Confirmations(ctx, txHash)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(confs).To(Equal(uint64(0)))
+				chain.Mine(2)
+				confs, err = client.Confirmations(ctx, txHash)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(confs).To(Equal(uint64(2)))
+
+				utxo, err := client.UTXO(ctx, btctypes.NewOutPoint(txHash, 0))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(utxo.Amount()).To(Equal(btctypes.Amount(50000)))
+				utxos, err := client.UTXOsFromAddress(ctx, from)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(utxos.Sum()).To(Equal(50000 - fee))
+			})
+		})
+	}
+
+	Context("when submitting invalid transactions", func() {
+		It("should return the errors of bitcoind", func() {
+			chain, err := New(btctypes.BtcRegtest)
+			Expect(err).NotTo(HaveOccurred())
+			server := NewServer(chain)
+			defer server.Close()
+			client := btcclient.NewCustomClient(logger, btctypes.BtcRegtest, server.URL)
+
+			key, from := newAccount(btctypes.BtcRegtest, false)
+			otherKey, _ := newAccount(btctypes.BtcRegtest, false)
+			_, err = chain.Fund(from, 100000)
+			Expect(err).NotTo(HaveOccurred())
+			chain.Mine(1)
+			utxos, err := client.UTXOsFromAddress(ctx, from)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Transactions signed by the wrong key fail the script checks. The SDK verifies transactions before
+			// submitting them, so the transaction is sent to the chain directly.
+			tx, err := client.BuildUnsignedTxAtFeeRate(utxos, nil, from, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tx.Sign(otherKey)).To(Succeed())
+			_, err = chain.SendRawTransaction(ctx, tx)
+			Expect(errors.Is(err, rpcclient.ErrVerifyRejected)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("mandatory-script-verify-flag-failed"))
+
+			tx, err = client.BuildUnsignedTxAtFeeRate(utxos, nil, from, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tx.Sign(key)).To(Succeed())
+			_, err = client.SubmitSignedTx(ctx, tx)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Inputs which have been spent in the mempool conflict with the transaction.
+			conflict, err := client.BuildUnsignedTxAtFeeRate(utxos, nil, from, 20)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflict.Sign(key)).To(Succeed())
+			_, err = client.SubmitSignedTx(ctx, conflict)
+			Expect(errors.Is(err, rpcclient.ErrVerifyRejected)).To(BeTrue())
+
+			// Transactions which have been mined cannot be submitted again, and their inputs are missing.
+			chain.Mine(1)
+			_, err = client.SubmitSignedTx(ctx, tx)
+			Expect(errors.Is(err, rpcclient.ErrAlreadyInChain)).To(BeTrue())
+			_, err = client.SubmitSignedTx(ctx, conflict)
+			Expect(errors.Is(err, rpcclient.ErrMissingInputs)).To(BeTrue())
+
+			results, err := chain.TestMempoolAccept(ctx, []btctypes.BtcTx{conflict})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Allowed).To(BeFalse())
+			Expect(results[0].RejectReason).To(Equal("bad-txns-inputs-missingorspent"))
+		})
+	})
+
+	Context("when replacing transactions", func() {
+		It("should only accept replacements which pay a higher fee (BIP125)", func() {
+			chain, err := New(btctypes.BtcRegtest)
+			Expect(err).NotTo(HaveOccurred())
+			server := NewServer(chain)
+			defer server.Close()
+			client := btcclient.NewCustomClient(logger, btctypes.BtcRegtest, server.URL)
+
+			key, from := newAccount(btctypes.BtcRegtest, false)
+			toKey, to := newAccount(btctypes.BtcRegtest, false)
+			_, err = chain.Fund(from, 100000)
+			Expect(err).NotTo(HaveOccurred())
+			chain.Mine(1)
+			utxos, err := client.UTXOsFromAddress(ctx, from)
+			Expect(err).NotTo(HaveOccurred())
+
+			// build returns a signed replaceable transaction paying the amount at the fee rate.
+			build := func(amount btctypes.Amount, feeRate btctypes.FeeRate) btctypes.BtcTx {
+				tx, err := client.BuildUnsignedTxWithOptions(utxos, btctypes.Recipients{btctypes.NewRecipient(to, amount)}, from, feeRate, btctypes.TxOptions{RBF: true})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tx.Sign(key)).To(Succeed())
+				return tx
+			}
+
+			tx := build(50000, 2)
+			_, err = client.SubmitSignedTx(ctx, tx)
+			Expect(err).NotTo(HaveOccurred())
+
+			// The child spending the output of the transaction is replaced along with it, so the replacement must pay
+			// for both of them.
+			child, err := client.BuildUnsignedTxAtFeeRate(btctypes.UTXOs{tx.OutputUTXO(to)}, nil, to, 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(child.Sign(toKey)).To(Succeed())
+			_, err = client.SubmitSignedTx(ctx, child)
+			Expect(err).NotTo(HaveOccurred())
+			parentEntry, err := chain.GetMempoolEntry(ctx, tx.Hash())
+			Expect(err).NotTo(HaveOccurred())
+			childEntry, err := chain.GetMempoolEntry(ctx, child.Hash())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parentEntry.DescendantCount).To(Equal(int64(2)))
+			Expect(parentEntry.DescendantSize).To(Equal(parentEntry.VSize + childEntry.VSize))
+			Expect(childEntry.AncestorCount).To(Equal(int64(2)))
+			Expect(childEntry.AncestorSize).To(Equal(parentEntry.DescendantSize))
+			Expect(childEntry.Fees.Ancestor).To(Equal(parentEntry.Fees.Descendant))
+
+			_, err = client.SubmitSignedTx(ctx, build(40000, 1))
+			Expect(errors.Is(err, rpcclient.ErrVerifyRejected)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("new feerate is not higher"))
+			_, err = client.SubmitSignedTx(ctx, build(40000, 4))
+			Expect(errors.Is(err, rpcclient.ErrVerifyRejected)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("not enough additional fees"))
+			Expect(chain.Mempool()).To(Equal([]types.TxHash{tx.Hash(), child.Hash()}))
+
+			replacement := build(40000, 6)
+			_, err = client.SubmitSignedTx(ctx, replacement)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chain.Mempool()).To(Equal([]types.TxHash{replacement.Hash()}))
+			_, err = chain.GetMempoolEntry(ctx, child.Hash())
+			Expect(err).To(HaveOccurred())
+			utxos, err = client.UTXOsFromAddress(ctx, to)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(utxos).To(HaveLen(1))
+			Expect(utxos[0].TxHash()).To(Equal(replacement.Hash()))
+			Expect(utxos[0].Amount()).To(Equal(btctypes.Amount(40000)))
+		})
+	})
+
+	Context("when used as the upstream node of Mercury", func() {
+		It("should serve the requests of the SDK", func() {
+			chain, err := New(btctypes.BtcRegtest)
+			Expect(err).NotTo(HaveOccurred())
+			node := NewServer(chain)
+			defer node.Close()
+
+			btcCache := cache.New(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "btcsim"), logger)
+			btcAPI := api.NewApi(btctypes.BtcRegtest, proxy.NewProxy(rpc.NewClient(node.URL, "", "")), btcCache, logger)
+			s := stat.New()
+			r := mux.NewRouter()
+			btcAPI.AddHandler(r, &s)
+			mercury := httptest.NewServer(r)
+			defer mercury.Close()
+			client := btcclient.NewCustomClient(logger, btctypes.BtcRegtest, mercury.URL)
+
+			key, from := newAccount(btctypes.BtcRegtest, false)
+			_, to := newAccount(btctypes.BtcRegtest, false)
+			_, err = chain.Fund(from, 100000)
+			Expect(err).NotTo(HaveOccurred())
+			chain.Mine(1)
+
+			txHash := transfer(client, key, from, to, 50000)
+			chain.Mine(1)
+			confs, err := client.Confirmations(ctx, txHash)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(confs).To(Equal(uint64(1)))
+		})
+	})
+})
@@ -0,0 +1,258 @@
+package btcsim
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/renproject/mercury/rpcclient"
+	"github.com/renproject/mercury/types"
+	"github.com/renproject/mercury/types/btctypes"
+)
+
+type request struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	ID     interface{}       `json:"id"`
+}
+
+type response struct {
+	Result interface{}      `json:"result"`
+	Error  *types.JSONError `json:"error"`
+	ID     interface{}      `json:"id"`
+}
+
+// NewServer starts a server which serves the bitcoind JSON-RPC API of the chain. The server ignores the path of
+// requests so that it can be used as the base URL of the SDK. It must be closed by the caller.
+func NewServer(chain *Chain) *httptest.Server {
+	return httptest.NewServer(chain)
+}
+
+// ServeHTTP implements the `http.Handler` interface. Single and batch requests are supported.
+func (chain *Chain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot read request: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		reqs := []request{}
+		if err := json.Unmarshal(data, &reqs); err != nil {
+			http.Error(w, fmt.Sprintf("cannot decode request: %v", err), http.StatusBadRequest)
+			return
+		}
+		resps := make([]response, len(reqs))
+		for i, req := range reqs {
+			resps[i] = chain.handle(req)
+		}
+		json.NewEncoder(w).Encode(resps)
+		return
+	}
+
+	req := request{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		http.Error(w, fmt.Sprintf("cannot decode request: %v", err), http.StatusBadRequest)
+		return
+	}
+	resp := chain.handle(req)
+
+	// Like bitcoind, errors are returned with a non-200 status code.
+	if resp.Error != nil {
+		if resp.Error.Code == rpcclient.CodeMethodNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (chain *Chain) handle(req request) response {
+	result, err := chain.call(req.Method, req.Params)
+	if err != nil {
+		jsonErr, ok := err.(*types.JSONError)
+		if !ok {
+			jsonErr = &types.JSONError{Code: rpcclient.CodeServerError, Message: err.Error()}
+		}
+		return response{Error: jsonErr, ID: req.ID}
+	}
+	return response{Result: result, ID: req.ID}
+}
+
+func (chain *Chain) call(method string, params []json.RawMessage) (interface{}, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	switch method {
+	case "getblockcount":
+		return chain.height(), nil
+	case "getbestblockhash":
+		return chain.tip().hash.String(), nil
+	case "getblockheader":
+		var blockHash string
+		if err := decodeParams(params, &blockHash); err != nil {
+			return nil, err
+		}
+		height, err := chain.blockHeight(blockHash)
+		if err != nil {
+			return nil, err
+		}
+		return chain.header(height), nil
+	case "getblock":
+		var blockHash string
+		if err := decodeParams(params, &blockHash); err != nil {
+			return nil, err
+		}
+		height, err := chain.blockHeight(blockHash)
+		if err != nil {
+			return nil, err
+		}
+		return chain.block(height), nil
+	case "getrawtransaction":
+		var txid types.TxHash
+		var verbose json.RawMessage
+		if err := decodeParams(params, &txid, &verbose); err != nil {
+			return nil, err
+		}
+		entry, err := chain.entry(txid)
+		if err != nil {
+			return nil, err
+		}
+		// The verbosity can be given as a boolean or an integer.
+		switch string(verbose) {
+		case "", "0", "false":
+			return chain.verbose(entry.tx, entry.height).Hex, nil
+		}
+		return chain.verbose(entry.tx, entry.height), nil
+	case "gettxout":
+		var txid types.TxHash
+		var index uint32
+		if err := decodeParams(params, &txid, &index); err != nil {
+			return nil, err
+		}
+		hash, err := chainhash.NewHashFromStr(string(txid))
+		if err != nil {
+			return nil, &types.JSONError{Code: codeInvalidParameter, Message: fmt.Sprintf("invalid txid: %s", txid)}
+		}
+		// Spent and unknown outputs are returned as null.
+		txOut, ok := chain.utxos[wire.OutPoint{Hash: *hash, Index: index}]
+		if !ok {
+			return nil, nil
+		}
+		return chain.txOut(*hash, txOut), nil
+	case "listunspent":
+		minConf, maxConf := int64(1), int64(9999999)
+		addresses := []string{}
+		if err := decodeParams(params, &minConf, &maxConf, &addresses); err != nil {
+			return nil, err
+		}
+		scripts := make([][]byte, len(addresses))
+		for i, address := range addresses {
+			addr, err := btctypes.AddressFromBase58(address, chain.network)
+			if err != nil {
+				return nil, invalidAddress(address)
+			}
+			script, err := btctypes.PayToAddrScript(addr, chain.network)
+			if err != nil {
+				return nil, invalidAddress(address)
+			}
+			scripts[i] = script
+		}
+		return chain.listUnspent(minConf, maxConf, scripts), nil
+	case "sendrawtransaction":
+		var rawTx string
+		if err := decodeParams(params, &rawTx); err != nil {
+			return nil, err
+		}
+		tx, err := decodeTx(rawTx)
+		if err != nil {
+			return nil, err
+		}
+		return chain.accept(tx)
+	case "testmempoolaccept":
+		rawTxs := []string{}
+		if err := decodeParams(params, &rawTxs); err != nil {
+			return nil, err
+		}
+		txs := make([]*wire.MsgTx, len(rawTxs))
+		for i, rawTx := range rawTxs {
+			tx, err := decodeTx(rawTx)
+			if err != nil {
+				return nil, err
+			}
+			txs[i] = tx
+		}
+		return chain.testMempoolAccept(txs), nil
+	case "decoderawtransaction":
+		var rawTx string
+		if err := decodeParams(params, &rawTx); err != nil {
+			return nil, err
+		}
+		tx, err := decodeTx(rawTx)
+		if err != nil {
+			return nil, err
+		}
+		verbose := chain.verbose(tx, -1)
+		verbose.Hex = ""
+		return verbose, nil
+	case "estimatesmartfee":
+		return chain.estimateSmartFee(), nil
+	case "getmempoolentry":
+		var txid types.TxHash
+		if err := decodeParams(params, &txid); err != nil {
+			return nil, err
+		}
+		entry, err := chain.entry(txid)
+		if err != nil || entry.height >= 0 {
+			return nil, errNotInMempool
+		}
+		return chain.mempoolEntry(entry), nil
+	case "generate", "generatetoaddress":
+		var n int
+		if err := decodeParams(params, &n); err != nil {
+			return nil, err
+		}
+		hashes := make([]string, n)
+		for i := range hashes {
+			hashes[i] = chain.mine().String()
+		}
+		return hashes, nil
+	default:
+		return nil, &types.JSONError{Code: rpcclient.CodeMethodNotFound, Message: "Method not found"}
+	}
+}
+
+// decodeParams decodes the positional parameters into the given values. Missing parameters are left unchanged, and
+// parameters which are not supported by the chain (e.g. the verbosity of blocks) are ignored.
+func decodeParams(params []json.RawMessage, values ...interface{}) error {
+	for i, param := range params {
+		if i >= len(values) {
+			break
+		}
+		if raw, ok := values[i].(*json.RawMessage); ok {
+			*raw = param
+			continue
+		}
+		if err := json.Unmarshal(param, values[i]); err != nil {
+			return &types.JSONError{Code: rpcclient.CodeInvalidParams, Message: fmt.Sprintf("invalid parameter %d: %v", i, err)}
+		}
+	}
+	return nil
+}
+
+func decodeTx(rawTx string) (*wire.MsgTx, error) {
+	data, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return nil, &types.JSONError{Code: codeDeserializationError, Message: "TX decode failed"}
+	}
+	return deserialize(data)
+}
@@ -0,0 +1,144 @@
+// Package ethsim serves the JSON-RPC API of a simulated Ethereum backend (see `testutil.CreateSimulatedNetwork`) over
+// HTTP, so that the SDK and the Mercury server can be tested without a node or Ganache.
+package ethsim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrAlreadyKnown is returned when a transaction is submitted more than once.
+var ErrAlreadyKnown = errors.New("already known")
+
+// Server is a JSON-RPC server of a simulated backend. When automine is enabled, every transaction is mined in its own
+// block as soon as it is submitted. Otherwise, transactions stay pending until `Commit` is called (or `evm_mine` is
+// requested).
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	backend  *backends.SimulatedBackend
+	chainID  *big.Int
+	automine bool
+}
+
+// NewServer starts a server for the backend. The server must be closed by the caller, which does not close the
+// backend.
+func NewServer(backend *backends.SimulatedBackend, automine bool) (*Server, error) {
+	server := &Server{
+		backend:  backend,
+		chainID:  backend.Blockchain().Config().ChainID,
+		automine: automine,
+	}
+
+	handler := rpc.NewServer()
+	services := map[string]interface{}{
+		"eth": &ethService{server: server},
+		"net": &netService{server: server},
+		"evm": &evmService{server: server},
+	}
+	for name, service := range services {
+		if err := handler.RegisterName(name, service); err != nil {
+			return nil, fmt.Errorf("cannot register %s service: %v", name, err)
+		}
+	}
+	server.Server = httptest.NewServer(handler)
+	return server, nil
+}
+
+// Backend returns the simulated backend of the server.
+func (server *Server) Backend() *backends.SimulatedBackend {
+	return server.backend
+}
+
+// ChainID returns the chain ID of the backend, which must be used to sign transactions.
+func (server *Server) ChainID() *big.Int {
+	return new(big.Int).Set(server.chainID)
+}
+
+// SetAutomine enables or disables automine. Pending transactions are not mined when automine is enabled.
+func (server *Server) SetAutomine(automine bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.automine = automine
+}
+
+// Commit mines the pending transactions in a new block.
+func (server *Server) Commit() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.backend.Commit()
+}
+
+// SendTransaction validates the transaction and adds it to the pending block. The checks of the transaction pool of
+// geth are used, as the backend panics when given an invalid transaction.
+func (server *Server) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if _, _, err := server.backend.TransactionByHash(ctx, tx.Hash()); err == nil {
+		return ErrAlreadyKnown
+	}
+	from, err := types.Sender(types.NewEIP155Signer(server.chainID), tx)
+	if err != nil {
+		return fmt.Errorf("invalid sender: %v", err)
+	}
+	nonce, err := server.backend.PendingNonceAt(ctx, from)
+	if err != nil {
+		return err
+	}
+	switch {
+	case tx.Nonce() < nonce:
+		return core.ErrNonceTooLow
+	case tx.Nonce() > nonce:
+		// The backend does not have a queue for future transactions.
+		return core.ErrNonceTooHigh
+	}
+	if tx.Gas() > server.backend.Blockchain().CurrentBlock().GasLimit() {
+		return core.ErrGasLimit
+	}
+	intrinsicGas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, true)
+	if err != nil {
+		return err
+	}
+	if tx.Gas() < intrinsicGas {
+		return core.ErrIntrinsicGas
+	}
+	balance, err := server.backend.BalanceAt(ctx, from, nil)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(tx.Cost()) < 0 {
+		return core.ErrInsufficientFunds
+	}
+
+	if err := sendTransaction(ctx, server.backend, tx); err != nil {
+		return err
+	}
+	if server.automine {
+		server.backend.Commit()
+	}
+	return nil
+}
+
+// sendTransaction returns the panics of the backend as errors. This can happen if pending transactions have spent the
+// balance of the sender.
+func sendTransaction(ctx context.Context, backend *backends.SimulatedBackend, tx *types.Transaction) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return backend.SendTransaction(ctx, tx)
+}
@@ -0,0 +1,13 @@
+package ethsim_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEthsim(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Ethsim Suite")
+}
@@ -0,0 +1,198 @@
+package ethsim_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/testutil/ethsim"
+
+	"github.com/renproject/kv"
+	"github.com/renproject/mercury/api"
+	"github.com/renproject/mercury/cache"
+	"github.com/renproject/mercury/proxy"
+	"github.com/renproject/mercury/rpc"
+	"github.com/renproject/mercury/sdk/account/ethaccount"
+	"github.com/renproject/mercury/sdk/client/ethclient"
+	"github.com/renproject/mercury/sdk/contract/erc20"
+	"github.com/renproject/mercury/stat"
+	"github.com/renproject/mercury/types/ethtypes"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Simulated Ethereum server", func() {
+	logger := logrus.StandardLogger()
+	ctx := context.Background()
+
+	// decimalsContract returns 18 for every call, which is enough to call `decimals` on an ERC20 contract.
+	decimalsContract := common.HexToAddress("0x00000000000000000000000000000000000000e2")
+	decimalsCode := common.FromHex("0x601260005260206000f3")
+
+	// newServer returns a server with a funded account and the decimals contract.
+	newServer := func(automine bool) (*Server, *ecdsa.PrivateKey) {
+		key, err := crypto.GenerateKey()
+		Expect(err).NotTo(HaveOccurred())
+		backend := backends.NewSimulatedBackend(core.GenesisAlloc{
+			crypto.PubkeyToAddress(key.PublicKey): {Balance: ethtypes.Ether(10).ToBig()},
+			decimalsContract:                      {Balance: big.NewInt(0), Code: decimalsCode},
+		}, 10000000)
+		server, err := NewServer(backend, automine)
+		Expect(err).NotTo(HaveOccurred())
+		return server, key
+	}
+
+	// newAccounts returns an account of the funded key and a random account.
+	newAccounts := func(client ethclient.Client, key *ecdsa.PrivateKey) (ethaccount.Account, ethaccount.Account) {
+		sender, err := ethaccount.NewAccountFromPrivateKey(client, key)
+		Expect(err).NotTo(HaveOccurred())
+		recipient, err := ethaccount.RandomAccount(client)
+		Expect(err).NotTo(HaveOccurred())
+		return sender, recipient
+	}
+
+	Context("when automine is enabled", func() {
+		It("should mine transactions as soon as they are submitted", func() {
+			server, key := newServer(true)
+			defer server.Close()
+			client, err := ethclient.NewCustomClient(logger, server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			sender, recipient := newAccounts(client, key)
+
+			txHash, err := sender.Transfer(ctx, recipient.Address(), ethtypes.Ether(1), ethtypes.Gwei(1))
+			Expect(err).NotTo(HaveOccurred())
+
+			balance, err := recipient.Balance(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(balance.Eq(ethtypes.Ether(1))).To(BeTrue())
+			blockNumber, err := client.BlockNumber(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(blockNumber.Uint64()).To(Equal(uint64(1)))
+			confs, err := client.Confirmations(ctx, txHash)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(confs.Uint64()).To(Equal(uint64(0)))
+
+			tx, pending, err := client.EthClient().TransactionByHash(ctx, common.Hash(txHash))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(BeFalse())
+			Expect(tx.Value()).To(Equal(ethtypes.Ether(1).ToBig()))
+			block, err := client.EthClient().BlockByNumber(ctx, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(block.Transactions()).To(HaveLen(1))
+		})
+
+		It("should reject transactions which have already been submitted", func() {
+			server, key := newServer(true)
+			defer server.Close()
+			client, err := ethclient.NewCustomClient(logger, server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			sender, recipient := newAccounts(client, key)
+
+			tx, err := sender.BuildUnsignedTx(ctx, recipient.Address(), ethtypes.Ether(1), 21000, ethtypes.Gwei(1), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sender.SignUnsignedTx(ctx, &tx)).To(Succeed())
+			_, err = client.PublishSignedTx(ctx, tx)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = client.PublishSignedTx(ctx, tx)
+			Expect(err).To(MatchError(ErrAlreadyKnown.Error()))
+
+			replacement := ethtypes.NewUnsignedTx(server.ChainID(), 0, recipient.Address(), ethtypes.Ether(2), 21000, ethtypes.Gwei(1), nil)
+			Expect(sender.SignUnsignedTx(ctx, &replacement)).To(Succeed())
+			_, err = client.PublishSignedTx(ctx, replacement)
+			Expect(err).To(MatchError(core.ErrNonceTooLow.Error()))
+
+			expensive, err := sender.BuildUnsignedTx(ctx, recipient.Address(), ethtypes.Ether(100), 21000, ethtypes.Gwei(1), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sender.SignUnsignedTx(ctx, &expensive)).To(Succeed())
+			_, err = client.PublishSignedTx(ctx, expensive)
+			Expect(err).To(MatchError(core.ErrInsufficientFunds.Error()))
+		})
+	})
+
+	Context("when automine is disabled", func() {
+		It("should keep transactions pending until they are committed", func() {
+			server, key := newServer(false)
+			defer server.Close()
+			client, err := ethclient.NewCustomClient(logger, server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			sender, recipient := newAccounts(client, key)
+
+			_, err = sender.Transfer(ctx, recipient.Address(), ethtypes.Ether(1), ethtypes.Gwei(1))
+			Expect(err).NotTo(HaveOccurred())
+			txHash, err := sender.Transfer(ctx, recipient.Address(), ethtypes.Ether(1), ethtypes.Gwei(1))
+			Expect(err).NotTo(HaveOccurred())
+
+			balance, err := recipient.Balance(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(balance.Eq(ethtypes.Wei(0))).To(BeTrue())
+			_, pending, err := client.EthClient().TransactionByHash(ctx, common.Hash(txHash))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(BeTrue())
+			_, err = client.Confirmations(ctx, txHash)
+			Expect(err).To(HaveOccurred())
+
+			server.Commit()
+			balance, err = recipient.Balance(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(balance.Eq(ethtypes.Ether(2))).To(BeTrue())
+
+			// Blocks can also be mined using the method of Ganache.
+			rpcClient, err := gethrpc.Dial(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			defer rpcClient.Close()
+			Expect(rpcClient.CallContext(ctx, nil, "evm_mine")).To(Succeed())
+			confs, err := client.Confirmations(ctx, txHash)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(confs.Uint64()).To(Equal(uint64(1)))
+		})
+	})
+
+	Context("when calling contracts", func() {
+		It("should return the result of the call", func() {
+			server, _ := newServer(true)
+			defer server.Close()
+			client, err := ethclient.NewCustomClient(logger, server.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			token, err := erc20.New(client, ethtypes.Address(decimalsContract))
+			Expect(err).NotTo(HaveOccurred())
+			decimals, err := token.Decimals(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decimals).To(Equal(uint8(18)))
+		})
+	})
+
+	Context("when used as the upstream node of Mercury", func() {
+		It("should serve the requests of the SDK", func() {
+			server, key := newServer(true)
+			defer server.Close()
+
+			ethCache := cache.New(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "ethsim"), logger)
+			ethAPI := api.NewApi(ethtypes.Ganache, proxy.NewProxy(rpc.NewClient(server.URL, "", "")), ethCache, logger)
+			s := stat.New()
+			r := mux.NewRouter()
+			ethAPI.AddHandler(r, &s)
+			mercury := httptest.NewServer(r)
+			defer mercury.Close()
+
+			client, err := ethclient.NewCustomClient(logger, fmt.Sprintf("%s/eth/%s", mercury.URL, ethtypes.Ganache))
+			Expect(err).NotTo(HaveOccurred())
+			sender, recipient := newAccounts(client, key)
+
+			_, err = sender.Transfer(ctx, recipient.Address(), ethtypes.Ether(1), ethtypes.Gwei(1))
+			Expect(err).NotTo(HaveOccurred())
+			balance, err := recipient.Balance(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(balance.Eq(ethtypes.Ether(1))).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,245 @@
+package ethsim
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ethService implements the methods of the `eth` namespace which are used by `ethclient`. The simulated backend only
+// has the state of the latest and pending blocks, so requests for other blocks return an error.
+type ethService struct {
+	server *Server
+}
+
+// CallArgs are the arguments of `eth_call` and `eth_estimateGas`. The type is exported as the RPC server only accepts
+// exported argument types.
+type CallArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Data     hexutil.Bytes   `json:"data"`
+	Input    hexutil.Bytes   `json:"input"`
+}
+
+func (args CallArgs) msg() ethereum.CallMsg {
+	data := args.Data
+	if len(args.Input) > 0 {
+		data = args.Input
+	}
+	return ethereum.CallMsg{
+		From:     args.From,
+		To:       args.To,
+		Gas:      uint64(args.Gas),
+		GasPrice: (*big.Int)(args.GasPrice),
+		Value:    (*big.Int)(args.Value),
+		Data:     data,
+	}
+}
+
+func (service *ethService) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(service.server.ChainID())
+}
+
+func (service *ethService) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(service.server.backend.Blockchain().CurrentBlock().NumberU64())
+}
+
+func (service *ethService) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	gasPrice, err := service.server.backend.SuggestGasPrice(ctx)
+	return (*hexutil.Big)(gasPrice), err
+}
+
+func (service *ethService) GetBalance(ctx context.Context, address common.Address, number rpc.BlockNumber) (*hexutil.Big, error) {
+	balance, err := service.server.backend.BalanceAt(ctx, address, blockNumber(number))
+	return (*hexutil.Big)(balance), err
+}
+
+func (service *ethService) GetTransactionCount(ctx context.Context, address common.Address, number rpc.BlockNumber) (hexutil.Uint64, error) {
+	if number == rpc.PendingBlockNumber {
+		nonce, err := service.server.backend.PendingNonceAt(ctx, address)
+		return hexutil.Uint64(nonce), err
+	}
+	nonce, err := service.server.backend.NonceAt(ctx, address, blockNumber(number))
+	return hexutil.Uint64(nonce), err
+}
+
+func (service *ethService) GetCode(ctx context.Context, address common.Address, number rpc.BlockNumber) (hexutil.Bytes, error) {
+	if number == rpc.PendingBlockNumber {
+		return service.server.backend.PendingCodeAt(ctx, address)
+	}
+	return service.server.backend.CodeAt(ctx, address, blockNumber(number))
+}
+
+func (service *ethService) Call(ctx context.Context, args CallArgs, number *rpc.BlockNumber) (hexutil.Bytes, error) {
+	if number != nil && *number == rpc.PendingBlockNumber {
+		return service.server.backend.PendingCallContract(ctx, args.msg())
+	}
+	var num *big.Int
+	if number != nil {
+		num = blockNumber(*number)
+	}
+	return service.server.backend.CallContract(ctx, args.msg(), num)
+}
+
+func (service *ethService) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
+	gas, err := service.server.backend.EstimateGas(ctx, args.msg())
+	return hexutil.Uint64(gas), err
+}
+
+func (service *ethService) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([]types.Log, error) {
+	logs, err := service.server.backend.FilterLogs(ctx, ethereum.FilterQuery(crit))
+	if logs == nil {
+		logs = []types.Log{}
+	}
+	return logs, err
+}
+
+func (service *ethService) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	blockchain := service.server.backend.Blockchain()
+	block := blockchain.CurrentBlock()
+	if number >= 0 {
+		block = blockchain.GetBlockByNumber(uint64(number))
+	}
+	return marshalBlock(block, fullTx, service.server.chainID)
+}
+
+func (service *ethService) GetBlockByHash(hash common.Hash, fullTx bool) (map[string]interface{}, error) {
+	return marshalBlock(service.server.backend.Blockchain().GetBlockByHash(hash), fullTx, service.server.chainID)
+}
+
+func (service *ethService) GetTransactionByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	tx, pending, err := service.server.backend.TransactionByHash(ctx, hash)
+	if err == ethereum.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return marshalTx(tx, nil, service.server.chainID)
+	}
+	receipt, err := service.server.backend.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return marshalTx(tx, receipt, service.server.chainID)
+}
+
+func (service *ethService) GetTransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	receipt, err := service.server.backend.TransactionReceipt(ctx, hash)
+	if receipt != nil && receipt.Logs == nil {
+		// The logs are required by the JSON decoder of receipts.
+		receipt.Logs = []*types.Log{}
+	}
+	return receipt, err
+}
+
+func (service *ethService) SendRawTransaction(ctx context.Context, data hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(data, tx); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), service.server.SendTransaction(ctx, tx)
+}
+
+// netService implements the `net` namespace.
+type netService struct {
+	server *Server
+}
+
+func (service *netService) Version() string {
+	return service.server.chainID.String()
+}
+
+// evmService implements the methods of Ganache which are used to control the chain.
+type evmService struct {
+	server *Server
+}
+
+// Mine mines the pending transactions in a new block.
+func (service *evmService) Mine() string {
+	service.server.Commit()
+	return "0x0"
+}
+
+// blockNumber returns the number of the block, or nil for the latest and pending blocks.
+func blockNumber(number rpc.BlockNumber) *big.Int {
+	if number < 0 {
+		return nil
+	}
+	return big.NewInt(int64(number))
+}
+
+// marshalBlock returns the JSON fields of the block, including the hashes of its transactions (or the transactions
+// themselves if fullTx is true).
+func marshalBlock(block *types.Block, fullTx bool, chainID *big.Int) (map[string]interface{}, error) {
+	if block == nil {
+		return nil, nil
+	}
+	fields, err := toFields(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	fields["size"] = hexutil.Uint64(block.Size())
+	fields["uncles"] = []common.Hash{}
+
+	txs := make([]interface{}, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		if !fullTx {
+			txs[i] = tx.Hash()
+			continue
+		}
+		receipt := &types.Receipt{BlockHash: block.Hash(), BlockNumber: block.Number(), TransactionIndex: uint(i)}
+		if txs[i], err = marshalTx(tx, receipt, chainID); err != nil {
+			return nil, err
+		}
+	}
+	fields["transactions"] = txs
+	return fields, nil
+}
+
+// marshalTx returns the JSON fields of the transaction. The block fields are taken from the receipt, which is nil for
+// pending transactions.
+func marshalTx(tx *types.Transaction, receipt *types.Receipt, chainID *big.Int) (map[string]interface{}, error) {
+	fields, err := toFields(tx)
+	if err != nil {
+		return nil, err
+	}
+	from, err := types.Sender(types.NewEIP155Signer(chainID), tx)
+	if err != nil {
+		return nil, err
+	}
+	fields["from"] = from
+	fields["blockHash"] = nil
+	fields["blockNumber"] = nil
+	fields["transactionIndex"] = nil
+	if receipt != nil {
+		fields["blockHash"] = receipt.BlockHash
+		fields["blockNumber"] = (*hexutil.Big)(receipt.BlockNumber)
+		fields["transactionIndex"] = hexutil.Uint64(receipt.TransactionIndex)
+	}
+	return fields, nil
+}
+
+func toFields(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
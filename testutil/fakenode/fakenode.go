@@ -0,0 +1,306 @@
+// Package fakenode implements a fake JSON-RPC node for testing clients of upstream nodes (e.g. `proxy.Proxy` and
+// `cache.Cache`). The node responds to each method with a scripted response, and can be told to misbehave: by adding
+// latency, failing a fraction of requests, responding to a burst of requests with 429 or 5xx status codes, hanging, or
+// responding with malformed JSON. Random failures use a seeded source, so tests using the node are deterministic.
+package fakenode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/renproject/mercury/types"
+)
+
+// Fault is a way in which the node fails to respond to a request.
+type Fault int
+
+const (
+	// TooManyRequests responds with a 429 status code, as a rate limited node would.
+	TooManyRequests Fault = iota + 1
+	// ServerError responds with a 500 status code and a JSON-RPC error.
+	ServerError
+	// BadGateway responds with a 502 status code and a body which is not JSON, as a load balancer would.
+	BadGateway
+	// Hang does not respond until the request is cancelled or the node is closed.
+	Hang
+	// Malformed responds with a 200 status code and a body which is not valid JSON.
+	Malformed
+)
+
+// String implements the `fmt.Stringer` interface.
+func (fault Fault) String() string {
+	switch fault {
+	case TooManyRequests:
+		return "too many requests"
+	case ServerError:
+		return "server error"
+	case BadGateway:
+		return "bad gateway"
+	case Hang:
+		return "hang"
+	case Malformed:
+		return "malformed"
+	default:
+		return fmt.Sprintf("fault(%d)", int(fault))
+	}
+}
+
+// Options configure the faults which are injected into every request.
+type Options struct {
+	// Latency is added to every request before it is handled.
+	Latency time.Duration
+	// ErrorRate is the fraction of requests which fail with `ErrorFault`.
+	ErrorRate float64
+	// ErrorFault is the fault of the requests which fail because of the error rate.
+	ErrorFault Fault
+	// Seed seeds the source used to pick which requests fail.
+	Seed int64
+}
+
+// DefaultOptions returns options which do not inject any faults.
+func DefaultOptions() Options {
+	return Options{
+		Latency:    0,
+		ErrorRate:  0,
+		ErrorFault: ServerError,
+		Seed:       0,
+	}
+}
+
+type request struct {
+	Method string      `json:"method"`
+	ID     interface{} `json:"id"`
+}
+
+type response struct {
+	Result json.RawMessage  `json:"result"`
+	Error  *types.JSONError `json:"error"`
+	ID     interface{}      `json:"id"`
+}
+
+// Node is a fake JSON-RPC node. It is safe for concurrent use.
+type Node struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	opts      Options
+	rand      *rand.Rand
+	responses map[string]response
+	faults    []Fault
+	calls     map[string]int
+	requests  int
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// New returns a new node which does not inject any faults.
+func New() *Node {
+	return NewWithOptions(DefaultOptions())
+}
+
+// NewWithOptions returns a new node which injects the faults of the options. The node must be closed by the caller.
+func NewWithOptions(opts Options) *Node {
+	node := &Node{
+		opts:      opts,
+		rand:      rand.New(rand.NewSource(opts.Seed)),
+		responses: map[string]response{},
+		calls:     map[string]int{},
+		done:      make(chan struct{}),
+	}
+	node.Server = httptest.NewServer(node)
+	return node
+}
+
+// Close closes the node. Requests which are hanging are released first.
+func (node *Node) Close() {
+	node.closeOnce.Do(func() { close(node.done) })
+	node.Server.Close()
+}
+
+// Respond sets the result of the method. The result is encoded as JSON unless it is a `json.RawMessage`.
+func (node *Node) Respond(method string, result interface{}) error {
+	data, ok := result.(json.RawMessage)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(result); err != nil {
+			return fmt.Errorf("cannot marshal result: %v", err)
+		}
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.responses[method] = response{Result: data}
+	return nil
+}
+
+// RespondError sets the JSON-RPC error returned by the method.
+func (node *Node) RespondError(method string, code int, message string) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.responses[method] = response{Error: &types.JSONError{Code: code, Message: message}}
+}
+
+// Inject makes the next n requests fail with the fault. Faults are used in the order they are injected.
+func (node *Node) Inject(fault Fault, n int) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		node.faults = append(node.faults, fault)
+	}
+}
+
+// SetLatency sets the latency added to every request.
+func (node *Node) SetLatency(latency time.Duration) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.opts.Latency = latency
+}
+
+// SetErrorRate sets the fraction of requests which fail with the fault.
+func (node *Node) SetErrorRate(rate float64, fault Fault) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.opts.ErrorRate = rate
+	node.opts.ErrorFault = fault
+}
+
+// Calls returns the number of calls of the method which were received, including calls which failed. Each call in a
+// batch is counted separately.
+func (node *Node) Calls(method string) int {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return node.calls[method]
+}
+
+// Requests returns the number of HTTP requests which were received.
+func (node *Node) Requests() int {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return node.requests
+}
+
+// ServeHTTP implements the `http.Handler` interface.
+func (node *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot read request: %v", err), http.StatusBadRequest)
+		return
+	}
+	data = bytes.TrimSpace(data)
+	batch := len(data) > 0 && data[0] == '['
+	reqs := []request{}
+	if batch {
+		err = json.Unmarshal(data, &reqs)
+	} else {
+		reqs = append(reqs, request{})
+		err = json.Unmarshal(data, &reqs[0])
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot decode request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	latency, fault := node.record(reqs)
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		case <-node.done:
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch fault {
+	case TooManyRequests:
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":"too many requests"}`)
+		return
+	case ServerError:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response{Error: &types.JSONError{Code: -32603, Message: "internal error"}, ID: reqs[0].ID})
+		return
+	case BadGateway:
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, "<html><body>502 Bad Gateway</body></html>")
+		return
+	case Hang:
+		select {
+		case <-r.Context().Done():
+		case <-node.done:
+		}
+		return
+	case Malformed:
+		fmt.Fprint(w, `{"result":`)
+		return
+	}
+
+	resps := make([]response, len(reqs))
+	statusCode := http.StatusOK
+	for i, req := range reqs {
+		resps[i] = node.respond(req)
+		if !batch && resps[i].Error != nil {
+			if resps[i].Error.Code == -32601 {
+				statusCode = http.StatusNotFound
+			} else {
+				statusCode = http.StatusInternalServerError
+			}
+		}
+	}
+	w.WriteHeader(statusCode)
+	if batch {
+		json.NewEncoder(w).Encode(resps)
+		return
+	}
+	json.NewEncoder(w).Encode(resps[0])
+}
+
+// record counts the request, and returns its latency and fault (which is zero if the request does not fail).
+func (node *Node) record(reqs []request) (time.Duration, Fault) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.requests++
+	for _, req := range reqs {
+		node.calls[req.Method]++
+	}
+
+	if len(node.faults) > 0 {
+		fault := node.faults[0]
+		node.faults = node.faults[1:]
+		return node.opts.Latency, fault
+	}
+	if node.opts.ErrorRate > 0 && node.rand.Float64() < node.opts.ErrorRate {
+		return node.opts.Latency, node.opts.ErrorFault
+	}
+	return node.opts.Latency, 0
+}
+
+func (node *Node) respond(req request) response {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	resp, ok := node.responses[req.Method]
+	if !ok {
+		resp.Error = &types.JSONError{Code: -32601, Message: "Method not found"}
+	}
+	resp.ID = req.ID
+	return resp
+}
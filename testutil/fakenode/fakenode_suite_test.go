@@ -0,0 +1,13 @@
+package fakenode_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFakenode(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fakenode Suite")
+}
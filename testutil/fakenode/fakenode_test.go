@@ -0,0 +1,126 @@
+package fakenode_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/testutil/fakenode"
+)
+
+var _ = Describe("Fake node", func() {
+	// post sends the request to the node and returns the status code and body of the response.
+	post := func(ctx context.Context, url, data string) (int, string, error) {
+		req, err := http.NewRequest("POST", url, bytes.NewBufferString(data))
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.StatusCode, string(body), nil
+	}
+
+	It("should respond with the scripted results and errors", func() {
+		node := New()
+		defer node.Close()
+		Expect(node.Respond("getblockcount", 100)).To(Succeed())
+		node.RespondError("sendrawtransaction", -26, "txn-mempool-conflict")
+
+		status, body, err := post(context.Background(), node.URL, `{"id":1,"method":"getblockcount","params":[]}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`{"result":100,"error":null,"id":1}`))
+
+		status, body, err = post(context.Background(), node.URL, `{"id":2,"method":"sendrawtransaction","params":[]}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(http.StatusInternalServerError))
+		Expect(body).To(MatchJSON(`{"result":null,"error":{"code":-26,"message":"txn-mempool-conflict","data":null},"id":2}`))
+
+		status, _, err = post(context.Background(), node.URL, `{"id":3,"method":"stop","params":[]}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(http.StatusNotFound))
+
+		status, body, err = post(context.Background(), node.URL, `[{"id":1,"method":"getblockcount"},{"id":2,"method":"getblockcount"}]`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(http.StatusOK))
+		resps := []json.RawMessage{}
+		Expect(json.Unmarshal([]byte(body), &resps)).To(Succeed())
+		Expect(resps).To(HaveLen(2))
+
+		Expect(node.Calls("getblockcount")).To(Equal(3))
+		Expect(node.Requests()).To(Equal(4))
+	})
+
+	It("should inject the faults in order", func() {
+		node := New()
+		defer node.Close()
+		Expect(node.Respond("getblockcount", 100)).To(Succeed())
+		node.Inject(TooManyRequests, 2)
+		node.Inject(BadGateway, 1)
+		node.Inject(Malformed, 1)
+
+		data := `{"id":1,"method":"getblockcount","params":[]}`
+		for _, expected := range []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusBadGateway} {
+			status, _, err := post(context.Background(), node.URL, data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(expected))
+		}
+		status, body, err := post(context.Background(), node.URL, data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(json.Valid([]byte(body))).To(BeFalse())
+
+		status, _, err = post(context.Background(), node.URL, data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(http.StatusOK))
+	})
+
+	It("should hang until the request is cancelled", func() {
+		node := New()
+		defer node.Close()
+		node.Inject(Hang, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, _, err := post(ctx, node.URL, `{"id":1,"method":"getblockcount","params":[]}`)
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+	})
+
+	It("should add latency and fail requests at the error rate deterministically", func() {
+		opts := DefaultOptions()
+		opts.Latency = 10 * time.Millisecond
+		opts.ErrorRate = 0.5
+		opts.Seed = 1
+
+		statuses := func() []int {
+			node := NewWithOptions(opts)
+			defer node.Close()
+			Expect(node.Respond("getblockcount", 100)).To(Succeed())
+
+			statuses := make([]int, 20)
+			for i := range statuses {
+				start := time.Now()
+				status, _, err := post(context.Background(), node.URL, `{"id":1,"method":"getblockcount","params":[]}`)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(time.Since(start)).To(BeNumerically(">=", opts.Latency))
+				statuses[i] = status
+			}
+			return statuses
+		}
+
+		first := statuses()
+		Expect(first).To(ContainElement(http.StatusOK))
+		Expect(first).To(ContainElement(http.StatusInternalServerError))
+		Expect(statuses()).To(Equal(first))
+	})
+})
@@ -35,6 +35,15 @@ func NewRecipient(address Address, amount Amount) Recipient {
 
 type Recipients []Recipient
 
+// Sum returns the total amount paid to the recipients.
+func (recipients Recipients) Sum() Amount {
+	total := Amount(0)
+	for _, recipient := range recipients {
+		total += recipient.Amount
+	}
+	return total
+}
+
 // SerializePublicKey serializes the public key to bytes.
 func SerializePublicKey(pubkey ecdsa.PublicKey) []byte {
 	return (*btcec.PublicKey)(&pubkey).SerializeCompressed()
@@ -43,8 +52,16 @@ func SerializePublicKey(pubkey ecdsa.PublicKey) []byte {
 // AddressFromBase58 decodes the base58 encoded address to an `Address`.
 func AddressFromBase58(addr string, network Network) (Address, error) {
 	switch network.Chain() {
-	case types.Bitcoin:
-		return btcutil.DecodeAddress(addr, network.Params())
+	case types.Bitcoin, types.Litecoin, types.Dogecoin:
+		// SegWit addresses of any registered network can be decoded, so the network needs to be checked.
+		address, err := btcutil.DecodeAddress(addr, network.Params())
+		if err != nil {
+			return nil, err
+		}
+		if !address.IsForNet(network.Params()) {
+			return nil, fmt.Errorf("address %s is not for %s %s", addr, network.Chain(), network)
+		}
+		return address, nil
 	case types.ZCash:
 		return DecodeAddress(addr)
 	case types.BitcoinCash:
@@ -57,7 +74,7 @@ func AddressFromBase58(addr string, network Network) (Address, error) {
 // AddressFromPubKey gets the `Address` from a public key.
 func AddressFromPubKey(pubkey ecdsa.PublicKey, network Network) (Address, error) {
 	switch network.Chain() {
-	case types.Bitcoin:
+	case types.Bitcoin, types.Litecoin, types.Dogecoin:
 		return btcutil.NewAddressPubKeyHash(btcutil.Hash160(SerializePublicKey(pubkey)), network.Params())
 	case types.ZCash:
 		return NewAddressPubKey(SerializePublicKey(pubkey), network), nil
@@ -71,7 +88,7 @@ func AddressFromPubKey(pubkey ecdsa.PublicKey, network Network) (Address, error)
 // AddressFromPubKeyHash gets the `Address` from a public key hash.
 func AddressFromPubKeyHash(pHash []byte, network Network) (Address, error) {
 	switch network.Chain() {
-	case types.Bitcoin:
+	case types.Bitcoin, types.Litecoin, types.Dogecoin:
 		return btcutil.NewAddressPubKeyHash(pHash, network.Params())
 	case types.ZCash:
 		return NewAddressPubKeyHash(pHash, network), nil
@@ -83,7 +100,7 @@ func AddressFromPubKeyHash(pHash []byte, network Network) (Address, error) {
 // AddressFromScript gets the `Address` from a script.
 func AddressFromScript(script []byte, network Network) (Address, error) {
 	switch network.Chain() {
-	case types.Bitcoin:
+	case types.Bitcoin, types.Litecoin, types.Dogecoin:
 		return btcutil.NewAddressScriptHash(script, network.Params())
 	case types.ZCash:
 		return NewAddressScriptHash(script, network), nil
@@ -100,7 +117,7 @@ func SegWitAddressFromPubKey(pubKey ecdsa.PublicKey, network Network) (Address,
 		return nil, ErrDoesNotSupportSegWit
 	}
 	switch network.Chain() {
-	case types.Bitcoin:
+	case types.Bitcoin, types.Litecoin:
 		return btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(SerializePublicKey(pubKey)), network.Params())
 	default:
 		return nil, types.ErrUnknownChain
@@ -113,7 +130,7 @@ func SegWitAddressFromScript(script []byte, network Network) (Address, error) {
 		return nil, ErrDoesNotSupportSegWit
 	}
 	switch network.Chain() {
-	case types.Bitcoin:
+	case types.Bitcoin, types.Litecoin:
 		scriptHash := sha256.Sum256(script)
 		return btcutil.NewAddressWitnessScriptHash(scriptHash[:], network.Params())
 	default:
@@ -17,6 +17,15 @@ const (
 	ZEC = Amount(1e8 * ZAT)
 )
 
+// FeeRate represents a fee rate in the smallest possible unit per virtual byte. It is a distinct type so that fee rates
+// cannot be used where absolute fees are expected.
+type FeeRate int64
+
+// Fee returns the fee of a transaction with the given virtual size at the fee rate.
+func (feeRate FeeRate) Fee(vsize int) Amount {
+	return Amount(feeRate) * Amount(vsize)
+}
+
 func AmountFromFloat64(amount float64) Amount {
 	amt, err := btcutil.NewAmount(amount)
 	if err != nil {
@@ -127,7 +127,7 @@ func DecodeAddress(address string) (btcutil.Address, error) {
 // PayToAddrScript gets the PayToAddrScript for an address on the given blockchain
 func PayToAddrScript(address Address, network Network) ([]byte, error) {
 	switch network.Chain() {
-	case types.Bitcoin:
+	case types.Bitcoin, types.Litecoin, types.Dogecoin:
 		return txscript.PayToAddrScript(address)
 	case types.BitcoinCash:
 		return bch.PayToAddrScript(address)
@@ -13,6 +13,9 @@ type Network interface {
 	Prefix(addrType AddressType) []byte
 	Params() *chaincfg.Params
 	SegWitEnabled() bool
+
+	// IsLocal returns whether the network is a local development network (e.g. localnet or regtest).
+	IsLocal() bool
 }
 
 type network uint8
@@ -21,10 +24,21 @@ const (
 	BtcLocalnet network = 0
 	BtcMainnet  network = 1
 	BtcTestnet  network = 2
+	BtcRegtest  network = 15
+	BtcSignet   network = 16
 
 	BchLocalnet network = 6
 	BchMainnet  network = 7
 	BchTestnet  network = 8
+	BchRegtest  network = 17
+
+	LtcLocalnet network = 9
+	LtcMainnet  network = 10
+	LtcTestnet  network = 11
+
+	DogeLocalnet network = 12
+	DogeMainnet  network = 13
+	DogeTestnet  network = 14
 )
 
 // NewNetwork parse the network from a string.
@@ -36,6 +50,10 @@ func NewNetwork(chain types.Chain, network string) Network {
 		return NewZecNetwork(network)
 	case types.BitcoinCash:
 		return NewBchNetwork(network)
+	case types.Litecoin:
+		return NewLtcNetwork(network)
+	case types.Dogecoin:
+		return NewDogeNetwork(network)
 	default:
 		panic(types.ErrUnknownChain)
 	}
@@ -49,6 +67,10 @@ func NewBtcNetwork(network string) Network {
 		return BtcMainnet
 	case "testnet", "testnet3":
 		return BtcTestnet
+	case "regtest":
+		return BtcRegtest
+	case "signet":
+		return BtcSignet
 	case "localnet", "localhost":
 		return BtcLocalnet
 	default:
@@ -56,7 +78,7 @@ func NewBtcNetwork(network string) Network {
 	}
 }
 
-// NewBchNetwork parse the ltc network from a string.
+// NewBchNetwork parse the bch network from a string.
 func NewBchNetwork(network string) Network {
 	network = strings.ToLower(strings.TrimSpace(network))
 	switch network {
@@ -64,6 +86,8 @@ func NewBchNetwork(network string) Network {
 		return BchMainnet
 	case "testnet", "testnet3":
 		return BchTestnet
+	case "regtest":
+		return BchRegtest
 	case "localnet", "localhost":
 		return BchLocalnet
 	default:
@@ -71,6 +95,36 @@ func NewBchNetwork(network string) Network {
 	}
 }
 
+// NewLtcNetwork parse the ltc network from a string.
+func NewLtcNetwork(network string) Network {
+	network = strings.ToLower(strings.TrimSpace(network))
+	switch network {
+	case "mainnet":
+		return LtcMainnet
+	case "testnet", "testnet4":
+		return LtcTestnet
+	case "localnet", "localhost":
+		return LtcLocalnet
+	default:
+		panic(types.ErrUnknownNetwork)
+	}
+}
+
+// NewDogeNetwork parse the doge network from a string.
+func NewDogeNetwork(network string) Network {
+	network = strings.ToLower(strings.TrimSpace(network))
+	switch network {
+	case "mainnet":
+		return DogeMainnet
+	case "testnet":
+		return DogeTestnet
+	case "localnet", "localhost":
+		return DogeLocalnet
+	default:
+		panic(types.ErrUnknownNetwork)
+	}
+}
+
 // Params returns the params config for the network
 func (network network) Params() *chaincfg.Params {
 	switch network {
@@ -78,6 +132,18 @@ func (network network) Params() *chaincfg.Params {
 		return &chaincfg.MainNetParams
 	case BtcTestnet, BtcLocalnet, BchTestnet, BchLocalnet:
 		return &chaincfg.TestNet3Params
+	case BtcRegtest, BchRegtest:
+		return &chaincfg.RegressionNetParams
+	case BtcSignet:
+		return &SigNetParams
+	case LtcMainnet:
+		return &LtcMainNetParams
+	case LtcTestnet, LtcLocalnet:
+		return &LtcTestNetParams
+	case DogeMainnet:
+		return &DogeMainNetParams
+	case DogeTestnet, DogeLocalnet:
+		return &DogeTestNetParams
 	default:
 		panic(types.ErrUnknownNetwork)
 	}
@@ -86,24 +152,38 @@ func (network network) Params() *chaincfg.Params {
 // SegWitEnabled returns the params config for the network
 func (network network) SegWitEnabled() bool {
 	switch network.Chain() {
-	case types.Bitcoin:
+	case types.Bitcoin, types.Litecoin:
 		return true
-	case types.ZCash, types.BitcoinCash:
+	case types.ZCash, types.BitcoinCash, types.Dogecoin:
 		return false
 	default:
 		panic(types.ErrUnknownNetwork)
 	}
 }
 
+// IsLocal implements the `Network` interface.
+func (network network) IsLocal() bool {
+	switch network {
+	case BtcLocalnet, BchLocalnet, LtcLocalnet, DogeLocalnet, BtcRegtest, BchRegtest:
+		return true
+	default:
+		return false
+	}
+}
+
 // String implements the `Stringer` interface.
 func (network network) String() string {
 	switch network {
-	case BtcMainnet, BchMainnet:
+	case BtcMainnet, BchMainnet, LtcMainnet, DogeMainnet:
 		return "mainnet"
-	case BtcTestnet, BchTestnet:
+	case BtcTestnet, BchTestnet, LtcTestnet, DogeTestnet:
 		return "testnet"
-	case BtcLocalnet, BchLocalnet:
+	case BtcLocalnet, BchLocalnet, LtcLocalnet, DogeLocalnet:
 		return "localnet"
+	case BtcRegtest, BchRegtest:
+		return "regtest"
+	case BtcSignet:
+		return "signet"
 	default:
 		panic(types.ErrUnknownNetwork)
 	}
@@ -112,10 +192,14 @@ func (network network) String() string {
 // Chain implements the types.Network interface.
 func (network network) Chain() types.Chain {
 	switch network {
-	case BtcMainnet, BtcTestnet, BtcLocalnet:
+	case BtcMainnet, BtcTestnet, BtcLocalnet, BtcRegtest, BtcSignet:
 		return types.Bitcoin
-	case BchMainnet, BchTestnet, BchLocalnet:
+	case BchMainnet, BchTestnet, BchLocalnet, BchRegtest:
 		return types.BitcoinCash
+	case LtcMainnet, LtcTestnet, LtcLocalnet:
+		return types.Litecoin
+	case DogeMainnet, DogeTestnet, DogeLocalnet:
+		return types.Dogecoin
 	default:
 		panic(types.ErrUnknownNetwork)
 	}
@@ -20,7 +20,7 @@ var _ = Describe("btc types", func() {
 		return address.IsForNet(network.Params())
 	}
 
-	for _, network := range []Network{BtcTestnet, BtcMainnet} {
+	for _, network := range []Network{BtcTestnet, BtcMainnet, BtcRegtest, BtcSignet, LtcTestnet, LtcMainnet} {
 		network := network
 
 		Context(fmt.Sprintf("when generating new %s addresses of %v", network.Chain(), network), func() {
@@ -87,6 +87,86 @@ var _ = Describe("btc types", func() {
 
 	}
 
+	for _, network := range []Network{DogeTestnet, DogeMainnet} {
+		network := network
+
+		Context(fmt.Sprintf("when generating new %s addresses of %v", network.Chain(), network), func() {
+			It("should be able to decode an address from string", func() {
+				test := func() bool {
+					randAddr, err := testutil.RandomAddress(network)
+					Expect(err).NotTo(HaveOccurred())
+					address, err := AddressFromBase58(randAddr.EncodeAddress(), network)
+					Expect(err).NotTo(HaveOccurred())
+					return validateAddress(address, network)
+				}
+				Expect(quick.Check(test, nil)).To(Succeed())
+			})
+
+			It("should not be able to generate a SegWit address", func() {
+				randKey, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = SegWitAddressFromPubKey(randKey.PublicKey, network)
+				Expect(err).To(Equal(ErrDoesNotSupportSegWit))
+			})
+		})
+	}
+
+	Context("regtest and signet addresses", func() {
+		It("should use the regtest prefix for SegWit addresses", func() {
+			address, err := testutil.RandomSegWitAddress(BtcRegtest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(address.EncodeAddress()).To(HavePrefix("bcrt1"))
+
+			_, err = AddressFromBase58(address.EncodeAddress(), BtcTestnet)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should decode regtest cash addresses", func() {
+			key, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			address, err := AddressFromPubKey(key.PublicKey, BchRegtest)
+			Expect(err).NotTo(HaveOccurred())
+			decoded, err := AddressFromBase58("bchreg:"+address.EncodeAddress(), BchRegtest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decoded.EncodeAddress()).To(Equal(address.EncodeAddress()))
+		})
+	})
+
+	Context("litecoin and dogecoin addresses", func() {
+		It("should use the prefixes of the network", func() {
+			key, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			ltcAddr, err := AddressFromPubKey(key.PublicKey, LtcMainnet)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ltcAddr.EncodeAddress()).To(HavePrefix("L"))
+			ltcSegWitAddr, err := SegWitAddressFromPubKey(key.PublicKey, LtcMainnet)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ltcSegWitAddr.EncodeAddress()).To(HavePrefix("ltc1"))
+			ltcTestSegWitAddr, err := SegWitAddressFromPubKey(key.PublicKey, LtcTestnet)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ltcTestSegWitAddr.EncodeAddress()).To(HavePrefix("tltc1"))
+
+			dogeAddr, err := AddressFromPubKey(key.PublicKey, DogeMainnet)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dogeAddr.EncodeAddress()).To(HavePrefix("D"))
+		})
+
+		It("should not decode addresses of other networks", func() {
+			btcAddr, err := testutil.RandomAddress(BtcMainnet)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = AddressFromBase58(btcAddr.EncodeAddress(), LtcMainnet)
+			Expect(err).To(HaveOccurred())
+			_, err = AddressFromBase58(btcAddr.EncodeAddress(), DogeMainnet)
+			Expect(err).To(HaveOccurred())
+
+			ltcAddr, err := testutil.RandomAddress(LtcMainnet)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = AddressFromBase58(ltcAddr.EncodeAddress(), BtcMainnet)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Context("bitcoin amount ", func() {
 		It("should be converted correctly", func() {
 			Expect(1e8 * SAT).Should(Equal(BTC))
@@ -140,5 +220,27 @@ var _ = Describe("btc types", func() {
 			}
 			Expect(quick.Check(unknownNetwork, nil)).To(Succeed())
 		})
+
+		It("should be able to parse regtest and signet networks", func() {
+			Expect(NewNetwork(types.Bitcoin, "regtest")).To(Equal(BtcRegtest))
+			Expect(NewNetwork(types.Bitcoin, "signet")).To(Equal(BtcSignet))
+			Expect(NewNetwork(types.BitcoinCash, "regtest")).To(Equal(BchRegtest))
+			Expect(NewNetwork(types.ZCash, "regtest")).To(Equal(ZecRegnet))
+			Expect(BtcRegtest.Params().Bech32HRPSegwit).To(Equal("bcrt"))
+			Expect(BtcSignet.String()).To(Equal("signet"))
+			Expect(BtcRegtest.IsLocal()).To(BeTrue())
+			Expect(ZecRegnet.IsLocal()).To(BeTrue())
+			Expect(BtcSignet.IsLocal()).To(BeFalse())
+			Expect(ZecTestnet.IsLocal()).To(BeFalse())
+		})
+
+		It("should be able to parse litecoin and dogecoin networks", func() {
+			Expect(NewNetwork(types.Litecoin, "mainnet")).To(Equal(LtcMainnet))
+			Expect(NewNetwork(types.Litecoin, "testnet")).To(Equal(LtcTestnet))
+			Expect(NewNetwork(types.Dogecoin, "mainnet")).To(Equal(DogeMainnet))
+			Expect(NewNetwork(types.Dogecoin, "testnet")).To(Equal(DogeTestnet))
+			Expect(LtcMainnet.String()).To(Equal("mainnet"))
+			Expect(DogeTestnet.Chain()).To(Equal(types.Dogecoin))
+		})
 	})
 })
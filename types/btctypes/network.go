@@ -14,6 +14,7 @@ type ZecNetwork struct {
 	params        *chaincfg.Params
 	expiryHeight  uint32
 	netString     string
+	local         bool
 }
 
 var ZecMainnet = ZecNetwork{
@@ -56,6 +57,7 @@ var ZecRegnet = ZecNetwork{
 	expiryHeight: 10000000,
 	netString:    "regtest",
 	params:       &chaincfg.RegressionNetParams,
+	local:        true,
 }
 
 var ZecLocalnet = ZecTestnet
@@ -66,6 +68,8 @@ func NewZecNetwork(network string) ZecNetwork {
 		return ZecMainnet
 	case "testnet", "testnet3":
 		return ZecTestnet
+	case "regtest":
+		return ZecRegnet
 	case "localnet", "localhost":
 		return ZecLocalnet
 	default:
@@ -81,6 +85,10 @@ func (net ZecNetwork) String() string {
 	return net.netString
 }
 
+func (net ZecNetwork) IsLocal() bool {
+	return net.local
+}
+
 func (net ZecNetwork) SegWitEnabled() bool {
 	return false
 }
@@ -0,0 +1,89 @@
+package btctypes
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// SigNetParams are the params of the default Bitcoin signet. Signet uses the same address prefixes as the Bitcoin test
+// network.
+var SigNetParams = chaincfg.Params{
+	Name:        "signet",
+	Net:         wire.BitcoinNet(0x40cf030a),
+	DefaultPort: "38333",
+
+	Bech32HRPSegwit:  "tb",
+	PubKeyHashAddrID: 0x6f,
+	ScriptHashAddrID: 0xc4,
+	PrivateKeyID:     0xef,
+	HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94},
+	HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf},
+	HDCoinType:       1,
+}
+
+// LtcMainNetParams are the params of the Litecoin main network.
+var LtcMainNetParams = chaincfg.Params{
+	Name:        "ltc-mainnet",
+	Net:         wire.BitcoinNet(0xdbb6c0fb),
+	DefaultPort: "9333",
+
+	Bech32HRPSegwit:  "ltc",
+	PubKeyHashAddrID: 0x30,
+	ScriptHashAddrID: 0x32,
+	PrivateKeyID:     0xb0,
+	HDPrivateKeyID:   [4]byte{0x04, 0x88, 0xad, 0xe4},
+	HDPublicKeyID:    [4]byte{0x04, 0x88, 0xb2, 0x1e},
+	HDCoinType:       2,
+}
+
+// LtcTestNetParams are the params of the Litecoin test network (version 4).
+var LtcTestNetParams = chaincfg.Params{
+	Name:        "ltc-testnet4",
+	Net:         wire.BitcoinNet(0xf1c8d2fd),
+	DefaultPort: "19335",
+
+	Bech32HRPSegwit:  "tltc",
+	PubKeyHashAddrID: 0x6f,
+	ScriptHashAddrID: 0x3a,
+	PrivateKeyID:     0xef,
+	HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94},
+	HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf},
+	HDCoinType:       1,
+}
+
+// DogeMainNetParams are the params of the Dogecoin main network. Dogecoin does not support SegWit.
+var DogeMainNetParams = chaincfg.Params{
+	Name:        "doge-mainnet",
+	Net:         wire.BitcoinNet(0xc0c0c0c0),
+	DefaultPort: "22556",
+
+	PubKeyHashAddrID: 0x1e,
+	ScriptHashAddrID: 0x16,
+	PrivateKeyID:     0x9e,
+	HDPrivateKeyID:   [4]byte{0x02, 0xfa, 0xc3, 0x98},
+	HDPublicKeyID:    [4]byte{0x02, 0xfa, 0xca, 0xfd},
+	HDCoinType:       3,
+}
+
+// DogeTestNetParams are the params of the Dogecoin test network. Dogecoin does not support SegWit.
+var DogeTestNetParams = chaincfg.Params{
+	Name:        "doge-testnet",
+	Net:         wire.BitcoinNet(0xdcb7c1fc),
+	DefaultPort: "44556",
+
+	PubKeyHashAddrID: 0x71,
+	ScriptHashAddrID: 0xc4,
+	PrivateKeyID:     0xf1,
+	HDPrivateKeyID:   [4]byte{0x04, 0x35, 0x83, 0x94},
+	HDPublicKeyID:    [4]byte{0x04, 0x35, 0x87, 0xcf},
+	HDCoinType:       1,
+}
+
+func init() {
+	// Register the params so that their SegWit addresses can be decoded by btcutil.
+	for _, params := range []*chaincfg.Params{&SigNetParams, &LtcMainNetParams, &LtcTestNetParams, &DogeMainNetParams, &DogeTestNetParams} {
+		if err := chaincfg.Register(params); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -24,6 +24,10 @@ type BchScript struct {
 	Script
 }
 
+type DogeScript struct {
+	Script
+}
+
 type script struct {
 	address Address
 	data    []byte
@@ -37,7 +41,7 @@ func NewScript(data []byte, network Network) Script {
 	}
 	baseScript := &script{address, data}
 	switch network.Chain() {
-	case types.Bitcoin:
+	case types.Bitcoin, types.Litecoin:
 		segWitAddress, err := SegWitAddressFromScript(data, network)
 		if err != nil {
 			panic("invariant violation: failed to calcucalte SegWit address of a btc script")
@@ -50,6 +54,8 @@ func NewScript(data []byte, network Network) Script {
 		return &ZecScript{baseScript}
 	case types.BitcoinCash:
 		return &BchScript{baseScript}
+	case types.Dogecoin:
+		return &DogeScript{baseScript}
 	default:
 		panic(types.ErrUnknownChain)
 	}
@@ -75,6 +81,9 @@ func (s *script) Address() Address {
 	return s.address
 }
 
+// EstimateTxSize estimates the size of a transaction spending P2PKH and gateway UTXOs to P2PKH recipients.
+//
+// Deprecated: use EstimateVSize with UTXOs which have been updated with the script.
 func (s *script) EstimateTxSize(numSpenderUTXOs, numGatewayUTXOs, numRecipients int) int {
 	scriptLen := len(s.Bytes())
 	return (113+scriptLen)*numGatewayUTXOs + EstimateTxSize(numSpenderUTXOs, numRecipients)
@@ -0,0 +1,163 @@
+package btctypes
+
+import (
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/renproject/mercury/types"
+)
+
+// Sizes (in bytes) of the fields of inputs and outputs. Signatures are assumed to have the maximum length of a DER
+// signature, so that estimates are never too low.
+const (
+	outPointSize    = 36
+	sequenceSize    = 4
+	valueSize       = 8
+	sigSize         = 73 // DER signature and sighash type
+	pubKeySize      = 33 // Compressed public key
+	p2wpkhProgSize  = 22 // Witness program of a P2WPKH output
+	witnessScale    = 4
+	segWitFlagsSize = 2 // Marker and flag of transactions with witnesses
+	// Script of a gateway (a 32 byte hash, followed by a P2PKH script), assumed for P2SH UTXOs without a script on
+	// networks which do not support SegWit.
+	gatewayScriptSize = 33 + 1 + 25
+)
+
+// VSize returns the virtual size of a transaction of the given weight.
+func VSize(weight int) int {
+	return (weight + witnessScale - 1) / witnessScale
+}
+
+// InputWeight returns the weight of an input of the network spending the UTXO. UTXOs with a script (e.g. gateways) are
+// spent using P2SH or P2WSH, depending on the script pubkey. P2SH UTXOs without a script are assumed to be P2WPKH
+// outputs wrapped in P2SH on networks which support SegWit, and gateways on other networks.
+func InputWeight(network Network, utxo UTXO) int {
+	scriptPubKey := utxo.ScriptPubKey()
+	script := utxo.Script()
+	keySpend := pushDataSize(sigSize) + pushDataSize(pubKeySize)
+
+	switch {
+	case utxo.SegWit() && script != nil:
+		witness := varIntSize(3) + varBytesSize(sigSize) + varBytesSize(pubKeySize) + varBytesSize(len(script))
+		return witnessScale*nonWitnessInputSize(0) + witness
+	case utxo.SegWit():
+		witness := varIntSize(2) + varBytesSize(sigSize) + varBytesSize(pubKeySize)
+		return witnessScale*nonWitnessInputSize(0) + witness
+	case script != nil:
+		return witnessScale * nonWitnessInputSize(keySpend+pushDataSize(len(script)))
+	case IsWitnessInput(network, utxo):
+		witness := varIntSize(2) + varBytesSize(sigSize) + varBytesSize(pubKeySize)
+		return witnessScale*nonWitnessInputSize(pushDataSize(p2wpkhProgSize)) + witness
+	case txscript.IsPayToScriptHash(scriptPubKey):
+		return witnessScale * nonWitnessInputSize(keySpend+pushDataSize(gatewayScriptSize))
+	default:
+		return witnessScale * nonWitnessInputSize(keySpend)
+	}
+}
+
+// IsWitnessInput returns whether the input of the network spending the UTXO has a witness. This includes P2SH UTXOs
+// without a script, which are assumed to be P2WPKH outputs wrapped in P2SH on networks which support SegWit.
+func IsWitnessInput(network Network, utxo UTXO) bool {
+	if utxo.SegWit() {
+		return true
+	}
+	return network.SegWitEnabled() && utxo.Script() == nil && txscript.IsPayToScriptHash(utxo.ScriptPubKey())
+}
+
+// OutputWeight returns the weight of an output with the script pubkey.
+func OutputWeight(scriptPubKey []byte) int {
+	return witnessScale * (valueSize + varBytesSize(len(scriptPubKey)))
+}
+
+// OverheadWeight returns the weight of the fields of a transaction which are not part of its inputs or outputs.
+// Transactions spending SegWit UTXOs also include the SegWit marker and flag.
+func OverheadWeight(network Network, numInputs, numOutputs int, segWit bool) int {
+	// Version, input and output counts, and lock time.
+	size := 4 + varIntSize(numInputs) + varIntSize(numOutputs) + 4
+	if network.Chain() == types.ZCash {
+		// Version group ID and expiry height.
+		size += 4 + 4
+		if ZecVersion == versionSapling {
+			// Value balance, and the counts of shielded spends and outputs.
+			size += 8 + varIntSize(0) + varIntSize(0)
+		}
+		// Count of JoinSplits.
+		size += varIntSize(0)
+	}
+	weight := witnessScale * size
+	if segWit {
+		weight += segWitFlagsSize
+	}
+	return weight
+}
+
+// EstimateWeight returns the weight of a signed transaction spending the UTXOs to the recipients.
+func EstimateWeight(network Network, utxos UTXOs, recipients Recipients) (int, error) {
+	segWit := false
+	for _, utxo := range utxos {
+		segWit = segWit || IsWitnessInput(network, utxo)
+	}
+
+	weight := OverheadWeight(network, len(utxos), len(recipients), segWit)
+	for _, utxo := range utxos {
+		weight += InputWeight(network, utxo)
+		if segWit && !IsWitnessInput(network, utxo) {
+			// Inputs without witnesses have an empty witness in transactions with witnesses.
+			weight += varIntSize(0)
+		}
+	}
+	for _, recipient := range recipients {
+		scriptPubKey, err := PayToAddrScript(recipient.Address, network)
+		if err != nil {
+			return 0, err
+		}
+		weight += OutputWeight(scriptPubKey)
+	}
+	return weight, nil
+}
+
+// EstimateVSize returns the virtual size of a signed transaction spending the UTXOs to the recipients. The virtual
+// size of transactions without witnesses is their size in bytes.
+func EstimateVSize(network Network, utxos UTXOs, recipients Recipients) (int, error) {
+	weight, err := EstimateWeight(network, utxos, recipients)
+	if err != nil {
+		return 0, err
+	}
+	return VSize(weight), nil
+}
+
+// nonWitnessInputSize returns the size of an input with a signature script of the given size.
+func nonWitnessInputSize(sigScriptSize int) int {
+	return outPointSize + varBytesSize(sigScriptSize) + sequenceSize
+}
+
+// pushDataSize returns the size of a script pushing n bytes of data.
+func pushDataSize(n int) int {
+	switch {
+	case n < txscript.OP_PUSHDATA1:
+		return 1 + n
+	case n <= 0xff:
+		return 2 + n
+	case n <= 0xffff:
+		return 3 + n
+	default:
+		return 5 + n
+	}
+}
+
+// varBytesSize returns the size of n bytes prefixed by their length.
+func varBytesSize(n int) int {
+	return varIntSize(n) + n
+}
+
+// varIntSize returns the size of n encoded as a variable length integer.
+func varIntSize(n int) int {
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	case n <= 0xffffffff:
+		return 5
+	default:
+		return 9
+	}
+}
@@ -0,0 +1,149 @@
+package btctypes_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/types/btctypes"
+
+	"github.com/renproject/mercury/types"
+)
+
+var _ = Describe("Transaction sizes", func() {
+	// script is the script of a gateway. Its content does not matter, as the signatures are not verified.
+	script := bytes.Repeat([]byte{txscript.OP_NOP}, 90)
+
+	// weight returns the actual weight of the signed transaction.
+	weight := func(network Network, tx BtcTx) int {
+		data, err := tx.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		if network.Chain() != types.Bitcoin {
+			return 4 * len(data)
+		}
+		msgTx := new(wire.MsgTx)
+		Expect(msgTx.Deserialize(bytes.NewReader(data))).To(Succeed())
+		return 3*msgTx.SerializeSizeStripped() + msgTx.SerializeSize()
+	}
+
+	testCases := []struct {
+		Network Network
+		SegWit  bool
+		Script  bool
+	}{
+		{BtcRegtest, false, false},
+		{BtcRegtest, true, false},
+		{BtcRegtest, false, true},
+		{BtcRegtest, true, true},
+		{BchRegtest, false, false},
+		{BchRegtest, false, true},
+		{ZecRegnet, false, false},
+		{ZecRegnet, false, true},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		Context(fmt.Sprintf("when spending %s outputs (segwit = %v, script = %v)", testCase.Network.Chain(), testCase.SegWit, testCase.Script), func() {
+			It("should estimate the weight of the signed transaction", func() {
+				key, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+				Expect(err).NotTo(HaveOccurred())
+
+				var address Address
+				switch {
+				case testCase.SegWit && testCase.Script:
+					address, err = SegWitAddressFromScript(script, testCase.Network)
+				case testCase.SegWit:
+					address, err = SegWitAddressFromPubKey(key.PublicKey, testCase.Network)
+				case testCase.Script:
+					address, err = AddressFromScript(script, testCase.Network)
+				default:
+					address, err = AddressFromPubKey(key.PublicKey, testCase.Network)
+				}
+				Expect(err).NotTo(HaveOccurred())
+				scriptPubKey, err := PayToAddrScript(address, testCase.Network)
+				Expect(err).NotTo(HaveOccurred())
+				recipient, err := AddressFromPubKey(key.PublicKey, testCase.Network)
+				Expect(err).NotTo(HaveOccurred())
+
+				utxos := UTXOs{}
+				for i := 0; i < 3; i++ {
+					utxo := NewUTXO(NewOutPoint(types.TxHash(fmt.Sprintf("%064x", i+1)), 0), 100000, scriptPubKey, 1, nil)
+					if testCase.Script {
+						utxo.SetScript(script)
+					}
+					utxos = append(utxos, utxo)
+				}
+				recipients := Recipients{NewRecipient(recipient, 10000), NewRecipient(address, 20000)}
+
+				estimate, err := EstimateWeight(testCase.Network, utxos, recipients)
+				Expect(err).NotTo(HaveOccurred())
+				vsize, err := EstimateVSize(testCase.Network, utxos, recipients)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(vsize).To(Equal(VSize(estimate)))
+
+				// Signatures are at most two bytes shorter than the estimate.
+				for i := 0; i < 10; i++ {
+					tx, err := NewUnsignedTx(testCase.Network, utxos, recipients)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(tx.Sign(key)).To(Succeed())
+					actual := weight(testCase.Network, tx)
+					Expect(estimate).To(BeNumerically(">=", actual))
+					Expect(estimate).To(BeNumerically("<=", actual+2*4*len(utxos)))
+				}
+			})
+		})
+	}
+
+	Context("when spending P2SH outputs without a script", func() {
+		// utxo returns a P2SH UTXO of the network without a script.
+		utxo := func(network Network) UTXO {
+			address, err := AddressFromScript([]byte{txscript.OP_TRUE}, network)
+			Expect(err).NotTo(HaveOccurred())
+			scriptPubKey, err := PayToAddrScript(address, network)
+			Expect(err).NotTo(HaveOccurred())
+			return NewUTXO(NewOutPoint(types.TxHash(fmt.Sprintf("%064x", 1)), 0), 100000, scriptPubKey, 1, nil)
+		}
+
+		It("should estimate the weight of a wrapped SegWit input", func() {
+			// The input has a 23 byte signature script, and a witness with a signature and public key.
+			wrapped := utxo(BtcRegtest)
+			Expect(IsWitnessInput(BtcRegtest, wrapped)).To(BeTrue())
+			Expect(InputWeight(BtcRegtest, wrapped)).To(Equal(4*(36+1+23+4) + 1 + 74 + 34))
+
+			// Transactions spending wrapped SegWit inputs include the SegWit marker and flag.
+			address, err := AddressFromScript([]byte{txscript.OP_TRUE}, BtcRegtest)
+			Expect(err).NotTo(HaveOccurred())
+			scriptPubKey, err := PayToAddrScript(address, BtcRegtest)
+			Expect(err).NotTo(HaveOccurred())
+			weight, err := EstimateWeight(BtcRegtest, UTXOs{wrapped}, Recipients{NewRecipient(address, 10000)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weight).To(Equal(OverheadWeight(BtcRegtest, 1, 1, true) + InputWeight(BtcRegtest, wrapped) + OutputWeight(scriptPubKey)))
+		})
+
+		It("should not assume a witness on networks without SegWit", func() {
+			for _, network := range []Network{BchRegtest, ZecRegnet} {
+				input := utxo(network)
+				Expect(IsWitnessInput(network, input)).To(BeFalse())
+
+				// The signature script has a signature, a public key and the script of a gateway.
+				Expect(InputWeight(network, input)).To(Equal(4 * (36 + 1 + 74 + 34 + 60 + 4)))
+			}
+		})
+	})
+
+	Context("when estimating the overhead of transactions", func() {
+		It("should include the fields of each transaction format", func() {
+			Expect(OverheadWeight(BtcRegtest, 1, 2, false)).To(Equal(4 * 10))
+			Expect(OverheadWeight(BtcRegtest, 1, 2, true)).To(Equal(4*10 + 2))
+			Expect(OverheadWeight(BtcRegtest, 300, 2, false)).To(Equal(4 * 12))
+			Expect(OverheadWeight(ZecRegnet, 1, 2, false)).To(Equal(4 * 29))
+		})
+	})
+})
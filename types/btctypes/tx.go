@@ -18,6 +18,28 @@ type BtcTx interface {
 	UTXOs() UTXOs
 	Recipients() Recipients
 	OutputUTXO(address Address) UTXO
+	Replaceable() bool
+}
+
+// RBFSequence is the sequence of the inputs of transactions which signal that they can be replaced by transactions
+// paying a higher fee (BIP125).
+const RBFSequence = wire.MaxTxInSequenceNum - 2
+
+// ErrDoesNotSupportRBF is returned when building a replaceable transaction for a chain which does not support BIP125.
+var ErrDoesNotSupportRBF = fmt.Errorf("this blockchain does not support replace-by-fee")
+
+// TxOptions are the options used to build a transaction.
+type TxOptions struct {
+	// RBF signals that the transaction can be replaced by a transaction paying a higher fee (BIP125). It is only
+	// supported by Bitcoin and Litecoin.
+	RBF bool
+}
+
+// DefaultTxOptions returns options which build transactions that cannot be replaced.
+func DefaultTxOptions() TxOptions {
+	return TxOptions{
+		RBF: false,
+	}
 }
 
 type tx struct {
@@ -28,9 +50,26 @@ type tx struct {
 	recipients  Recipients
 	tx          MsgTx
 	signed      bool
+	replaceable bool
 }
 
+// NewUnsignedTx returns a transaction spending the UTXOs to the recipients, which cannot be replaced.
 func NewUnsignedTx(network Network, utxos UTXOs, recipients Recipients) (BtcTx, error) {
+	return NewUnsignedTxWithOptions(network, utxos, recipients, DefaultTxOptions())
+}
+
+// NewUnsignedTxWithOptions returns a transaction spending the UTXOs to the recipients, built using the options.
+func NewUnsignedTxWithOptions(network Network, utxos UTXOs, recipients Recipients, opts TxOptions) (BtcTx, error) {
+	sequence := uint32(wire.MaxTxInSequenceNum)
+	if opts.RBF {
+		switch network.Chain() {
+		case types.Bitcoin, types.Litecoin:
+			sequence = RBFSequence
+		default:
+			return nil, ErrDoesNotSupportRBF
+		}
+	}
+
 	outputUTXOs := map[string]UTXO{}
 	msgTx := NewMsgTx(network)
 	for _, utxo := range utxos {
@@ -38,7 +77,9 @@ func NewUnsignedTx(network Network, utxos UTXOs, recipients Recipients) (BtcTx,
 		if err != nil {
 			return nil, err
 		}
-		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, utxo.Vout()), nil, nil))
+		txIn := wire.NewTxIn(wire.NewOutPoint(hash, utxo.Vout()), nil, nil)
+		txIn.Sequence = sequence
+		msgTx.AddTxIn(txIn)
 	}
 	for i, recipient := range recipients {
 		script, err := PayToAddrScript(recipient.Address, network)
@@ -54,7 +95,9 @@ func NewUnsignedTx(network Network, utxos UTXOs, recipients Recipients) (BtcTx,
 		sigHashes:   []types.SignatureHash{},
 		tx:          msgTx,
 		utxos:       utxos,
+		recipients:  append(Recipients{}, recipients...),
 		signed:      false,
+		replaceable: opts.RBF,
 	}
 	for i, utxo := range utxos {
 		sigHash, err := utxo.SigHash(txscript.SigHashAll, msgTx, i)
@@ -178,6 +221,11 @@ func (t *tx) Recipients() Recipients {
 	return t.recipients
 }
 
+// Replaceable returns whether the transaction signals that it can be replaced (BIP125).
+func (t *tx) Replaceable() bool {
+	return t.replaceable
+}
+
 type MsgTx interface {
 	Serialize(buffer io.Writer) error
 	TxHash() chainhash.Hash
@@ -191,7 +239,8 @@ type MsgTx interface {
 
 func NewMsgTx(network Network) MsgTx {
 	switch network.Chain() {
-	case types.Bitcoin:
+	case types.Bitcoin, types.Litecoin, types.Dogecoin:
+		// Litecoin and Dogecoin use the same transaction format as Bitcoin.
 		return NewBtcMsgTx(wire.NewMsgTx(BtcVersion))
 	case types.ZCash:
 		net := network.(ZecNetwork)
@@ -279,6 +328,9 @@ func NewBchMsgTx(msgTx *wire.MsgTx) BchMsgTx {
 	return BchMsgTx{msgTx}
 }
 
+// EstimateTxSize estimates the size of a transaction spending P2PKH UTXOs to P2PKH recipients.
+//
+// Deprecated: use EstimateVSize, which takes the types of the inputs and outputs into account.
 func EstimateTxSize(numUTXOs, numRecipients int) int {
 	return 146*numUTXOs + 33*numRecipients + 10
 }
@@ -0,0 +1,59 @@
+package btctypes_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/types/btctypes"
+
+	"github.com/renproject/mercury/testutil"
+	"github.com/renproject/mercury/types"
+)
+
+var _ = Describe("Transactions", func() {
+	// newTx returns a transaction of the network spending a UTXO to a random address.
+	newTx := func(network Network, opts TxOptions) (BtcTx, Recipients, error) {
+		address, err := testutil.RandomAddress(network)
+		Expect(err).NotTo(HaveOccurred())
+		scriptPubKey, err := PayToAddrScript(address, network)
+		Expect(err).NotTo(HaveOccurred())
+		utxos := UTXOs{NewUTXO(NewOutPoint(types.TxHash(fmt.Sprintf("%064x", 1)), 0), 100000, scriptPubKey, 1, nil)}
+		recipients := Recipients{NewRecipient(address, 40000), NewRecipient(address, 50000)}
+		tx, err := NewUnsignedTxWithOptions(network, utxos, recipients, opts)
+		return tx, recipients, err
+	}
+
+	Context("when building replaceable transactions", func() {
+		It("should signal replaceability using the sequence of the inputs", func() {
+			tx, recipients, err := newTx(BtcRegtest, TxOptions{RBF: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tx.Replaceable()).To(BeTrue())
+			Expect(tx.Recipients()).To(Equal(recipients))
+			Expect(tx.Recipients().Sum()).To(Equal(Amount(90000)))
+
+			data, err := tx.Serialize()
+			Expect(err).NotTo(HaveOccurred())
+			msgTx := new(wire.MsgTx)
+			Expect(msgTx.Deserialize(bytes.NewReader(data))).To(Succeed())
+			Expect(msgTx.TxIn[0].Sequence).To(Equal(uint32(RBFSequence)))
+
+			tx, _, err = newTx(BtcRegtest, DefaultTxOptions())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tx.Replaceable()).To(BeFalse())
+			data, err = tx.Serialize()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msgTx.Deserialize(bytes.NewReader(data))).To(Succeed())
+			Expect(msgTx.TxIn[0].Sequence).To(Equal(uint32(wire.MaxTxInSequenceNum)))
+		})
+
+		It("should not support chains without replace-by-fee", func() {
+			_, _, err := newTx(BchRegtest, TxOptions{RBF: true})
+			Expect(err).To(Equal(ErrDoesNotSupportRBF))
+			_, _, err = newTx(ZecRegnet, TxOptions{RBF: true})
+			Expect(err).To(Equal(ErrDoesNotSupportRBF))
+		})
+	})
+})
@@ -1,6 +1,10 @@
 package types
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
 
 // JSONError defines a JSON error object that is compatible with the JSON-RPC 2.0 specification. See
 // https://www.jsonrpc.org/specification for more information.
@@ -10,6 +14,25 @@ type JSONError struct {
 	Data    json.RawMessage `json:"data"`
 }
 
+// Error implements the `error` interface.
+func (err *JSONError) Error() string {
+	if err.Code == 0 {
+		return err.Message
+	}
+	return fmt.Sprintf("%s (code %d)", err.Message, err.Code)
+}
+
+// Is returns whether the error matches the target when using `errors.Is`. The target matches if it has the same code,
+// and its message is contained in the message of the error (ignoring case). This allows targets with an empty message
+// to match every error with the same code.
+func (err *JSONError) Is(target error) bool {
+	t, ok := target.(*JSONError)
+	if !ok {
+		return false
+	}
+	return err.Code == t.Code && strings.Contains(strings.ToLower(err.Message), strings.ToLower(t.Message))
+}
+
 // JSONRequest defines a JSON request object that is compatible with the JSON-RPC 2.0 specification. See
 // https://www.jsonrpc.org/specification for more information.
 type JSONRequest struct {
@@ -12,6 +12,8 @@ const (
 	Ethereum    Chain = 1
 	ZCash       Chain = 2
 	BitcoinCash Chain = 3
+	Litecoin    Chain = 4
+	Dogecoin    Chain = 5
 )
 
 func NewChain(chain string) Chain {
@@ -23,6 +25,10 @@ func NewChain(chain string) Chain {
 		return Ethereum
 	case "ZCASH", "ZEC":
 		return ZCash
+	case "LITECOIN", "LTC":
+		return Litecoin
+	case "DOGECOIN", "DOGE":
+		return Dogecoin
 	default:
 		panic(ErrUnknownChain)
 	}
@@ -39,6 +45,10 @@ func (chain Chain) String() string {
 		return "zec"
 	case BitcoinCash:
 		return "bch"
+	case Litecoin:
+		return "ltc"
+	case Dogecoin:
+		return "doge"
 	default:
 		panic(ErrUnknownChain)
 	}
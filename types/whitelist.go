@@ -1,5 +1,7 @@
 package types
 
+import "fmt"
+
 type AccessLevel uint8
 
 const (
@@ -7,3 +9,35 @@ const (
 	CachedAccess AccessLevel = 1
 	NoAccess     AccessLevel = 0
 )
+
+// String implements the `Stringer` interface.
+func (level AccessLevel) String() string {
+	switch level {
+	case FullAccess:
+		return "full"
+	case CachedAccess:
+		return "cached"
+	default:
+		return "none"
+	}
+}
+
+// MarshalText implements the `encoding.TextMarshaler` interface.
+func (level AccessLevel) MarshalText() ([]byte, error) {
+	return []byte(level.String()), nil
+}
+
+// UnmarshalText implements the `encoding.TextUnmarshaler` interface.
+func (level *AccessLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "full":
+		*level = FullAccess
+	case "cached":
+		*level = CachedAccess
+	case "none":
+		*level = NoAccess
+	default:
+		return fmt.Errorf("unknown access level: %s", text)
+	}
+	return nil
+}
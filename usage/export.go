@@ -0,0 +1,55 @@
+package usage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Export formats.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Export writes the records to the writer in the given format.
+func Export(w io.Writer, records []Record, format string) error {
+	switch format {
+	case FormatCSV:
+		return ExportCSV(w, records)
+	case FormatJSON:
+		return ExportJSON(w, records)
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// ExportCSV writes the records to the writer as CSV with a header row.
+func ExportCSV(w io.Writer, records []Record) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"date", "caller", "network", "method", "requests", "errors"}); err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := []string{
+			record.Date,
+			record.Caller,
+			record.Network,
+			record.Method,
+			strconv.FormatUint(record.Requests, 10),
+			strconv.FormatUint(record.Errors, 10),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ExportJSON writes the records to the writer as a JSON array.
+func ExportJSON(w io.Writer, records []Record) error {
+	return json.NewEncoder(w).Encode(records)
+}
@@ -0,0 +1,227 @@
+// Package usage persists the number of requests sent by each caller as daily aggregates, so that the upstream capacity
+// consumed by each caller can be reported.
+package usage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renproject/kv"
+	"github.com/renproject/mercury/stat"
+)
+
+// DateFormat is the format of the date of each aggregate. Dates are in UTC.
+const DateFormat = "2006-01-02"
+
+// Anonymous is the caller recorded for requests which do not identify their caller, or whose caller is not in the
+// options of the store.
+const Anonymous = "anonymous"
+
+// Record is the number of requests sent by a caller to a method of a network on a day.
+type Record struct {
+	Date     string `json:"date"`
+	Caller   string `json:"caller"`
+	Network  string `json:"network"`
+	Method   string `json:"method"`
+	Requests uint64 `json:"requests"`
+	Errors   uint64 `json:"errors"`
+}
+
+// key returns the key of the record in the store.
+func (record Record) key() string {
+	return strings.Join([]string{
+		record.Date,
+		url.QueryEscape(record.Caller),
+		url.QueryEscape(record.Network),
+		url.QueryEscape(record.Method),
+	}, "|")
+}
+
+// Options configure the callers persisted by a Store.
+type Options struct {
+	// Callers are the callers which are persisted (e.g. "tag:darknode", see `api.Caller`). Requests from any other
+	// caller are recorded as anonymous, so that callers cannot create an unlimited number of records.
+	Callers []string
+}
+
+// DefaultOptions returns options which do not persist any callers.
+func DefaultOptions() Options {
+	return Options{
+		Callers: nil,
+	}
+}
+
+// Store aggregates requests in memory and periodically adds them to the records in a table.
+type Store struct {
+	table   kv.Table
+	mu      *sync.Mutex
+	callers map[string]bool
+	pending map[string]*Record
+
+	// flushMu serialises flushes, so that requests can be recorded while the pending records are written.
+	flushMu *sync.Mutex
+}
+
+// New returns a new Store which persists records in the given table, using the default options.
+func New(table kv.Table) *Store {
+	return NewWithOptions(table, DefaultOptions())
+}
+
+// NewWithOptions returns a new Store which persists records in the given table, using the given options.
+func NewWithOptions(table kv.Table, opts Options) *Store {
+	store := &Store{
+		table:   table,
+		mu:      &sync.Mutex{},
+		pending: map[string]*Record{},
+		flushMu: &sync.Mutex{},
+	}
+	store.SetOptions(opts)
+	return store
+}
+
+// SetOptions replaces the options of the store (e.g. when the configuration is reloaded). Requests which have already
+// been recorded are not affected.
+func (store *Store) SetOptions(opts Options) {
+	callers := make(map[string]bool, len(opts.Callers))
+	for _, caller := range opts.Callers {
+		callers[caller] = true
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.callers = callers
+}
+
+// Record implements the `stat.Recorder` interface. The request is only persisted once the store is flushed.
+func (store *Store) Record(event stat.Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	record := Record{
+		Date:    event.Time.UTC().Format(DateFormat),
+		Caller:  event.Caller,
+		Network: event.Network,
+		Method:  event.Method,
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if !store.callers[record.Caller] {
+		record.Caller = Anonymous
+	}
+
+	key := record.key()
+	if store.pending[key] == nil {
+		store.pending[key] = &record
+	}
+	store.pending[key].Requests++
+	if event.Status >= 400 {
+		store.pending[key].Errors++
+	}
+}
+
+// restore adds records which could not be flushed back to the pending records.
+func (store *Store) restore(records map[string]*Record) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for key, record := range records {
+		if pending := store.pending[key]; pending != nil {
+			pending.Requests += record.Requests
+			pending.Errors += record.Errors
+			continue
+		}
+		store.pending[key] = record
+	}
+}
+
+// Run flushes the store at the given interval until the context is done, and once more before returning.
+func (store *Store) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return store.Flush()
+		case <-ticker.C:
+			if err := store.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Flush adds the pending requests to the records in the table. Requests can be recorded while the store is being
+// flushed, and they are added by the next flush.
+func (store *Store) Flush() error {
+	store.flushMu.Lock()
+	defer store.flushMu.Unlock()
+
+	store.mu.Lock()
+	pending := store.pending
+	store.pending = map[string]*Record{}
+	store.mu.Unlock()
+
+	for key, p := range pending {
+		record := Record{}
+		if err := store.table.Get(key, &record); err != nil && err != kv.ErrKeyNotFound {
+			store.restore(pending)
+			return fmt.Errorf("cannot get usage record: %v", err)
+		}
+		record.Date, record.Caller, record.Network, record.Method = p.Date, p.Caller, p.Network, p.Method
+		record.Requests += p.Requests
+		record.Errors += p.Errors
+		if err := store.table.Insert(key, record); err != nil {
+			store.restore(pending)
+			return fmt.Errorf("cannot insert usage record: %v", err)
+		}
+		delete(pending, key)
+	}
+	return nil
+}
+
+// Records flushes the store and returns the records for the days between from and to (inclusive), sorted by date,
+// caller, network and method. Empty dates are unbounded.
+func (store *Store) Records(from, to string) ([]Record, error) {
+	if err := store.Flush(); err != nil {
+		return nil, err
+	}
+	return Records(store.table, from, to)
+}
+
+// Records returns the records stored in the table for the days between from and to (inclusive), sorted by date,
+// caller, network and method. Empty dates are unbounded.
+func Records(table kv.Table, from, to string) ([]Record, error) {
+	for _, date := range []string{from, to} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(DateFormat, date); err != nil {
+			return nil, fmt.Errorf("invalid date %s: %v", date, err)
+		}
+	}
+
+	records := []Record{}
+	iter := table.Iterator()
+	for iter.Next() {
+		record := Record{}
+		if err := iter.Value(&record); err != nil {
+			return nil, fmt.Errorf("cannot decode usage record: %v", err)
+		}
+		if (from != "" && record.Date < from) || (to != "" && record.Date > to) {
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].key() < records[j].key()
+	})
+	return records, nil
+}
@@ -0,0 +1,121 @@
+package usage_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/renproject/mercury/usage"
+
+	"github.com/renproject/kv"
+	"github.com/renproject/mercury/stat"
+)
+
+var _ = Describe("Usage", func() {
+	day := func(date string) time.Time {
+		t, err := time.Parse(DateFormat, date)
+		Expect(err).ToNot(HaveOccurred())
+		return t.Add(12 * time.Hour)
+	}
+
+	record := func(store *Store, date, caller, method string, status int) {
+		store.Record(stat.Event{
+			Time:    day(date),
+			Caller:  caller,
+			Network: "btc/mainnet",
+			Method:  method,
+			Status:  status,
+		})
+	}
+
+	opts := Options{Callers: []string{"tag:darknode", "key:abcd"}}
+
+	Context("when recording requests", func() {
+		It("should aggregate them per day, caller, network and method", func() {
+			store := NewWithOptions(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "usage"), opts)
+			record(store, "2026-09-01", "tag:darknode", "getrawtransaction", 200)
+			record(store, "2026-09-01", "tag:darknode", "getrawtransaction", 500)
+			record(store, "2026-09-01", "", "getrawtransaction", 200)
+			record(store, "2026-09-02", "tag:darknode", "listunspent", 200)
+
+			records, err := store.Records("", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal([]Record{
+				{Date: "2026-09-01", Caller: Anonymous, Network: "btc/mainnet", Method: "getrawtransaction", Requests: 1},
+				{Date: "2026-09-01", Caller: "tag:darknode", Network: "btc/mainnet", Method: "getrawtransaction", Requests: 2, Errors: 1},
+				{Date: "2026-09-02", Caller: "tag:darknode", Network: "btc/mainnet", Method: "listunspent", Requests: 1},
+			}))
+
+			records, err = store.Records("2026-09-02", "2026-09-30")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(HaveLen(1))
+
+			_, err = store.Records("September", "")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should record callers which are not configured as anonymous", func() {
+			store := NewWithOptions(kv.NewTable(kv.NewMemDB(kv.JSONCodec), "usage"), opts)
+			record(store, "2026-09-01", "tag:darknode", "getrawtransaction", 200)
+			record(store, "2026-09-01", "tag:random", "getrawtransaction", 200)
+			record(store, "2026-09-01", "key:abcd", "getrawtransaction", 200)
+			record(store, "2026-09-01", "key:ef01", "getrawtransaction", 200)
+
+			// Requests which have already been recorded are not affected by new options.
+			store.SetOptions(Options{Callers: []string{"key:ef01"}})
+			record(store, "2026-09-01", "tag:darknode", "getrawtransaction", 200)
+			record(store, "2026-09-01", "key:ef01", "getrawtransaction", 200)
+
+			records, err := store.Records("", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal([]Record{
+				{Date: "2026-09-01", Caller: Anonymous, Network: "btc/mainnet", Method: "getrawtransaction", Requests: 3},
+				{Date: "2026-09-01", Caller: "key:abcd", Network: "btc/mainnet", Method: "getrawtransaction", Requests: 1},
+				{Date: "2026-09-01", Caller: "key:ef01", Network: "btc/mainnet", Method: "getrawtransaction", Requests: 1},
+				{Date: "2026-09-01", Caller: "tag:darknode", Network: "btc/mainnet", Method: "getrawtransaction", Requests: 1},
+			}))
+		})
+
+		It("should keep the records after a restart", func() {
+			db := kv.NewMemDB(kv.JSONCodec)
+			store := NewWithOptions(kv.NewTable(db, "usage"), opts)
+			record(store, "2026-09-01", "tag:darknode", "getrawtransaction", 200)
+			Expect(store.Flush()).To(Succeed())
+
+			store = NewWithOptions(kv.NewTable(db, "usage"), opts)
+			record(store, "2026-09-01", "tag:darknode", "getrawtransaction", 200)
+			Expect(store.Flush()).To(Succeed())
+
+			records, err := Records(kv.NewTable(db, "usage"), "", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Requests).To(Equal(uint64(2)))
+		})
+	})
+
+	Context("when exporting records", func() {
+		records := []Record{
+			{Date: "2026-09-01", Caller: "tag:darknode", Network: "btc/mainnet", Method: "getrawtransaction", Requests: 2, Errors: 1},
+		}
+
+		It("should write CSV with a header", func() {
+			buf := new(bytes.Buffer)
+			Expect(Export(buf, records, FormatCSV)).To(Succeed())
+			Expect(buf.String()).To(Equal("date,caller,network,method,requests,errors\n2026-09-01,tag:darknode,btc/mainnet,getrawtransaction,2,1\n"))
+		})
+
+		It("should write JSON", func() {
+			buf := new(bytes.Buffer)
+			Expect(Export(buf, records, FormatJSON)).To(Succeed())
+			decoded := []Record{}
+			Expect(json.Unmarshal(buf.Bytes(), &decoded)).To(Succeed())
+			Expect(decoded).To(Equal(records))
+		})
+
+		It("should return an error for unsupported formats", func() {
+			Expect(Export(new(bytes.Buffer), records, "xml")).ToNot(Succeed())
+		})
+	})
+})